@@ -0,0 +1,55 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+// Union returns a new set with all elements of the given sets, built
+// in a single pass over each of them. With no sets, it returns a new,
+// empty thread-safe set.
+func Union(sets ...Set) Set {
+	if len(sets) == 0 {
+		return NewSet()
+	}
+	union := sets[0].Clone()
+	for _, s := range sets[1:] {
+		union.UnionWith(s)
+	}
+	return union
+}
+
+// Intersect returns a new set with the elements that are in all of the
+// given sets. It starts from the smallest set and stops early once the
+// intersection is empty. With no sets, it returns a new, empty
+// thread-safe set.
+func Intersect(sets ...Set) Set {
+	if len(sets) == 0 {
+		return NewSet()
+	}
+	smallest := 0
+	for i, s := range sets {
+		if s.Size() < sets[smallest].Size() {
+			smallest = i
+		}
+	}
+	intersection := sets[smallest].Clone()
+	for i, s := range sets {
+		if i == smallest {
+			continue
+		}
+		if intersection.Size() == 0 {
+			break
+		}
+		intersection.IntersectWith(s)
+	}
+	return intersection
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"net"
+	"time"
+)
+
+func init() {
+	// Sets are gob-encoded as a list of interface values, which gob can
+	// only decode into registered types. Register the natively
+	// supported types gob doesn't know by itself, and the set types,
+	// so that sets can be sent as Set values, too.
+	gob.Register(time.Time{})
+	gob.Register(time.Duration(0))
+	gob.Register(net.IP{})
+	gob.Register([16]byte{})
+	gob.Register(json.Number(""))
+	gob.Register(&ThreadSafeSet{})
+	gob.Register(&ThreadUnsafeSet{})
+}
+
+// gobElem is the gob-encoded form of a single element.
+type gobElem struct {
+	Elem interface{}
+}
+
+func encodeGobElem(elem interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(gobElem{Elem: elem}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeGobElem(b []byte) (interface{}, error) {
+	var e gobElem
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&e); err != nil {
+		return nil, err
+	}
+	return e.Elem, nil
+}
+
+// binarySet is the gob-encoded form of a set.
+type binarySet struct {
+	Elems []interface{}
+	Mixed bool
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler with gob, which
+// makes sets gob-encodable. The elements keep their types, Hashable and
+// other custom types must be registered with gob.Register as for any
+// interface value sent with gob.
+func (set *ThreadUnsafeSet) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(binarySet{Elems: set.ToSlice(), Mixed: set.conf.mixed})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, adding the
+// elements encoded by MarshalBinary to the set. A zero set becomes a
+// set with the default storage.
+func (set *ThreadUnsafeSet) UnmarshalBinary(b []byte) error {
+	var decoded binarySet
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&decoded); err != nil {
+		return err
+	}
+	if set.store == nil {
+		*set = newThreadUnsafeSet()
+		set.conf.mixed = decoded.Mixed
+	}
+	for _, elem := range decoded.Elems {
+		if _, err := set.TryAdd(elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler with gob, see
+// ThreadUnsafeSet.MarshalBinary.
+func (set *ThreadSafeSet) MarshalBinary() ([]byte, error) {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.MarshalBinary()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, see
+// ThreadUnsafeSet.UnmarshalBinary.
+func (set *ThreadSafeSet) UnmarshalBinary(b []byte) error {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.UnmarshalBinary(b)
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+)
+
+func Test_MarshalBinary(t *testing.T) {
+	gob.Register(hashedInt(0))
+	for _, s := range []Set{NewSet(1, 2, 3), NewThreadUnsafeSet(hashedInt(1), hashedInt(2)), NewSet(time.Unix(1, 0))} {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(struct{ S Set }{s}); err != nil {
+			t.Fatal(err)
+		}
+		var decoded struct{ S Set }
+		if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.S.Equal(s) {
+			t.Errorf("Expected %v to round-trip, got %v", s, decoded.S)
+		}
+	}
+
+	b, err := NewThreadUnsafeSet(WithMixedTypes(), 1, "1").(*ThreadUnsafeSet).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mixed ThreadSafeSet
+	if err := mixed.UnmarshalBinary(b); err != nil || mixed.Size() != 2 || !mixed.Add(1.5) {
+		t.Errorf("Expected a mixed set to round-trip, got %v, %v", &mixed, err)
+	}
+	if err := NewThreadUnsafeSet(2.5).(*ThreadUnsafeSet).UnmarshalBinary(b); err == nil {
+		t.Errorf("Expected an error decoding mixed elements into a set of floats")
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "math/bits"
+
+// NewBitSet creates and returns a new set of non-negative ints with the
+// given elements, stored as a bitmap with one bit per int up to the
+// largest element. For dense ranges of ints, such as IDs, it is far
+// smaller than a hash-based set, and Union, Intersect, Difference and
+// SymmetricDifference between two bit sets work a word of 64 elements
+// at a time.
+//
+// Adding anything but a non-negative int to the set panics.
+// Operations on the resulting set are thread-safe.
+func NewBitSet(vals ...int) Set {
+	s := NewThreadUnsafeBitSet(vals...).(*ThreadUnsafeSet)
+	return s.ToThreadSafe()
+}
+
+// NewThreadUnsafeBitSet creates and returns a new set of non-negative
+// ints with the given elements, see NewBitSet.
+// Operations on the resulting set are not thread-safe.
+func NewThreadUnsafeBitSet(vals ...int) Set {
+	s := &ThreadUnsafeSet{store: &bitStore{}}
+	for _, item := range vals {
+		s.Add(item)
+	}
+	return s
+}
+
+// bitStore is a store of non-negative ints, holding int i as bit i%64
+// of word i/64.
+type bitStore struct {
+	words []uint64
+	n     int
+}
+
+func (s *bitStore) add(val interface{}) (bool, error) {
+	i, ok := val.(int)
+	if !ok || i < 0 {
+		return false, notStorable(val, "%#v can't be stored in a bit set, elements must be non-negative ints", val)
+	}
+	w := i / 64
+	if w >= len(s.words) {
+		// append grows the words geometrically, so that ascending adds
+		// take amortized constant time.
+		s.words = append(s.words, make([]uint64, w+1-len(s.words))...)
+	}
+	bit := uint64(1) << uint(i%64)
+	if s.words[w]&bit != 0 {
+		return false, nil
+	}
+	s.words[w] |= bit
+	s.n++
+	return true, nil
+}
+
+func (s *bitStore) has(val interface{}) bool {
+	i, ok := val.(int)
+	return ok && i >= 0 && i/64 < len(s.words) && s.words[i/64]&(1<<uint(i%64)) != 0
+}
+
+func (s *bitStore) get(val interface{}) (interface{}, bool) {
+	if !s.has(val) {
+		return nil, false
+	}
+	return val, true
+}
+
+func (s *bitStore) remove(val interface{}) (bool, error) {
+	if _, ok := val.(int); !ok {
+		return false, notStorable(val, "%#v can't be stored in a bit set, elements must be non-negative ints", val)
+	}
+	if !s.has(val) {
+		return false, nil
+	}
+	i := val.(int)
+	s.words[i/64] &^= 1 << uint(i%64)
+	s.n--
+	return true, nil
+}
+
+func (s *bitStore) len() int {
+	return s.n
+}
+
+func (s *bitStore) each(f func(val interface{}) bool) {
+	for w, word := range s.words {
+		for word != 0 {
+			b := bits.TrailingZeros64(word)
+			if f(w*64 + b) {
+				return
+			}
+			word &^= 1 << uint(b)
+		}
+	}
+}
+
+func (s *bitStore) clear() {
+	s.words, s.n = nil, 0
+}
+
+func (s *bitStore) grow(n int) {}
+
+func (s *bitStore) empty(capacity int) store {
+	return &bitStore{}
+}
+
+func (s *bitStore) combine(op setOp, other store) (store, bool) {
+	ret := &bitStore{words: make([]uint64, len(s.words)), n: s.n}
+	copy(ret.words, s.words)
+	if !ret.combineWith(op, other) {
+		return nil, false
+	}
+	return ret, true
+}
+
+func (s *bitStore) combineWith(op setOp, other store) bool {
+	o, ok := other.(*bitStore)
+	if !ok {
+		return false
+	}
+	if (op == opUnion || op == opSymmetricDifference) && len(o.words) > len(s.words) {
+		words := make([]uint64, len(o.words))
+		copy(words, s.words)
+		s.words = words
+	}
+	for w := range s.words {
+		var ow uint64
+		if w < len(o.words) {
+			ow = o.words[w]
+		}
+		switch op {
+		case opUnion:
+			s.words[w] |= ow
+		case opIntersect:
+			s.words[w] &= ow
+		case opDifference:
+			s.words[w] &^= ow
+		case opSymmetricDifference:
+			s.words[w] ^= ow
+		}
+	}
+	s.trim()
+	return true
+}
+
+// trim drops trailing empty words and recounts the elements.
+func (s *bitStore) trim() {
+	last := len(s.words)
+	for last > 0 && s.words[last-1] == 0 {
+		last--
+	}
+	s.words = s.words[:last]
+	s.n = 0
+	for _, word := range s.words {
+		s.n += bits.OnesCount64(word)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func Test_BitSet(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	a, b := NewBitSet(), NewBitSet()
+	ha, hb := NewSet(), NewSet()
+	for i := 0; i < N; i++ {
+		v, w := r.Intn(N), r.Intn(2*N)
+		a.Add(v)
+		ha.Add(v)
+		b.Add(w)
+		hb.Add(w)
+	}
+
+	check := func(name string, got, expected Set) {
+		if !got.Equal(expected) || !expected.Equal(got) {
+			t.Errorf("Expected %v to match the hash set result", name)
+		}
+	}
+	check("Union", a.Union(b), ha.Union(hb))
+	check("Intersect", a.Intersect(b), ha.Intersect(hb))
+	check("Difference", b.Difference(a), hb.Difference(ha))
+	check("SymmetricDifference", a.SymmetricDifference(b), ha.SymmetricDifference(hb))
+
+	c := a.Clone()
+	c.IntersectWith(b)
+	ha.IntersectWith(hb)
+	check("IntersectWith", c, ha)
+
+	if objs := NewBitSet(130, 3, 64).ToSlice(); objs[0] != 3 || objs[1] != 64 || objs[2] != 130 {
+		t.Errorf("Expected elements in ascending order, got %v", objs)
+	}
+	if NewBitSet(1).Contains("1", -1) {
+		t.Errorf("Expected only non-negative ints to be found")
+	}
+}
+
+func Test_BitSetRejectsNegative(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected adding a negative int to panic")
+		}
+	}()
+	NewThreadUnsafeBitSet(-1)
+}
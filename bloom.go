@@ -0,0 +1,184 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"hash/fnv"
+	"math"
+	"sync/atomic"
+)
+
+// bloomSize returns the number of bits or counters m and the number of
+// hash functions k of a filter for n expected elements at the given
+// false positive rate.
+func bloomSize(n int, fpRate float64) (m, k uint64) {
+	if n < 1 {
+		n = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+	fm := math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	fk := math.Max(1, math.Round(fm/float64(n)*math.Ln2))
+	return uint64(fm), uint64(fk)
+}
+
+// bloomPositions returns the k of m positions of hash, derived from one
+// 64-bit FNV hash by double hashing.
+func bloomPositions(hash string, k, m uint64) []uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(hash))
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32|1
+	pos := make([]uint64, k)
+	for i := uint64(0); i < k; i++ {
+		pos[i] = (h1 + i*h2) % m
+	}
+	return pos
+}
+
+// bloomFilter is a counting Bloom filter over element hashes. Counters
+// instead of bits make removals possible. They are updated atomically,
+// so that mightContain can run concurrently with add and remove, which
+// must not run concurrently with each other.
+type bloomFilter struct {
+	counters []uint32
+	k        uint64
+}
+
+// newBloomFilter sizes a filter for n expected elements at the given
+// false positive rate.
+func newBloomFilter(n int, fpRate float64) *bloomFilter {
+	m, k := bloomSize(n, fpRate)
+	return &bloomFilter{counters: make([]uint32, m), k: k}
+}
+
+func (f *bloomFilter) positions(hash string) []uint64 {
+	return bloomPositions(hash, f.k, uint64(len(f.counters)))
+}
+
+func (f *bloomFilter) add(hash string) {
+	for _, p := range f.positions(hash) {
+		atomic.AddUint32(&f.counters[p], 1)
+	}
+}
+
+func (f *bloomFilter) remove(hash string) {
+	for _, p := range f.positions(hash) {
+		if atomic.LoadUint32(&f.counters[p]) > 0 {
+			atomic.AddUint32(&f.counters[p], ^uint32(0))
+		}
+	}
+}
+
+func (f *bloomFilter) mightContain(hash string) bool {
+	for _, p := range f.positions(hash) {
+		if atomic.LoadUint32(&f.counters[p]) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// emptyLike returns a new, empty filter of the size of f.
+func (f *bloomFilter) emptyLike() *bloomFilter {
+	return &bloomFilter{counters: make([]uint32, len(f.counters)), k: f.k}
+}
+
+func (f *bloomFilter) clone() *bloomFilter {
+	c := f.emptyLike()
+	for i := range f.counters {
+		c.counters[i] = atomic.LoadUint32(&f.counters[i])
+	}
+	return c
+}
+
+// BloomFilter is a compact, read-only summary of the elements of a set,
+// made by ToBloomFilter. MightContain never reports an element of the
+// set as absent, and reports other elements as present at about the
+// false positive rate the filter was made with. It is safe for
+// concurrent use.
+type BloomFilter struct {
+	bits   []uint64
+	m, k   uint64
+	hasher Hasher // Hasher of the set, if it has one
+}
+
+// newCompactBloomFilter returns a filter for n elements of a set that
+// identifies them by hasher, or by their hash strings if it is nil.
+func newCompactBloomFilter(n int, fpRate float64, hasher Hasher) *BloomFilter {
+	m, k := bloomSize(n, fpRate)
+	return &BloomFilter{bits: make([]uint64, (m+63)/64), m: m, k: k, hasher: hasher}
+}
+
+func (f *BloomFilter) hash(val interface{}) (string, bool) {
+	if f.hasher != nil {
+		return f.hasher.Hash(val), true
+	}
+	hash, err := calcHash(val)
+	return hash, err == nil
+}
+
+func (f *BloomFilter) add(val interface{}) {
+	if hash, ok := f.hash(val); ok {
+		for _, p := range bloomPositions(hash, f.k, f.m) {
+			f.bits[p/64] |= 1 << (p % 64)
+		}
+	}
+}
+
+// MightContain reports whether val may be an element of the set the
+// filter was made of. If it reports false, val is certainly not.
+func (f *BloomFilter) MightContain(val interface{}) bool {
+	hash, ok := f.hash(val)
+	if !ok {
+		return false
+	}
+	for _, p := range bloomPositions(hash, f.k, f.m) {
+		if f.bits[p/64]&(1<<(p%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ToBloomFilter returns a Bloom filter of the elements of the set, with
+// the given false positive rate, e.g. 0.01. It takes about
+// -1.44·log2(fpRate) bits per element, 10 bits for 1%.
+func (set *ThreadUnsafeSet) ToBloomFilter(fpRate float64) *BloomFilter {
+	var hasher Hasher
+	if s, ok := unwrapStore(set.store, false).(*hasherStore); ok {
+		hasher = s.h
+	}
+	f := newCompactBloomFilter(set.Size(), fpRate, hasher)
+	set.store.each(func(elem interface{}) bool {
+		f.add(elem)
+		return false
+	})
+	return f
+}
+
+// ToBloomFilter returns a Bloom filter of the elements of the set, see
+// ThreadUnsafeSet.ToBloomFilter.
+func (set *ThreadSafeSet) ToBloomFilter(fpRate float64) *BloomFilter {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.ToBloomFilter(fpRate)
+}
+
+// ToBloomFilter returns a Bloom filter of the elements of the set, see
+// ThreadUnsafeSet.ToBloomFilter.
+func (set FrozenSet) ToBloomFilter(fpRate float64) *BloomFilter {
+	return set.elems().ToBloomFilter(fpRate)
+}
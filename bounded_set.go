@@ -0,0 +1,212 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"container/list"
+	"sync"
+)
+
+// EvictionPolicy decides what a full BoundedSet does with a new element.
+type EvictionPolicy int
+
+const (
+	// EvictLRU evicts the least recently used element, the one least
+	// recently added or found by Contains.
+	EvictLRU EvictionPolicy = iota
+	// EvictFIFO evicts the element added first.
+	EvictFIFO
+	// RejectNew keeps the elements of the set and rejects the new one.
+	RejectNew
+)
+
+// BoundedSet is a thread-safe set of at most a given number of elements,
+// which makes room for new elements according to its EvictionPolicy, e.g.
+// to deduplicate a stream with bounded memory:
+//
+//	seen := goset.NewBoundedSet(100000, goset.EvictLRU)
+//	if seen.Add(id) {
+//		// id is new, or was forgotten
+//	}
+type BoundedSet struct {
+	sync.Mutex
+	unsafeSet ThreadUnsafeSet
+	elems     listIndex  // Hash index into order
+	order     *list.List // Elements in the order they are evicted in, the next first
+	max       int
+	policy    EvictionPolicy
+}
+
+// NewBoundedSet creates and returns a new set of at most maxSize
+// elements, with the given elements added in order. It panics if maxSize
+// is not positive.
+func NewBoundedSet(maxSize int, policy EvictionPolicy, vals ...interface{}) *BoundedSet {
+	if maxSize <= 0 {
+		panic("goset: the size of a BoundedSet must be positive")
+	}
+	set := &BoundedSet{
+		unsafeSet: newThreadUnsafeSet(),
+		elems:     listIndex{},
+		order:     list.New(),
+		max:       maxSize,
+		policy:    policy,
+	}
+	for _, item := range vals {
+		set.Add(item)
+	}
+	return set
+}
+
+// Add adds an element to the set, evicting another one if the set is
+// full. Returns whether the item was newly added, which it is not if it
+// is present already, or if the set is full and its policy is RejectNew.
+func (set *BoundedSet) Add(val interface{}) bool {
+	added, _, _ := set.AddEvict(val)
+	return added
+}
+
+// AddEvict is like Add, but also returns the element evicted to make
+// room for val, if any.
+func (set *BoundedSet) AddEvict(val interface{}) (added bool, evicted interface{}, didEvict bool) {
+	hash, err := calcHash(val)
+	if err != nil {
+		panic(err)
+	}
+	set.Lock()
+	defer set.Unlock()
+	if e := set.elems.find(val, hash); e != nil {
+		if set.policy == EvictLRU {
+			set.order.MoveToBack(e)
+		}
+		return false, nil, false
+	}
+	full := set.order.Len() >= set.max
+	if full && set.policy == RejectNew {
+		return false, nil, false
+	}
+	// Add first, so that no element is evicted for one of the wrong type.
+	set.unsafeSet.Add(val)
+	if full {
+		evicted, didEvict = set.removeElement(set.order.Front()), true
+	}
+	set.elems.insert(hash, set.order.PushBack(val))
+	return true, evicted, didEvict
+}
+
+// removeElement removes the element of e from the set and returns it.
+// The caller must hold the lock.
+func (set *BoundedSet) removeElement(e *list.Element) interface{} {
+	val := set.order.Remove(e)
+	hash, _ := calcHash(val)
+	set.elems.delete(hash, e)
+	set.unsafeSet.Remove(val)
+	return val
+}
+
+// Contains returns whether the given items are all in the set. Under
+// EvictLRU, it counts as a use of the items.
+func (set *BoundedSet) Contains(val ...interface{}) bool {
+	set.Lock()
+	defer set.Unlock()
+	for _, v := range val {
+		hash, err := calcHash(v)
+		if err != nil {
+			return false
+		}
+		if set.elems.find(v, hash) == nil {
+			return false
+		}
+	}
+	if set.policy == EvictLRU {
+		for _, v := range val {
+			hash, _ := calcHash(v)
+			set.order.MoveToBack(set.elems.find(v, hash))
+		}
+	}
+	return true
+}
+
+// Remove remove a single element from the set.
+func (set *BoundedSet) Remove(i interface{}) {
+	hash, err := calcHash(i)
+	if err != nil {
+		panic(err)
+	}
+	set.Lock()
+	defer set.Unlock()
+	if e := set.elems.find(i, hash); e != nil {
+		set.removeElement(e)
+	}
+}
+
+// Size Returns the number of elements in the set.
+func (set *BoundedSet) Size() int {
+	set.Lock()
+	defer set.Unlock()
+	return set.order.Len()
+}
+
+// MaxSize returns the number of elements the set holds at most.
+func (set *BoundedSet) MaxSize() int {
+	return set.max
+}
+
+// Each iterates over a snapshot of the elements, see ToSlice, in the
+// order they would be evicted in and executes the passed func against
+// each element. The set is not locked while f runs, so f may use the
+// set. If passed func returns true, stop iteration at the time.
+func (set *BoundedSet) Each(f func(elem interface{}) bool) {
+	for _, elem := range set.ToSlice() {
+		if f(elem) {
+			return
+		}
+	}
+}
+
+// ToSlice returns the members of the set as a slice, in the order they
+// would be evicted in.
+func (set *BoundedSet) ToSlice() []interface{} {
+	set.Lock()
+	defer set.Unlock()
+	s := make([]interface{}, 0, set.order.Len())
+	for e := set.order.Front(); e != nil; e = e.Next() {
+		s = append(s, e.Value)
+	}
+	return s
+}
+
+// ToSet returns a thread-safe snapshot of the elements in the set.
+func (set *BoundedSet) ToSet() Set {
+	set.Lock()
+	defer set.Unlock()
+	unsafeClone := set.unsafeSet.Clone().(*ThreadUnsafeSet)
+	return &ThreadSafeSet{unsafeSet: *unsafeClone}
+}
+
+// Clear removes all elements from the set.
+func (set *BoundedSet) Clear() {
+	set.Lock()
+	defer set.Unlock()
+	set.unsafeSet.Clear()
+	set.elems = listIndex{}
+	set.order.Init()
+}
+
+// String provides a convenient string representation
+// of the current state of the set.
+func (set *BoundedSet) String() string {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.String()
+}
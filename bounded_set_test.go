@@ -0,0 +1,78 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "testing"
+
+func Test_BoundedSet(t *testing.T) {
+	lru := NewBoundedSet(2, EvictLRU, "a", "b")
+	lru.Contains("a")
+	if added, evicted, ok := lru.AddEvict("c"); !added || !ok || evicted != "b" {
+		t.Errorf("Expected c to evict the least recently used b, got %v", evicted)
+	}
+	if !lru.Contains("a", "c") || lru.Size() != 2 {
+		t.Errorf("Expected a and c, got %v", lru.ToSlice())
+	}
+
+	fifo := NewBoundedSet(2, EvictFIFO, "a", "b")
+	fifo.Contains("a")
+	fifo.Add("a")
+	if _, evicted, _ := fifo.AddEvict("c"); evicted != "a" {
+		t.Errorf("Expected c to evict the first added a, got %v", evicted)
+	}
+
+	reject := NewBoundedSet(2, RejectNew, "a", "b")
+	if added, _, ok := reject.AddEvict("c"); added || ok || reject.Contains("c") {
+		t.Errorf("Expected c to be rejected")
+	}
+	reject.Remove("a")
+	if !reject.Add("c") || reject.Size() != 2 {
+		t.Errorf("Expected c to fit once a is removed, got %v", reject.ToSlice())
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected a panic for an element of the wrong type")
+			}
+		}()
+		lru.Add(1)
+	}()
+	if lru.Size() != 2 {
+		t.Errorf("Expected no eviction for an element of the wrong type, got %v", lru.ToSlice())
+	}
+
+	// Each doesn't hold the lock while calling f.
+	lru.Each(func(elem interface{}) bool {
+		lru.Remove(elem)
+		return false
+	})
+	if lru.Size() != 0 {
+		t.Errorf("Expected Each to remove every element, got %v", lru.ToSlice())
+	}
+}
+
+func Test_BoundedSetHashCollisions(t *testing.T) {
+	s := NewBoundedSet(2, EvictFIFO, parityInt(1), parityInt(3))
+	if s.Size() != 2 || !s.Contains(parityInt(1), parityInt(3)) || s.Contains(parityInt(5)) {
+		t.Errorf("Expected colliding elements to be kept apart, got %v", s.ToSlice())
+	}
+	if _, evicted, _ := s.AddEvict(parityInt(5)); evicted != parityInt(1) {
+		t.Errorf("Expected parityInt(5) to evict parityInt(1), got %v", evicted)
+	}
+	s.Remove(parityInt(3))
+	if s.Size() != 1 || !s.Contains(parityInt(5)) {
+		t.Errorf("Expected only parityInt(5) to remain, got %v", s.ToSlice())
+	}
+}
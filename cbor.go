@@ -0,0 +1,373 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"time"
+)
+
+// CBOR (RFC 8949) major types and tags used to encode sets.
+const (
+	cborUint   = 0 << 5
+	cborNegInt = 1 << 5
+	cborBytes  = 2 << 5
+	cborText   = 3 << 5
+	cborArray  = 4 << 5
+	cborTag    = 6 << 5
+	cborSimple = 7 << 5
+
+	cborTagTime = 0   // RFC 3339 date/time string
+	cborTagUUID = 37  // 16-byte UUID
+	cborTagSet  = 258 // Array of unique elements
+)
+
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+// MarshalCBOR encodes the set as a CBOR array tagged as a set (tag
+// 258). Elements of integer kinds, floats, strings, []byte, bools,
+// time.Time, net.IP and [16]byte UUIDs can be encoded, elements of other
+// types make MarshalCBOR fail.
+func (set *ThreadUnsafeSet) MarshalCBOR() ([]byte, error) {
+	b := cborHead(nil, cborTag, cborTagSet)
+	b = cborHead(b, cborArray, uint64(set.Size()))
+	var err error
+	set.store.each(func(obj interface{}) bool {
+		b, err = cborAppend(b, obj)
+		return err != nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// UnmarshalCBOR adds the elements of the CBOR array b, tagged as a set
+// or not, to the set. Integers are decoded as int64 and floats with the
+// precision they were encoded with, unless the set holds elements of
+// another numeric type already, which they are then converted to. A
+// zero set becomes a set with the default storage.
+func (set *ThreadUnsafeSet) UnmarshalCBOR(b []byte) error {
+	d := cborDecoder{b: b}
+	major, n, err := d.head()
+	if err != nil {
+		return err
+	}
+	if major == cborTag && n == cborTagSet {
+		if major, n, err = d.head(); err != nil {
+			return err
+		}
+	}
+	if major != cborArray {
+		return fmt.Errorf("cbor: expected an array of set elements, got major type %d", major>>5)
+	}
+	if set.store == nil {
+		*set = newThreadUnsafeSet()
+	}
+	for i := uint64(0); i < n; i++ {
+		elem, err := d.value()
+		if err != nil {
+			return err
+		}
+		if elem, err = set.convert(elem); err != nil {
+			return err
+		}
+		if _, err := set.TryAdd(elem); err != nil {
+			return err
+		}
+	}
+	if len(d.b) > 0 {
+		return fmt.Errorf("cbor: %d bytes of trailing data", len(d.b))
+	}
+	return nil
+}
+
+// convert converts a decoded number to the numeric element type of the
+// set, if it has one. It returns a *TypeMismatchError if the number
+// doesn't fit the type, or is not integral for an integer type.
+func (set *ThreadUnsafeSet) convert(elem interface{}) (interface{}, error) {
+	v := reflect.ValueOf(elem)
+	if set.typ == nil || v.Type() == set.typ || !isNumber(v) || !isNumber(reflect.Zero(set.typ)) {
+		return elem, nil
+	}
+	if !convertible(v, set.typ) {
+		return nil, &TypeMismatchError{Want: set.typ, Got: v.Type(),
+			msg: fmt.Sprintf("%v can't be converted to %s without changing its value", elem, set.typ)}
+	}
+	return v.Convert(set.typ).Interface(), nil
+}
+
+// convertible reports whether the number v keeps its value when
+// converted to the numeric type typ.
+func convertible(v reflect.Value, typ reflect.Type) bool {
+	to := reflect.Zero(typ)
+	switch kindClass(v) {
+	case reflect.Int:
+		i := v.Int()
+		switch kindClass(to) {
+		case reflect.Int:
+			return !to.OverflowInt(i)
+		case reflect.Uint:
+			return i >= 0 && !to.OverflowUint(uint64(i))
+		}
+	case reflect.Uint:
+		u := v.Uint()
+		switch kindClass(to) {
+		case reflect.Int:
+			return u <= math.MaxInt64 && !to.OverflowInt(int64(u))
+		case reflect.Uint:
+			return !to.OverflowUint(u)
+		}
+	case reflect.Float64:
+		f := v.Float()
+		switch kindClass(to) {
+		case reflect.Int:
+			// -2^63 is an int64, 2^63 is not.
+			return f == math.Trunc(f) && f >= -(1<<63) && f < 1<<63 && !to.OverflowInt(int64(f))
+		case reflect.Uint:
+			return f == math.Trunc(f) && f >= 0 && f < 1<<64 && !to.OverflowUint(uint64(f))
+		case reflect.Float64:
+			return !to.OverflowFloat(f)
+		}
+	}
+	// Integers become the nearest float.
+	return true
+}
+
+func isNumber(v reflect.Value) bool {
+	switch kindClass(v) {
+	case reflect.Int, reflect.Uint, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// MarshalCBOR encodes the set as a CBOR array, see
+// ThreadUnsafeSet.MarshalCBOR.
+func (set *ThreadSafeSet) MarshalCBOR() ([]byte, error) {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.MarshalCBOR()
+}
+
+// UnmarshalCBOR adds the elements of the CBOR array b to the set, see
+// ThreadUnsafeSet.UnmarshalCBOR.
+func (set *ThreadSafeSet) UnmarshalCBOR(b []byte) error {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.UnmarshalCBOR(b)
+}
+
+// cborHead appends the head of a data item of the given major type and
+// argument to b, in its shortest form.
+func cborHead(b []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return append(b, major|25, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		return append(b, major|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	b = append(b, major|27)
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], n)
+	return append(b, buf[:]...)
+}
+
+func cborInt(b []byte, i int64) []byte {
+	if i < 0 {
+		return cborHead(b, cborNegInt, uint64(-(i + 1)))
+	}
+	return cborHead(b, cborUint, uint64(i))
+}
+
+// cborAppend appends the CBOR encoding of obj to b.
+func cborAppend(b []byte, obj interface{}) ([]byte, error) {
+	switch o := obj.(type) {
+	case bool:
+		if o {
+			return append(b, cborSimple|21), nil
+		}
+		return append(b, cborSimple|20), nil
+	case string:
+		return append(cborHead(b, cborText, uint64(len(o))), o...), nil
+	case []byte:
+		return append(cborHead(b, cborBytes, uint64(len(o))), o...), nil
+	case net.IP:
+		return append(cborHead(b, cborBytes, uint64(len(o))), o...), nil
+	case [16]byte:
+		b = cborHead(b, cborTag, cborTagUUID)
+		return append(cborHead(b, cborBytes, 16), o[:]...), nil
+	case time.Time:
+		s := o.Format(time.RFC3339Nano)
+		b = cborHead(b, cborTag, cborTagTime)
+		return append(cborHead(b, cborText, uint64(len(s))), s...), nil
+	case float32:
+		b = append(b, cborSimple|26)
+		var buf [4]byte
+		binary.BigEndian.PutUint32(buf[:], math.Float32bits(o))
+		return append(b, buf[:]...), nil
+	case float64:
+		b = append(b, cborSimple|27)
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], math.Float64bits(o))
+		return append(b, buf[:]...), nil
+	}
+	switch v := reflect.ValueOf(obj); kindClass(v) {
+	case reflect.Int:
+		return cborInt(b, v.Int()), nil
+	case reflect.Uint:
+		return cborHead(b, cborUint, v.Uint()), nil
+	}
+	return nil, fmt.Errorf("cbor: %T can't be encoded", obj)
+}
+
+// cborDecoder decodes the data items at the start of b.
+type cborDecoder struct {
+	b []byte
+}
+
+// head decodes the head of a data item, returning its major type and
+// argument. For floats and simple values, the argument is the raw
+// value.
+func (d *cborDecoder) head() (byte, uint64, error) {
+	if len(d.b) == 0 {
+		return 0, 0, errCBORTruncated
+	}
+	major, info := d.b[0]&0xe0, d.b[0]&0x1f
+	d.b = d.b[1:]
+	if info < 24 {
+		return major, uint64(info), nil
+	}
+	if info > 27 {
+		return 0, 0, fmt.Errorf("cbor: indefinite lengths and reserved values are not supported")
+	}
+	size := 1 << (info - 24)
+	if len(d.b) < size {
+		return 0, 0, errCBORTruncated
+	}
+	var n uint64
+	for _, c := range d.b[:size] {
+		n = n<<8 | uint64(c)
+	}
+	d.b = d.b[size:]
+	return major, n, nil
+}
+
+// bytes decodes the n bytes of a byte or text string.
+func (d *cborDecoder) bytes(n uint64) ([]byte, error) {
+	if uint64(len(d.b)) < n {
+		return nil, errCBORTruncated
+	}
+	b := d.b[:n:n]
+	d.b = d.b[n:]
+	return b, nil
+}
+
+// value decodes a data item that can be a set element.
+func (d *cborDecoder) value() (interface{}, error) {
+	raw := d.b
+	major, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUint:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case cborNegInt:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: -1-%d overflows int64", n)
+		}
+		return -1 - int64(n), nil
+	case cborBytes:
+		b, err := d.bytes(n)
+		return append([]byte(nil), b...), err
+	case cborText:
+		b, err := d.bytes(n)
+		return string(b), err
+	case cborTag:
+		return d.tagged(n)
+	case cborSimple:
+		switch raw[0] & 0x1f {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 25:
+			return halfToFloat32(uint16(n)), nil
+		case 26:
+			return math.Float32frombits(uint32(n)), nil
+		case 27:
+			return math.Float64frombits(n), nil
+		}
+	}
+	return nil, fmt.Errorf("cbor: data item 0x%02x can't be a set element", raw[0])
+}
+
+// tagged decodes the data item following a tag.
+func (d *cborDecoder) tagged(tag uint64) (interface{}, error) {
+	major, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case tag == cborTagTime && major == cborText:
+		b, err := d.bytes(n)
+		if err != nil {
+			return nil, err
+		}
+		return time.Parse(time.RFC3339Nano, string(b))
+	case tag == cborTagUUID && major == cborBytes && n == 16:
+		b, err := d.bytes(n)
+		if err != nil {
+			return nil, err
+		}
+		var uuid [16]byte
+		copy(uuid[:], b)
+		return uuid, nil
+	}
+	return nil, fmt.Errorf("cbor: tag %d can't be a set element", tag)
+}
+
+// halfToFloat32 converts an IEEE 754 half-precision float, which CBOR
+// encoders may use for floats that fit, to a float32.
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+	switch exp {
+	case 0:
+		// Subnormal, or zero.
+		f := float32(frac) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	case 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | frac<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | frac<<13)
+}
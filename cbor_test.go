@@ -0,0 +1,79 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func Test_CBOR(t *testing.T) {
+	b, err := NewThreadUnsafeSet(-1).(*ThreadUnsafeSet).MarshalCBOR()
+	if expected := []byte{0xd9, 0x01, 0x02, 0x81, 0x20}; err != nil || !bytes.Equal(b, expected) {
+		t.Errorf("Expected %x, got %x, %v", expected, b, err)
+	}
+
+	for _, s := range []Set{
+		NewSet(int64(1), int64(-300), int64(1)<<40),
+		NewSet(float32(1.5), float32(-2)),
+		NewThreadUnsafeSet("a", "b"),
+		NewSet(true, false),
+		NewSet([]byte{1, 2}),
+		NewSet(time.Date(2023, 5, 1, 12, 0, 0, 123, time.UTC)),
+		NewSet([16]byte{1, 2, 3}),
+	} {
+		b, err := s.(interface{ MarshalCBOR() ([]byte, error) }).MarshalCBOR()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded ThreadSafeSet
+		if err := decoded.UnmarshalCBOR(b); err != nil || !decoded.Equal(s) {
+			t.Errorf("Expected %v to round-trip, got %v, %v", s, &decoded, err)
+		}
+	}
+
+	ints := NewSet(1).(*ThreadSafeSet)
+	// An untagged array of 2, -1 and a half-precision 1.0.
+	if err := ints.UnmarshalCBOR([]byte{0x83, 0x02, 0x20, 0xf9, 0x3c, 0x00}); err != nil || !ints.Equal(NewSet(1, 2, -1)) {
+		t.Errorf("Expected the numbers to be converted to ints, got %v, %v", ints, err)
+	}
+	if err := ints.UnmarshalCBOR([]byte{0x81, 0x61, 'a'}); err == nil {
+		t.Errorf("Expected an error decoding a string into a set of ints")
+	}
+	bytesSet := NewSet(uint8(1)).(*ThreadSafeSet)
+	for _, b := range [][]byte{
+		{0x81, 0x19, 0x01, 0x2c}, // 300
+		{0x81, 0x20},             // -1
+		{0x81, 0xf9, 0x3e, 0x00}, // 1.5
+	} {
+		if err := bytesSet.UnmarshalCBOR(b); !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("Expected a type mismatch decoding %x into a set of uint8, got %v", b, err)
+		}
+	}
+	if !bytesSet.Equal(NewSet(uint8(1))) {
+		t.Errorf("Expected no element to be added, got %v", bytesSet)
+	}
+	if err := ints.UnmarshalCBOR([]byte{0x82, 0x01}); err == nil {
+		t.Errorf("Expected an error for truncated data")
+	}
+	if _, err := NewSet(net.IP{1}, net.IP{2}).(*ThreadSafeSet).MarshalCBOR(); err != nil {
+		t.Errorf("Expected IPs to be encoded, got %v", err)
+	}
+	if _, err := NewSet(foldedWords{"a"}).(*ThreadSafeSet).MarshalCBOR(); err == nil {
+		t.Errorf("Expected an error encoding a custom type")
+	}
+}
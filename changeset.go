@@ -0,0 +1,162 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ChangeSet is a change to a set: elements to add and elements to
+// remove. It is made from the two sets Diff returns,
+//
+//	cs := goset.NewChangeSet(actual.Diff(desired))
+//	actual.Apply(cs)
+//
+// and can be sent to other processes to replicate the change, encoded
+// by MarshalBinary, which keeps the types of the elements.
+type ChangeSet struct {
+	Adds    Set
+	Removes Set
+}
+
+// NewChangeSet returns the change adding adds and removing removes, of
+// which nil means no elements.
+func NewChangeSet(adds, removes Set) ChangeSet {
+	if adds == nil {
+		adds = NewSet()
+	}
+	if removes == nil {
+		removes = NewSet()
+	}
+	return ChangeSet{Adds: adds, Removes: removes}
+}
+
+// IsEmpty reports whether the change neither adds nor removes elements.
+func (cs ChangeSet) IsEmpty() bool {
+	return (cs.Adds == nil || cs.Adds.Size() == 0) && (cs.Removes == nil || cs.Removes.Size() == 0)
+}
+
+// String returns the change as +{ added } -{ removed }.
+func (cs ChangeSet) String() string {
+	return fmt.Sprintf("+%v -%v", cs.Adds, cs.Removes)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, writing the adds
+// and the removes like Save.
+func (cs ChangeSet) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	for _, s := range []Set{cs.Adds, cs.Removes} {
+		if s == nil {
+			s = NewSet()
+		}
+		if err := s.Save(&buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing cs
+// with the change encoded by MarshalBinary.
+func (cs *ChangeSet) UnmarshalBinary(b []byte) error {
+	r := bytes.NewReader(b)
+	adds, err := Load(r)
+	if err != nil {
+		return err
+	}
+	removes, err := Load(r)
+	if err != nil {
+		return err
+	}
+	*cs = ChangeSet{Adds: adds, Removes: removes}
+	return nil
+}
+
+// Apply removes the removes of cs from the set and adds its adds, so
+// that an element in both is in the set afterwards. If an element can't
+// be added or removed, it undoes the change and panics, the Try methods
+// tell which elements can be stored.
+func (set *ThreadUnsafeSet) Apply(cs ChangeSet) {
+	if err := set.apply(cs); err != nil {
+		panic(err)
+	}
+}
+
+// apply applies cs, or undoes it and returns an error.
+func (set *ThreadUnsafeSet) apply(cs ChangeSet) error {
+	return set.applyElems(changeElems(cs))
+}
+
+// changeElems returns the elements of the adds and the removes of cs.
+func changeElems(cs ChangeSet) (adds, removes []interface{}) {
+	if cs.Adds != nil {
+		adds = cs.Adds.ToSlice()
+	}
+	if cs.Removes != nil {
+		removes = cs.Removes.ToSlice()
+	}
+	return adds, removes
+}
+
+// applyElems removes removes from the set and adds adds, or undoes the
+// change and returns an error.
+func (set *ThreadUnsafeSet) applyElems(adds, removes []interface{}) error {
+	var removed, added []interface{}
+	var err error
+	for _, elem := range removes {
+		stored, ok := set.store.get(elem)
+		if !ok {
+			continue
+		}
+		if _, err = set.store.remove(elem); err != nil {
+			break
+		}
+		removed = append(removed, stored)
+	}
+	if err == nil {
+		for _, elem := range adds {
+			var ok bool
+			if ok, err = set.TryAdd(elem); err != nil {
+				break
+			}
+			if ok {
+				added = append(added, elem)
+			}
+		}
+	}
+	if err != nil {
+		for _, elem := range added {
+			set.store.remove(elem)
+		}
+		for _, elem := range removed {
+			set.store.add(elem)
+		}
+	}
+	return err
+}
+
+// Apply applies cs to the set under one lock, so that no one sees the
+// set partially changed, see ThreadUnsafeSet.Apply. The elements of cs
+// are copied before the set is locked, so cs may hold the set itself or
+// sets that are being changed by Apply.
+func (set *ThreadSafeSet) Apply(cs ChangeSet) {
+	adds, removes := changeElems(cs)
+	set.Lock()
+	err := set.unsafeSet.applyElems(adds, removes)
+	set.Unlock()
+	if err != nil {
+		panic(err)
+	}
+}
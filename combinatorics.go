@@ -0,0 +1,174 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PowerSetLimit is the largest set PowerSet accepts, as the power set of
+// a set of n elements has 2^n elements. Use PowerSetIterator to visit the
+// subsets of larger sets lazily.
+const PowerSetLimit = 20
+
+// Pair is an element of the set returned by CartesianProduct.
+type Pair struct {
+	First  interface{}
+	Second interface{}
+}
+
+// Hash implements Hashable, pairs are equal if both of their elements
+// are equal.
+func (p Pair) Hash() string {
+	first, second := elemKey(p.First), elemKey(p.Second)
+	return strconv.Itoa(len(first)) + ":" + first + ";" + second
+}
+
+// elemKey returns the hash of elem prefixed with its type, so that
+// elements of different types with the same hash, like 1 and "1", get
+// different keys.
+func elemKey(elem interface{}) string {
+	return fmt.Sprintf("%T=", elem) + sortKey(elem)
+}
+
+// CartesianProduct returns a new set with a Pair for every combination
+// of an element of a and an element of b.
+//
+// The result is thread-unsafe if a is a ThreadUnsafeSet, and thread-safe
+// otherwise.
+func CartesianProduct(a, b Set) Set {
+	firsts, seconds := a.ToSlice(), b.ToSlice()
+	product := newThreadUnsafeSet()
+	product.Grow(len(firsts) * len(seconds))
+	for _, first := range firsts {
+		for _, second := range seconds {
+			product.Add(Pair{First: first, Second: second})
+		}
+	}
+	return resultFor(a, &product)
+}
+
+// setHash returns a hash of the given elements that does not depend on
+// their order, so that equal sets have equal hashes.
+func setHash(elems []interface{}) string {
+	hashes := make([]string, 0, len(elems))
+	for _, elem := range elems {
+		hashes = append(hashes, elemKey(elem))
+	}
+	sort.Strings(hashes)
+
+	// Prefix every hash with its length so that hashes containing the
+	// separator can't be confused.
+	var builder strings.Builder
+	builder.WriteString("{")
+	for _, hash := range hashes {
+		builder.WriteString(strconv.Itoa(len(hash)))
+		builder.WriteString(":")
+		builder.WriteString(hash)
+		builder.WriteString(";")
+	}
+	builder.WriteString("}")
+	return builder.String()
+}
+
+// powerSet adds all subsets of elems, made by newSubset, to ret.
+func powerSet(ret Set, elems []interface{}, newSubset func() Set) {
+	if len(elems) > PowerSetLimit {
+		panic(fmt.Errorf("power set of %d elements exceeds the limit of %d elements, use PowerSetIterator instead",
+			len(elems), PowerSetLimit))
+	}
+	it := powerSetIterator(elems, newSubset)
+	for subset := range it.C {
+		ret.Add(subset)
+	}
+}
+
+// powerSetIterator returns an Iterator over all subsets of elems, which
+// are made by newSubset. Subsets are built one at a time, as they are
+// received.
+func powerSetIterator(elems []interface{}, newSubset func() Set) *Iterator {
+	if len(elems) >= 64 {
+		panic(fmt.Errorf("power set of %d elements can't be enumerated", len(elems)))
+	}
+	iterator, ch, stopCh := newIterator()
+
+	go func() {
+	L:
+		for mask := uint64(0); mask < 1<<uint(len(elems)); mask++ {
+			subset := newSubset()
+			for i, elem := range elems {
+				if mask&(1<<uint(i)) != 0 {
+					subset.Add(elem)
+				}
+			}
+			select {
+			case <-stopCh:
+				break L
+			case ch <- subset:
+			}
+		}
+		close(ch)
+	}()
+	return iterator
+}
+
+// combinationsIterator returns an Iterator over all subsets of elems with
+// k elements, which are made by newSubset. Subsets are built one at a
+// time, as they are received.
+func combinationsIterator(elems []interface{}, k int, newSubset func() Set) *Iterator {
+	iterator, ch, stopCh := newIterator()
+
+	go func() {
+		defer close(ch)
+		n := len(elems)
+		if k < 0 || k > n {
+			return
+		}
+
+		// idx holds the positions of the elements of the current
+		// combination, in increasing order.
+		idx := make([]int, k)
+		for i := range idx {
+			idx[i] = i
+		}
+		for {
+			subset := newSubset()
+			for _, i := range idx {
+				subset.Add(elems[i])
+			}
+			select {
+			case <-stopCh:
+				return
+			case ch <- subset:
+			}
+
+			// Advance the rightmost position that can still move.
+			i := k - 1
+			for i >= 0 && idx[i] == n-k+i {
+				i--
+			}
+			if i < 0 {
+				return
+			}
+			idx[i]++
+			for j := i + 1; j < k; j++ {
+				idx[j] = idx[j-1] + 1
+			}
+		}
+	}()
+	return iterator
+}
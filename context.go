@@ -0,0 +1,129 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// checkEvery is the number of elements an operation processes between
+// two checks of its context.
+const checkEvery = 1024
+
+// OperationError is returned by the context-aware operations when they
+// are aborted because their context is done. The operands of an aborted
+// operation are left untouched.
+type OperationError struct {
+	Op  string // The aborted operation, e.g. "union"
+	Err error  // The error of the context
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("%s aborted: %v", e.Op, e.Err)
+}
+
+// Unwrap returns the error of the context.
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// Progress describes how far a long running operation has come.
+type Progress struct {
+	Op        string        // The running operation, e.g. "union"
+	Processed int           // Number of elements processed so far
+	Total     int           // Number of elements to process, 0 if unknown
+	Elapsed   time.Duration // Time since the operation started
+	Done      bool          // Whether the operation has finished
+}
+
+type progressKey struct{}
+
+type progressHook struct {
+	every int
+	fn    func(Progress)
+}
+
+// WithProgress returns a copy of ctx that makes the context-aware
+// operations it is passed to call fn after every `every` processed
+// elements, and once more when they finish. fn is called synchronously
+// from the running operation, possibly while sets are locked, so it
+// should return quickly and must not use the sets involved.
+func WithProgress(ctx context.Context, every int, fn func(Progress)) context.Context {
+	if every < 1 {
+		every = checkEvery
+	}
+	return context.WithValue(ctx, progressKey{}, &progressHook{every: every, fn: fn})
+}
+
+// opTracker cooperatively checks the context of a long running operation
+// and reports its progress.
+type opTracker struct {
+	ctx   context.Context
+	op    string
+	n     int
+	total int
+	start time.Time
+	hook  *progressHook
+}
+
+func newOpTracker(ctx context.Context, op string, total int) *opTracker {
+	hook, _ := ctx.Value(progressKey{}).(*progressHook)
+	t := &opTracker{ctx: ctx, op: op, total: total, hook: hook}
+	if hook != nil {
+		t.start = time.Now()
+	}
+	return t
+}
+
+// step records that one more element was processed and returns an
+// *OperationError once the context is done.
+func (t *opTracker) step() error {
+	t.n++
+	if t.hook != nil && t.n%t.hook.every == 0 {
+		t.report(false)
+	}
+	if t.n%checkEvery != 0 {
+		return nil
+	}
+	return t.check()
+}
+
+// finish reports the completion of the operation.
+func (t *opTracker) finish() {
+	if t.hook != nil {
+		t.report(true)
+	}
+}
+
+func (t *opTracker) report(done bool) {
+	t.hook.fn(Progress{
+		Op:        t.op,
+		Processed: t.n,
+		Total:     t.total,
+		Elapsed:   time.Since(t.start),
+		Done:      done,
+	})
+}
+
+// check returns an *OperationError if the context is done.
+func (t *opTracker) check() error {
+	select {
+	case <-t.ctx.Done():
+		return &OperationError{Op: t.op, Err: t.ctx.Err()}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "sync/atomic"
+
+// cowStore lets clones of a ThreadSafeSet share one store until either
+// of them is modified, at which point the modified set copies the store
+// and stops sharing it. This makes Clone O(1) no matter the size of the
+// set.
+//
+// Sets sharing a store are guarded by different locks, so the shared
+// store is only ever read: a set that finds it is not the only user of
+// its shared store makes its own copy before it makes any change.
+type cowStore struct {
+	shared *sharedStore
+}
+
+type sharedStore struct {
+	s    store
+	refs int32 // number of cowStores using s
+}
+
+// cowClone returns a set sharing the store of set, making the store
+// shareable first if necessary. The caller must hold the write lock.
+//
+// An externalStore is copied instead, as set would copy it into memory
+// on its next change and stop using it.
+func (set *ThreadSafeSet) cowClone() ThreadUnsafeSet {
+	if _, ok := set.unsafeSet.store.(externalStore); ok {
+		return *set.unsafeSet.Clone().(*ThreadUnsafeSet)
+	}
+	c, ok := set.unsafeSet.store.(*cowStore)
+	if !ok {
+		c = &cowStore{shared: &sharedStore{s: set.unsafeSet.store, refs: 1}}
+		set.unsafeSet.store = c
+	}
+	atomic.AddInt32(&c.shared.refs, 1)
+	return ThreadUnsafeSet{store: &cowStore{shared: c.shared}, typ: set.unsafeSet.typ, conf: set.unsafeSet.conf}
+}
+
+// unwrapStore returns the store behind s, which may be shared with other
+// sets unless exclusive is set. The stores of WithLog, OnAdd and Watch,
+// which must see every change, are only looked through if exclusive is
+// not set.
+func unwrapStore(s store, exclusive bool) store {
+	if !exclusive {
+		switch w := s.(type) {
+		case *hookStore:
+			return unwrapStore(w.store, false)
+		case *logStore:
+			return unwrapStore(w.store, false)
+		}
+	}
+	c, ok := s.(*cowStore)
+	if !ok {
+		return s
+	}
+	if exclusive {
+		return c.own()
+	}
+	return c.shared.s
+}
+
+// own returns the store of c after copying it if it is shared.
+func (c *cowStore) own() store {
+	old := c.shared
+	if atomic.LoadInt32(&old.refs) == 1 {
+		return old.s
+	}
+	s := old.s.empty(old.s.len())
+	old.s.each(func(val interface{}) bool {
+		s.add(val)
+		return false
+	})
+	c.shared = &sharedStore{s: s, refs: 1}
+	atomic.AddInt32(&old.refs, -1)
+	return s
+}
+
+func (c *cowStore) add(val interface{}) (bool, error) {
+	// Avoid copying the store for elements that are present already.
+	if _, ok := c.shared.s.get(val); ok {
+		return false, nil
+	}
+	return c.own().add(val)
+}
+
+func (c *cowStore) get(val interface{}) (interface{}, bool) {
+	return c.shared.s.get(val)
+}
+
+func (c *cowStore) remove(val interface{}) (bool, error) {
+	if _, ok := c.shared.s.get(val); !ok {
+		return false, nil
+	}
+	return c.own().remove(val)
+}
+
+func (c *cowStore) len() int {
+	return c.shared.s.len()
+}
+
+func (c *cowStore) each(f func(val interface{}) bool) {
+	c.shared.s.each(f)
+}
+
+func (c *cowStore) clear() {
+	old := c.shared
+	if atomic.LoadInt32(&old.refs) == 1 {
+		old.s.clear()
+		return
+	}
+	c.shared = &sharedStore{s: old.s.empty(0), refs: 1}
+	atomic.AddInt32(&old.refs, -1)
+}
+
+func (c *cowStore) grow(n int) {
+	c.own().grow(n)
+}
+
+func (c *cowStore) empty(capacity int) store {
+	return c.shared.s.empty(capacity)
+}
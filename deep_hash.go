@@ -0,0 +1,139 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WithDeepHashing lets the set hold slices, maps, structs and other
+// values that are not Hashable, by identifying elements with DeepHash.
+// It costs a reflective walk over each element on every operation, so
+// it is opt-in.
+func WithDeepHashing() Option {
+	return WithHasher(HasherFunc(DeepHash))
+}
+
+// DeepHash returns a hash of the contents of v: values of the same type
+// that are deeply equal, like reflect.DeepEqual reports, have the same
+// hash. Pointers are followed, maps are hashed independently of their
+// iteration order, and Hashable values are hashed by their Hash method.
+// Channels and funcs are hashed by identity.
+func DeepHash(v interface{}) string {
+	var b strings.Builder
+	deepHash(&b, reflect.ValueOf(v), map[uintptr]bool{})
+	return b.String()
+}
+
+var hashableType = reflect.TypeOf((*Hashable)(nil)).Elem()
+
+// deepHash writes the type and contents of v to b. visited holds the
+// pointers on the current path, to cut cycles short.
+func deepHash(b *strings.Builder, v reflect.Value, visited map[uintptr]bool) {
+	if !v.IsValid() {
+		b.WriteString("nil")
+		return
+	}
+	b.WriteString(v.Type().String())
+	if v.Type().Implements(hashableType) && v.CanInterface() {
+		if v.Kind() != reflect.Ptr || !v.IsNil() {
+			writeString(b, v.Interface().(Hashable).Hash())
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		b.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		b.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	case reflect.Complex64, reflect.Complex128:
+		b.WriteString(strconv.FormatComplex(v.Complex(), 'g', -1, 128))
+	case reflect.String:
+		writeString(b, v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		b.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			deepHash(b, v.Index(i), visited)
+			b.WriteString(",")
+		}
+		b.WriteString("]")
+	case reflect.Map:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		entries := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			var entry strings.Builder
+			deepHash(&entry, key, visited)
+			entry.WriteString(":")
+			deepHash(&entry, v.MapIndex(key), visited)
+			entries = append(entries, entry.String())
+		}
+		sort.Strings(entries)
+		b.WriteString("{")
+		for _, entry := range entries {
+			writeString(b, entry)
+		}
+		b.WriteString("}")
+	case reflect.Struct:
+		b.WriteString("{")
+		for i := 0; i < v.NumField(); i++ {
+			b.WriteString(v.Type().Field(i).Name)
+			b.WriteString(":")
+			deepHash(b, v.Field(i), visited)
+			b.WriteString(",")
+		}
+		b.WriteString("}")
+	case reflect.Ptr:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		if visited[v.Pointer()] {
+			b.WriteString("cycle")
+			return
+		}
+		visited[v.Pointer()] = true
+		b.WriteString("&")
+		deepHash(b, v.Elem(), visited)
+		delete(visited, v.Pointer())
+	case reflect.Interface:
+		deepHash(b, v.Elem(), visited)
+	default:
+		// Channels, funcs and unsafe pointers only equal themselves.
+		b.WriteString("@")
+		b.WriteString(strconv.FormatUint(uint64(v.Pointer()), 16))
+	}
+}
+
+// writeString writes s prefixed with its length, so that strings
+// containing separators can't be confused.
+func writeString(b *strings.Builder, s string) {
+	b.WriteString(strconv.Itoa(len(s)))
+	b.WriteString(":")
+	b.WriteString(s)
+}
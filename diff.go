@@ -0,0 +1,41 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+// Diff returns the elements of other that are not in the set, which
+// must be added to turn the set into other, and the elements of the set
+// that are not in other, which must be removed.
+func (set *ThreadUnsafeSet) Diff(other Set) (added Set, removed Set) {
+	o := set.unsafeOf(other)
+	a := set.emptyLike(0)
+	o.store.each(func(obj interface{}) bool {
+		if !set.Contains(obj) {
+			a.Add(obj)
+		}
+		return false
+	})
+	return &a, set.Difference(o)
+}
+
+// Diff returns the elements that must be added to and removed from the
+// set to turn it into other, see ThreadUnsafeSet.Diff. Both sets are
+// locked once, so the two sets are consistent with each other.
+func (set *ThreadSafeSet) Diff(other Set) (added Set, removed Set) {
+	o := set.threadSafeOf(other)
+
+	unlock := set.rlockWith(o)
+	a, r := set.unsafeSet.Diff(&o.unsafeSet)
+	unlock()
+	return a.(*ThreadUnsafeSet).ToThreadSafe(), r.(*ThreadUnsafeSet).ToThreadSafe()
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	// ErrNotHashable matches the errors for elements that can't be
+	// stored in a set at all, because they can't be hashed or don't
+	// fit the storage of the set, like negative ints in a bit set.
+	ErrNotHashable = errors.New("element can't be stored in a set")
+
+	// ErrTypeMismatch matches the errors for elements of a different
+	// type than the elements of a set.
+	ErrTypeMismatch = errors.New("element type doesn't match the set")
+
+	// ErrIncompatibleSet matches the errors for operations on two sets
+	// that can't be combined.
+	ErrIncompatibleSet = errors.New("sets can't be combined")
+
+	// ErrReadOnly is the error for changes to a read-only set, like a
+	// MappedSet.
+	ErrReadOnly = errors.New("set is read-only")
+)
+
+// NotHashableError is the error for an element that can't be stored in
+// a set. It matches ErrNotHashable.
+type NotHashableError struct {
+	Elem interface{} // The element that can't be stored
+	msg  string
+}
+
+func (e *NotHashableError) Error() string {
+	if e.msg != "" {
+		return e.msg
+	}
+	return fmt.Sprintf("%T is not a hashable object, can't calculate its hash", e.Elem)
+}
+
+// Is reports whether target is ErrNotHashable.
+func (e *NotHashableError) Is(target error) bool {
+	return target == ErrNotHashable
+}
+
+// notStorable returns a *NotHashableError for elem with a message
+// formatted like fmt.Sprintf.
+func notStorable(elem interface{}, format string, args ...interface{}) error {
+	return &NotHashableError{Elem: elem, msg: fmt.Sprintf(format, args...)}
+}
+
+// TypeMismatchError is the error for an element whose type doesn't
+// match the type of the elements of a set. It matches ErrTypeMismatch.
+type TypeMismatchError struct {
+	Want reflect.Type // The type of the elements of the set, if there is one
+	Got  reflect.Type // The type of the offending element
+	msg  string
+}
+
+func (e *TypeMismatchError) Error() string {
+	if e.msg != "" {
+		return e.msg
+	}
+	return fmt.Sprintf("type conflict when you add a new element to set (type of set elem: %s, type of new elem %s)",
+		e.Want, e.Got)
+}
+
+// Is reports whether target is ErrTypeMismatch.
+func (e *TypeMismatchError) Is(target error) bool {
+	return target == ErrTypeMismatch
+}
+
+// wrongType returns a *TypeMismatchError for val, which can't be stored
+// in a set holding elements of another type, with a message formatted
+// like fmt.Sprintf.
+func wrongType(val interface{}, format string, args ...interface{}) error {
+	return &TypeMismatchError{Got: reflect.TypeOf(val), msg: fmt.Sprintf(format, args...)}
+}
+
+// IncompatibleSetError is the error for an operation on two sets that
+// can't be combined, because they are of different types. It matches
+// ErrIncompatibleSet.
+type IncompatibleSetError struct {
+	Set, Other Set
+}
+
+func (e *IncompatibleSetError) Error() string {
+	return fmt.Sprintf("a %T can't be combined with a %T", e.Set, e.Other)
+}
+
+// Is reports whether target is ErrIncompatibleSet.
+func (e *IncompatibleSetError) Is(target error) bool {
+	return target == ErrIncompatibleSet
+}
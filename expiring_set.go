@@ -0,0 +1,256 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// ExpiringSet is a thread-safe set whose elements expire after a time to
+// live, e.g. to remember the recently seen elements of a stream:
+//
+//	seen := goset.NewExpiringSet(time.Hour)
+//	go seen.RunReaper(ctx, time.Minute)
+//	if seen.Add(id) {
+//		// first time id is seen in the last hour
+//	}
+//
+// Expired elements are no longer in the set as far as its methods are
+// concerned, and are removed by the next method that iterates the set,
+// by Reap, or by RunReaper.
+type ExpiringSet struct {
+	sync.RWMutex
+	unsafeSet ThreadUnsafeSet
+	expiry    map[string]time.Time // Store {$hash: $expiry} of elem, none if it never expires
+	queue     expiryQueue
+	ttl       time.Duration
+	now       func() time.Time
+}
+
+// NewExpiringSet creates and returns a new expiring set with the given
+// elements. Elements added by Add live for ttl, or never expire if ttl
+// is zero.
+func NewExpiringSet(ttl time.Duration, vals ...interface{}) *ExpiringSet {
+	set := &ExpiringSet{unsafeSet: newThreadUnsafeSet(), expiry: map[string]time.Time{}, ttl: ttl, now: time.Now}
+	for _, item := range vals {
+		set.Add(item)
+	}
+	return set
+}
+
+// Add adds an element to the set, to live for the ttl of the set, see
+// AddWithTTL.
+func (set *ExpiringSet) Add(val interface{}) bool {
+	return set.AddWithTTL(val, set.ttl)
+}
+
+// AddWithTTL adds an element to the set, which expires after ttl, or
+// never if ttl is zero or negative. Adding an element that is already
+// present sets its new time to live. Returns whether the item was newly
+// added, or had expired.
+func (set *ExpiringSet) AddWithTTL(val interface{}, ttl time.Duration) bool {
+	hash, err := calcHash(val)
+	if err != nil {
+		panic(err)
+	}
+	set.Lock()
+	defer set.Unlock()
+	now := set.now()
+	live := set.unsafeSet.Contains(val) && !set.expired(hash, now)
+	set.unsafeSet.Add(val)
+	if ttl <= 0 {
+		delete(set.expiry, hash)
+		return !live
+	}
+	at := now.Add(ttl)
+	set.expiry[hash] = at
+	heap.Push(&set.queue, expiryEntry{at: at, hash: hash, val: val})
+	return !live
+}
+
+// expired returns whether the element with the given hash has expired by
+// now. The caller must hold the lock.
+func (set *ExpiringSet) expired(hash string, now time.Time) bool {
+	at, ok := set.expiry[hash]
+	return ok && !now.Before(at)
+}
+
+// TTL returns how long the element has left to live, or false if it is
+// not in the set. An element that never expires has a negative TTL.
+func (set *ExpiringSet) TTL(val interface{}) (time.Duration, bool) {
+	hash, err := calcHash(val)
+	if err != nil {
+		return 0, false
+	}
+	set.RLock()
+	defer set.RUnlock()
+	now := set.now()
+	if !set.unsafeSet.Contains(val) || set.expired(hash, now) {
+		return 0, false
+	}
+	at, ok := set.expiry[hash]
+	if !ok {
+		return -1, true
+	}
+	return at.Sub(now), true
+}
+
+// Contains returns whether the given items
+// are all in the set and not expired.
+func (set *ExpiringSet) Contains(val ...interface{}) bool {
+	set.RLock()
+	defer set.RUnlock()
+	now := set.now()
+	for _, v := range val {
+		hash, err := calcHash(v)
+		if err != nil || !set.unsafeSet.Contains(v) || set.expired(hash, now) {
+			return false
+		}
+	}
+	return true
+}
+
+// Remove remove a single element from the set.
+func (set *ExpiringSet) Remove(i interface{}) {
+	hash, err := calcHash(i)
+	if err != nil {
+		panic(err)
+	}
+	set.Lock()
+	set.unsafeSet.Remove(i)
+	delete(set.expiry, hash)
+	set.Unlock()
+}
+
+// Reap removes the expired elements from the set and returns them.
+func (set *ExpiringSet) Reap() []interface{} {
+	set.Lock()
+	defer set.Unlock()
+	return set.reap()
+}
+
+// reap is Reap for a caller holding the lock.
+func (set *ExpiringSet) reap() []interface{} {
+	now := set.now()
+	var reaped []interface{}
+	for len(set.queue) > 0 && !now.Before(set.queue[0].at) {
+		e := heap.Pop(&set.queue).(expiryEntry)
+		// Entries of elements added again, or removed, are stale.
+		if at, ok := set.expiry[e.hash]; ok && at.Equal(e.at) {
+			set.unsafeSet.Remove(e.val)
+			delete(set.expiry, e.hash)
+			reaped = append(reaped, e.val)
+		}
+	}
+	return reaped
+}
+
+// RunReaper reaps the expired elements every interval, until ctx is
+// done. It is meant to run in its own goroutine, so that expired elements
+// don't pile up in a set that is seldom iterated.
+func (set *ExpiringSet) RunReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			set.Reap()
+		}
+	}
+}
+
+// Size Returns the number of elements in the set that have not expired.
+func (set *ExpiringSet) Size() int {
+	set.Lock()
+	defer set.Unlock()
+	set.reap()
+	return set.unsafeSet.Size()
+}
+
+// Each iterates over a snapshot of the elements that have not expired,
+// see ToSlice, and executes the passed func against each element. The
+// set is not locked while f runs, so f may use the set. If passed func
+// returns true, stop iteration at the time.
+func (set *ExpiringSet) Each(f func(elem interface{}) bool) {
+	for _, elem := range set.ToSlice() {
+		if f(elem) {
+			break
+		}
+	}
+}
+
+// ToSlice returns the members of the set that have not expired as a
+// slice.
+func (set *ExpiringSet) ToSlice() []interface{} {
+	set.Lock()
+	defer set.Unlock()
+	set.reap()
+	return set.unsafeSet.ToSlice()
+}
+
+// ToSet returns a thread-safe snapshot of the elements in the set that
+// have not expired.
+func (set *ExpiringSet) ToSet() Set {
+	set.Lock()
+	defer set.Unlock()
+	set.reap()
+	unsafeClone := set.unsafeSet.Clone().(*ThreadUnsafeSet)
+	return &ThreadSafeSet{unsafeSet: *unsafeClone}
+}
+
+// Clear removes all elements from the set.
+func (set *ExpiringSet) Clear() {
+	set.Lock()
+	defer set.Unlock()
+	set.unsafeSet.Clear()
+	set.expiry = map[string]time.Time{}
+	set.queue = nil
+}
+
+// String provides a convenient string representation
+// of the current state of the set.
+func (set *ExpiringSet) String() string {
+	set.Lock()
+	defer set.Unlock()
+	set.reap()
+	return set.unsafeSet.String()
+}
+
+// expiryEntry is the expiry of an element in an expiryQueue.
+type expiryEntry struct {
+	at   time.Time
+	hash string
+	val  interface{}
+}
+
+// expiryQueue is a heap of expiries, the earliest first.
+type expiryQueue []expiryEntry
+
+func (q expiryQueue) Len() int            { return len(q) }
+func (q expiryQueue) Less(i, j int) bool  { return q[i].at.Before(q[j].at) }
+func (q expiryQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *expiryQueue) Push(x interface{}) { *q = append(*q, x.(expiryEntry)) }
+
+func (q *expiryQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = expiryEntry{}
+	*q = old[:len(old)-1]
+	return e
+}
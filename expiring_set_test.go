@@ -0,0 +1,68 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_ExpiringSet(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewExpiringSet(time.Minute)
+	s.now = func() time.Time { return now }
+
+	s.Add("a")
+	s.AddWithTTL("b", time.Hour)
+	s.AddWithTTL("c", 0)
+	if !s.Contains("a", "b", "c") || s.Size() != 3 {
+		t.Errorf("Expected a, b and c, got %v", s)
+	}
+
+	now = now.Add(time.Minute)
+	if s.Contains("a") {
+		t.Errorf("Expected a to have expired")
+	}
+	if ttl, ok := s.TTL("b"); !ok || ttl != 59*time.Minute {
+		t.Errorf("Expected b to live 59 more minutes, got %v", ttl)
+	}
+	if ttl, ok := s.TTL("c"); !ok || ttl >= 0 {
+		t.Errorf("Expected c never to expire, got %v", ttl)
+	}
+	if s.Size() != 2 {
+		t.Errorf("Expected b and c, got %v", s)
+	}
+	if !s.Add("a") {
+		t.Errorf("Expected an expired element to be added anew")
+	}
+
+	// Adding b again postpones its expiry.
+	s.AddWithTTL("b", 2*time.Hour)
+	now = now.Add(time.Hour)
+	if reaped := s.Reap(); len(reaped) != 1 || reaped[0] != "a" {
+		t.Errorf("Expected a to be reaped, got %v", reaped)
+	}
+	if !s.Contains("b", "c") || s.Size() != 2 {
+		t.Errorf("Expected b and c to live on, got %v", s)
+	}
+
+	// Each doesn't hold the lock while calling f.
+	s.Each(func(elem interface{}) bool {
+		s.Remove(elem)
+		return false
+	})
+	if s.Size() != 0 {
+		t.Errorf("Expected Each to remove every element, got %v", s)
+	}
+}
@@ -0,0 +1,476 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package extsort computes the union, intersection and differences of
+// sets too large to be held in memory. The elements, which are byte
+// strings like IDs or keys, are read from streams, sorted into runs on
+// disk within a memory budget, and merged, so the result is written in
+// sorted order and without duplicates.
+//
+//	a := extsort.NewLineReader(oldIDs)
+//	b := extsort.NewLineReader(newIDs)
+//	err := extsort.Difference(a, b, func(id []byte) error {
+//		_, err := fmt.Fprintf(out, "%s\n", id)
+//		return err
+//	}, nil)
+package extsort
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// Reader yields the elements of a set, in any order and possibly with
+// duplicates. Next returns io.EOF after the last element. The returned
+// slice is only used until the next call.
+type Reader interface {
+	Next() ([]byte, error)
+}
+
+// ReaderFunc is a Reader that calls itself.
+type ReaderFunc func() ([]byte, error)
+
+// Next returns f().
+func (f ReaderFunc) Next() ([]byte, error) {
+	return f()
+}
+
+// NewLineReader returns a Reader over the lines of r, which are the
+// elements, without their line endings. Empty lines are skipped.
+func NewLineReader(r io.Reader) Reader {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	return ReaderFunc(func() ([]byte, error) {
+		for s.Scan() {
+			if line := bytes.TrimSuffix(s.Bytes(), []byte("\r")); len(line) > 0 {
+				return line, nil
+			}
+		}
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	})
+}
+
+// Config configures the sorting of the inputs. The zero value, or a nil
+// *Config, uses the defaults.
+type Config struct {
+	// TempDir is the directory the sorted runs are written to, the
+	// default directory for temporary files if empty.
+	TempDir string
+	// MemoryLimit is the number of bytes of elements held in memory
+	// while sorting a run, 64 MiB if zero. The elements are counted
+	// with an overhead for their slice headers.
+	MemoryLimit int
+	// MaxOpenRuns is the number of runs merged at once, 256 if zero.
+	// More runs are merged in several passes.
+	MaxOpenRuns int
+}
+
+const (
+	defaultMemoryLimit = 64 << 20
+	defaultMaxOpenRuns = 256
+	elemOverhead       = 24 // Size of a slice header
+)
+
+func (c *Config) memoryLimit() int {
+	if c == nil || c.MemoryLimit <= 0 {
+		return defaultMemoryLimit
+	}
+	return c.MemoryLimit
+}
+
+func (c *Config) maxOpenRuns() int {
+	if c == nil || c.MaxOpenRuns < 2 {
+		return defaultMaxOpenRuns
+	}
+	return c.MaxOpenRuns
+}
+
+func (c *Config) tempDir() string {
+	if c == nil {
+		return ""
+	}
+	return c.TempDir
+}
+
+// Distinct writes the elements of r to emit, sorted and without
+// duplicates.
+func Distinct(r Reader, emit func(elem []byte) error, cfg *Config) error {
+	s, err := sortedStream(r, cfg)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+	for {
+		elem, err := s.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := emit(elem); err != nil {
+			return err
+		}
+	}
+}
+
+// Union writes the elements that are in a or b to emit, sorted and
+// without duplicates.
+func Union(a, b Reader, emit func(elem []byte) error, cfg *Config) error {
+	return join(a, b, emit, cfg, true, true, true)
+}
+
+// Intersect writes the elements that are in both a and b to emit,
+// sorted and without duplicates.
+func Intersect(a, b Reader, emit func(elem []byte) error, cfg *Config) error {
+	return join(a, b, emit, cfg, false, true, false)
+}
+
+// Difference writes the elements that are in a but not in b to emit,
+// sorted and without duplicates.
+func Difference(a, b Reader, emit func(elem []byte) error, cfg *Config) error {
+	return join(a, b, emit, cfg, true, false, false)
+}
+
+// SymmetricDifference writes the elements that are in either a or b but
+// not in both to emit, sorted and without duplicates.
+func SymmetricDifference(a, b Reader, emit func(elem []byte) error, cfg *Config) error {
+	return join(a, b, emit, cfg, true, false, true)
+}
+
+// join merges the sorted streams of a and b, writing the elements only
+// in a, in both, and only in b to emit as onlyA, both and onlyB say.
+func join(a, b Reader, emit func(elem []byte) error, cfg *Config, onlyA, both, onlyB bool) error {
+	sa, err := sortedStream(a, cfg)
+	if err != nil {
+		return err
+	}
+	defer sa.close()
+	sb, err := sortedStream(b, cfg)
+	if err != nil {
+		return err
+	}
+	defer sb.close()
+
+	x, errA := sa.Next()
+	y, errB := sb.Next()
+	for {
+		if errA != nil && errA != io.EOF {
+			return errA
+		}
+		if errB != nil && errB != io.EOF {
+			return errB
+		}
+		if errA == io.EOF && errB == io.EOF {
+			return nil
+		}
+		c := 0
+		switch {
+		case errA == io.EOF:
+			c = 1
+		case errB == io.EOF:
+			c = -1
+		default:
+			c = bytes.Compare(x, y)
+		}
+		switch {
+		case c < 0:
+			if onlyA {
+				err = emit(x)
+			}
+			x, errA = sa.Next()
+		case c > 0:
+			if onlyB {
+				err = emit(y)
+			}
+			y, errB = sb.Next()
+		default:
+			if both {
+				err = emit(x)
+			}
+			x, errA = sa.Next()
+			y, errB = sb.Next()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// stream is a sorted stream of distinct elements.
+type stream interface {
+	Reader
+	close()
+}
+
+// sliceStream streams a sorted slice.
+type sliceStream [][]byte
+
+func (s *sliceStream) Next() ([]byte, error) {
+	if len(*s) == 0 {
+		return nil, io.EOF
+	}
+	elem := (*s)[0]
+	*s = (*s)[1:]
+	return elem, nil
+}
+
+func (s *sliceStream) close() {}
+
+// sortedStream returns the elements of r as a sorted stream of distinct
+// elements. Inputs that fit into the memory limit are sorted in memory,
+// larger ones are sorted into runs on disk, which the stream merges.
+func sortedStream(r Reader, cfg *Config) (stream, error) {
+	var runs []string
+	removeRuns := func() {
+		for _, run := range runs {
+			os.Remove(run)
+		}
+	}
+	for {
+		elems, eof, err := readRun(r, cfg.memoryLimit())
+		if err != nil {
+			removeRuns()
+			return nil, err
+		}
+		if eof && runs == nil {
+			s := sliceStream(elems)
+			return &s, nil
+		}
+		if len(elems) > 0 {
+			run, err := writeRun(cfg.tempDir(), (*sliceStream)(&elems))
+			if err != nil {
+				removeRuns()
+				return nil, err
+			}
+			runs = append(runs, run)
+		}
+		if eof {
+			break
+		}
+	}
+
+	// Merge the runs in passes until they can be merged at once.
+	for len(runs) > cfg.maxOpenRuns() {
+		var merged []string
+		for i := 0; i < len(runs); i += cfg.maxOpenRuns() {
+			group := runs[i:min(i+cfg.maxOpenRuns(), len(runs))]
+			m, err := openMerge(group)
+			if err != nil {
+				runs = append(merged, runs[i:]...)
+				removeRuns()
+				return nil, err
+			}
+			run, err := writeRun(cfg.tempDir(), m)
+			m.close()
+			if err != nil {
+				runs = append(merged, runs[i:]...)
+				removeRuns()
+				return nil, err
+			}
+			merged = append(merged, run)
+		}
+		runs = merged
+	}
+	m, err := openMerge(runs)
+	if err != nil {
+		removeRuns()
+		return nil, err
+	}
+	return m, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// readRun reads elements from r until they fill limit bytes, and returns
+// them sorted and without duplicates, and whether r is exhausted.
+func readRun(r Reader, limit int) (elems [][]byte, eof bool, err error) {
+	size := 0
+	for size < limit {
+		elem, err := r.Next()
+		if err == io.EOF {
+			eof = true
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		elems = append(elems, append([]byte(nil), elem...))
+		size += len(elem) + elemOverhead
+	}
+	sort.Slice(elems, func(i, j int) bool {
+		return bytes.Compare(elems[i], elems[j]) < 0
+	})
+	distinct := elems[:0]
+	for i, elem := range elems {
+		if i == 0 || !bytes.Equal(elem, elems[i-1]) {
+			distinct = append(distinct, elem)
+		}
+	}
+	return distinct, eof, nil
+}
+
+// writeRun writes the elements of s to a new temporary file in dir, each
+// prefixed with its length, and returns the path of the file.
+func writeRun(dir string, s Reader) (string, error) {
+	f, err := ioutil.TempFile(dir, "extsort-run-")
+	if err != nil {
+		return "", err
+	}
+	w := bufio.NewWriter(f)
+	var buf [binary.MaxVarintLen64]byte
+	for {
+		elem, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(elem)))])
+			_, err = w.Write(elem)
+		}
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return "", err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+var errCorruptRun = errors.New("extsort: corrupt run file")
+
+// runReader reads a run written by writeRun.
+type runReader struct {
+	f    *os.File
+	r    *bufio.Reader
+	elem []byte
+}
+
+func (r *runReader) next() error {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errCorruptRun
+		}
+		return err
+	}
+	if uint64(cap(r.elem)) < n {
+		r.elem = make([]byte, n)
+	}
+	r.elem = r.elem[:n]
+	if _, err := io.ReadFull(r.r, r.elem); err != nil {
+		return errCorruptRun
+	}
+	return nil
+}
+
+// merge is a stream merging runs, which it removes when it is closed.
+type merge struct {
+	runs []*runReader // a heap of the runs that are not exhausted
+	all  []*runReader
+	last []byte
+	any  bool // whether last is set
+	err  error
+}
+
+func openMerge(paths []string) (*merge, error) {
+	m := &merge{}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			m.close()
+			return nil, err
+		}
+		r := &runReader{f: f, r: bufio.NewReader(f)}
+		m.all = append(m.all, r)
+		if err := r.next(); err == nil {
+			m.runs = append(m.runs, r)
+		} else if err != io.EOF {
+			m.close()
+			return nil, err
+		}
+	}
+	heap.Init(m)
+	return m, nil
+}
+
+func (m *merge) Len() int           { return len(m.runs) }
+func (m *merge) Less(i, j int) bool { return bytes.Compare(m.runs[i].elem, m.runs[j].elem) < 0 }
+func (m *merge) Swap(i, j int)      { m.runs[i], m.runs[j] = m.runs[j], m.runs[i] }
+func (m *merge) Push(x interface{}) { m.runs = append(m.runs, x.(*runReader)) }
+func (m *merge) Pop() interface{} {
+	r := m.runs[len(m.runs)-1]
+	m.runs = m.runs[:len(m.runs)-1]
+	return r
+}
+
+// Next returns the smallest element of the runs greater than the last
+// one it returned.
+func (m *merge) Next() ([]byte, error) {
+	for m.err == nil && len(m.runs) > 0 {
+		r := m.runs[0]
+		// The buffer of the run is reused by next, so keep a copy.
+		fresh := !m.any || !bytes.Equal(r.elem, m.last)
+		if fresh {
+			m.last = append(m.last[:0], r.elem...)
+			m.any = true
+		}
+		if err := r.next(); err == io.EOF {
+			heap.Pop(m)
+		} else if err != nil {
+			m.err = err
+		} else {
+			heap.Fix(m, 0)
+		}
+		if fresh {
+			return m.last, nil
+		}
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	return nil, io.EOF
+}
+
+func (m *merge) close() {
+	for _, r := range m.all {
+		r.f.Close()
+		os.Remove(r.f.Name())
+	}
+	m.all, m.runs = nil, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package extsort
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ids returns a Reader over the decimal numbers from start to end in
+// steps of step, each twice.
+func ids(start, end, step int) Reader {
+	i, twice := start, false
+	return ReaderFunc(func() ([]byte, error) {
+		if i >= end {
+			return nil, io.EOF
+		}
+		elem := []byte(fmt.Sprintf("%06d", i))
+		if twice {
+			i += step
+		}
+		twice = !twice
+		return elem, nil
+	})
+}
+
+func collect(op func(a, b Reader, emit func([]byte) error, cfg *Config) error, a, b Reader, cfg *Config) ([]string, error) {
+	var out []string
+	err := op(a, b, func(elem []byte) error {
+		out = append(out, string(elem))
+		return nil
+	}, cfg)
+	return out, err
+}
+
+func Test_Operations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extsort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Small runs, merged in several passes.
+	spilling := &Config{TempDir: dir, MemoryLimit: 500, MaxOpenRuns: 3}
+	for _, cfg := range []*Config{nil, spilling} {
+		// Multiples of 2 and 3 below 1000.
+		union, err := collect(Union, ids(0, 1000, 2), ids(0, 1000, 3), cfg)
+		if err != nil || len(union) != 667 || union[0] != "000000" || union[666] != "000999" {
+			t.Errorf("Unexpected union of %d elements, %v", len(union), err)
+		}
+		inter, err := collect(Intersect, ids(0, 1000, 2), ids(0, 1000, 3), cfg)
+		if err != nil || len(inter) != 167 || inter[1] != "000006" {
+			t.Errorf("Unexpected intersection of %d elements, %v", len(inter), err)
+		}
+		diff, err := collect(Difference, ids(0, 1000, 2), ids(0, 1000, 3), cfg)
+		if err != nil || len(diff) != 333 || diff[0] != "000002" {
+			t.Errorf("Unexpected difference of %d elements, %v", len(diff), err)
+		}
+		sym, err := collect(SymmetricDifference, ids(0, 1000, 2), ids(0, 1000, 3), cfg)
+		if err != nil || len(sym) != 500 {
+			t.Errorf("Unexpected symmetric difference of %d elements, %v", len(sym), err)
+		}
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Expected the runs to be removed, got %d files", len(files))
+	}
+}
+
+func Test_LineReader(t *testing.T) {
+	var out []string
+	err := Distinct(NewLineReader(strings.NewReader("b\r\na\n\nb\n")), func(elem []byte) error {
+		out = append(out, string(elem))
+		return nil
+	}, nil)
+	if err != nil || !reflect.DeepEqual(out, []string{"a", "b"}) {
+		t.Errorf("Expected [a b], got %v, %v", out, err)
+	}
+
+	failing := ReaderFunc(func() ([]byte, error) { return nil, errors.New("broken") })
+	if _, err := collect(Union, ids(0, 10, 1), failing, nil); err == nil {
+		t.Errorf("Expected errors of the inputs to be returned")
+	}
+}
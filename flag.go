@@ -0,0 +1,85 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"encoding"
+	"fmt"
+	"strings"
+)
+
+// FlagValue is a flag.Value, and a pflag.Value, that adds the values of
+// a command line flag to a set, so that a flag can be repeated or take
+// a comma-separated list, or both:
+//
+//	tags := goset.NewSet()
+//	flag.Var(goset.NewFlagValue(tags), "tag", "tags to apply, repeatable")
+//	// -tag a,b -tag a makes tags {a, b}
+//
+// The values are parsed as the element type of the set by its
+// UnmarshalText method, see ThreadUnsafeSet.UnmarshalText, or added as
+// strings for other Set implementations.
+type FlagValue struct {
+	set Set
+}
+
+// NewFlagValue returns a FlagValue adding to set.
+func NewFlagValue(set Set) *FlagValue {
+	return &FlagValue{set: set}
+}
+
+// Elems returns the set the flag adds to.
+func (f *FlagValue) Elems() Set {
+	return f.set
+}
+
+// Set adds the values of s to the set. They are separated by commas, or
+// by the separator given to WithTextSeparator when the set was created.
+func (f *FlagValue) Set(s string) error {
+	if u, ok := f.set.(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	for _, item := range strings.Split(s, defaultTextSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			if _, err := f.set.TryAdd(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// String returns the sorted, comma-separated elements of the set.
+func (f *FlagValue) String() string {
+	// The flag package calls String on a zero FlagValue to tell
+	// whether a default is set.
+	if f == nil || f.set == nil {
+		return ""
+	}
+	if m, ok := f.set.(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	items := make([]string, 0, f.set.Size())
+	for _, elem := range f.set.ToSortedSlice(nil) {
+		items = append(items, fmt.Sprint(elem))
+	}
+	return strings.Join(items, defaultTextSeparator)
+}
+
+// Type returns the name of the flag type for pflag's usage messages.
+func (f *FlagValue) Type() string {
+	return "set"
+}
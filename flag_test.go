@@ -0,0 +1,38 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"flag"
+	"testing"
+)
+
+func Test_FlagValue(t *testing.T) {
+	tags, ports := NewSet(), NewThreadUnsafeSet(uint16(80))
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(NewFlagValue(tags), "tag", "")
+	fs.Var(NewFlagValue(ports), "port", "")
+	if err := fs.Parse([]string{"-tag", "a,b", "-tag", "a", "-port", "443", "-port", "8080,443"}); err != nil {
+		t.Fatal(err)
+	}
+	if !tags.Equal(NewSet("a", "b")) || !ports.Equal(NewThreadUnsafeSet(uint16(80), uint16(443), uint16(8080))) {
+		t.Errorf("Expected the flags to add to the sets, got %v and %v", tags, ports)
+	}
+	if s := fs.Lookup("port").Value.String(); s != "80,443,8080" {
+		t.Errorf("Expected 80,443,8080, got %v", s)
+	}
+	if err := fs.Parse([]string{"-port", "http"}); err == nil {
+		t.Errorf("Expected an error for a port that's not a number")
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"math"
+	"strconv"
+)
+
+// FloatMode decides which float32, float64, complex64 and complex128
+// elements of a set are the same element.
+type FloatMode int
+
+const (
+	// FloatsByValue is the default mode, in which floats are the same
+	// element if they are equal by ==, so 0 and -0 are one element,
+	// except that NaN is a single element equal to itself, so that it
+	// can be found and removed once added. NaNs with different payloads
+	// are the same element.
+	FloatsByValue FloatMode = iota
+
+	// FloatsByBits identifies floats by their IEEE 754 bit patterns, so
+	// 0 and -0 are different elements, as are NaNs with different
+	// payloads, while each NaN is equal to itself.
+	FloatsByBits
+)
+
+// WithFloatMode sets how the set identifies float and complex elements,
+// see FloatMode.
+func WithFloatMode(mode FloatMode) Option {
+	return func(opts *setOptions) {
+		opts.newStore = func() store { return &hashStore{floats: mode} }
+	}
+}
+
+// formatFloat returns the shortest string that identifies f exactly, in
+// FloatsByValue mode: -0 is formatted as 0 and every NaN as NaN.
+func formatFloat(f float64, bitSize int) string {
+	if f == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+// formatComplex is formatFloat for complex numbers.
+func formatComplex(c complex128, bitSize int) string {
+	return formatFloat(real(c), bitSize/2) + "," + formatFloat(imag(c), bitSize/2)
+}
+
+// floatBits returns the hash of val in FloatsByBits mode, or false if
+// val is not a float or complex number.
+func floatBits(val interface{}) (string, bool) {
+	switch v := val.(type) {
+	case float32:
+		return strconv.FormatUint(uint64(math.Float32bits(v)), 16), true
+	case float64:
+		return strconv.FormatUint(math.Float64bits(v), 16), true
+	case complex64:
+		return strconv.FormatUint(uint64(math.Float32bits(real(v))), 16) + "," +
+			strconv.FormatUint(uint64(math.Float32bits(imag(v))), 16), true
+	case complex128:
+		return strconv.FormatUint(math.Float64bits(real(v)), 16) + "," +
+			strconv.FormatUint(math.Float64bits(imag(v)), 16), true
+	default:
+		return "", false
+	}
+}
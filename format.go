@@ -0,0 +1,175 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// formatLimit is the number of elements the verbs of Format print before
+// the rest of the set is summed up by their count.
+const formatLimit = 100
+
+// SetStringConfig configures how String and Format render a set, see
+// WithStringConfig. The zero value renders the elements separated by
+// ", " with nothing around them.
+type SetStringConfig struct {
+	// Prefix and Suffix are written before and after the elements.
+	Prefix, Suffix string
+	// Separator is written between elements, ", " if empty.
+	Separator string
+	// MaxElems is the number of elements printed before the rest of the
+	// set is summed up by their count. If it is zero, String prints all
+	// elements and Format prints 100 of them.
+	MaxElems int
+	// FormatElem formats the elements, fmt.Sprint if nil. Format only
+	// uses it for %v, %+v and %s.
+	FormatElem func(elem interface{}) string
+}
+
+// WithStringConfig sets how String and Format render the set and the
+// sets derived from it, instead of the default goset.ThreadUnsafeSet{ a, b }.
+//
+//	set := goset.NewSet(goset.WithStringConfig(goset.SetStringConfig{Prefix: "[", Suffix: "]", Separator: " "}))
+func WithStringConfig(c SetStringConfig) Option {
+	return func(opts *setOptions) {
+		opts.conf.str = &c
+	}
+}
+
+// stringConfig returns the configuration of the rendering of the set,
+// which by default is name{ a, b, c }.
+func (set *ThreadUnsafeSet) stringConfig(name string) SetStringConfig {
+	if set.conf.str != nil {
+		return *set.conf.str
+	}
+	return SetStringConfig{Prefix: name + "{ ", Suffix: " }"}
+}
+
+// writeSet writes the elements each visits, formatted by format, as
+// configured by c. Beyond limit elements, the remaining ones are only
+// counted, unless limit is negative.
+func writeSet(w io.Writer, c SetStringConfig, size, limit int, each func(f func(elem interface{}) bool), format func(elem interface{}) string) {
+	sep := c.Separator
+	if sep == "" {
+		sep = ", "
+	}
+	io.WriteString(w, c.Prefix)
+	n := 0
+	each(func(elem interface{}) bool {
+		if n == limit {
+			return true
+		}
+		if n > 0 {
+			io.WriteString(w, sep)
+		}
+		io.WriteString(w, format(elem))
+		n++
+		return false
+	})
+	if n < size {
+		if n > 0 {
+			io.WriteString(w, sep)
+		}
+		fmt.Fprintf(w, "… +%d more", size-n)
+	}
+	io.WriteString(w, c.Suffix)
+}
+
+// elemFormatter returns the function formatting elements as configured
+// by c, which is fmt.Sprint by default.
+func elemFormatter(c SetStringConfig) func(elem interface{}) string {
+	if c.FormatElem != nil {
+		return c.FormatElem
+	}
+	return func(elem interface{}) string {
+		return fmt.Sprint(elem)
+	}
+}
+
+// formatSet implements fmt.Formatter for a set rendered as configured by
+// c. At most c.MaxElems elements, or formatLimit if it is zero, or as
+// many as the precision, like in %.10v, are printed. %+v prints the
+// elements sorted like ToSortedSlice(nil).
+func formatSet(f fmt.State, verb rune, c SetStringConfig, size int, each func(f func(elem interface{}) bool), sorted func() []interface{}) {
+	limit := formatLimit
+	if c.MaxElems > 0 {
+		limit = c.MaxElems
+	}
+	if p, ok := f.Precision(); ok {
+		limit = p
+	}
+	format := elemFormatter(c)
+	switch {
+	case verb == 's':
+	case verb == 'v' && f.Flag('#'):
+		format = func(elem interface{}) string {
+			return fmt.Sprintf("%#v", elem)
+		}
+	case verb == 'v' && f.Flag('+'):
+		elems := sorted()
+		each = func(f func(elem interface{}) bool) {
+			for _, elem := range elems {
+				if f(elem) {
+					return
+				}
+			}
+		}
+	case verb != 'v':
+		format = func(elem interface{}) string {
+			return fmt.Sprintf("%"+string(verb), elem)
+		}
+	}
+	writeSet(f, c, size, limit, each, format)
+}
+
+// Format implements fmt.Formatter. %v prints the set like String, but
+// truncated after 100 elements, or as many as the precision, like in
+// %.10v. %+v prints the elements sorted like ToSortedSlice(nil). %s is
+// the same as %v, other verbs are applied to each element.
+func (set *ThreadUnsafeSet) Format(f fmt.State, verb rune) {
+	formatSet(f, verb, set.stringConfig("goset.ThreadUnsafeSet"), set.Size(), set.store.each, func() []interface{} {
+		return set.ToSortedSlice(nil)
+	})
+}
+
+// Format implements fmt.Formatter, see ThreadUnsafeSet.Format.
+func (set *ThreadSafeSet) Format(f fmt.State, verb rune) {
+	set.RLock()
+	defer set.RUnlock()
+	set.unsafeSet.Format(f, verb)
+}
+
+// Format implements fmt.Formatter, see ThreadUnsafeSet.Format.
+func (set FrozenSet) Format(f fmt.State, verb rune) {
+	s := set.elems()
+	formatSet(f, verb, s.stringConfig("goset.FrozenSet"), s.Size(), s.store.each, func() []interface{} {
+		return s.ToSortedSlice(nil)
+	})
+}
+
+// setString returns the rendering of the elements of set, as configured
+// by its SetStringConfig, under the given name by default.
+func setString(set *ThreadUnsafeSet, name string) string {
+	c := set.stringConfig(name)
+	limit := -1
+	if c.MaxElems > 0 {
+		limit = c.MaxElems
+	}
+	var b strings.Builder
+	writeSet(&b, c, set.Size(), limit, set.store.each, elemFormatter(c))
+	return b.String()
+}
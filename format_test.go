@@ -0,0 +1,66 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_Format(t *testing.T) {
+	s := NewThreadUnsafeSet(3, 1, 2)
+	if got := fmt.Sprintf("%v", NewSet(1)); got != NewSet(1).String() {
+		t.Errorf("Expected %%v to print like String, got %s", got)
+	}
+	if got := fmt.Sprintf("%+v", NewSet(3, 1, 2)); got != "goset.ThreadUnsafeSet{ 1, 2, 3 }" {
+		t.Errorf("Expected %%+v to print sorted elements, got %s", got)
+	}
+	if got := fmt.Sprintf("%+.2v", s); got != "goset.ThreadUnsafeSet{ 1, 2, … +1 more }" {
+		t.Errorf("Expected the precision to truncate the set, got %s", got)
+	}
+	if got := fmt.Sprintf("%#v", NewFrozenSet("a")); got != `goset.FrozenSet{ "a" }` {
+		t.Errorf("Expected %%#v to be applied to the elements, got %s", got)
+	}
+
+	large := NewThreadUnsafeSet()
+	for i := 0; i < 10000; i++ {
+		large.Add(i)
+	}
+	if got := fmt.Sprint(large); !strings.HasSuffix(got, ", … +9900 more }") {
+		t.Errorf("Expected large sets to be truncated, got %s", got)
+	}
+	if got := large.String(); strings.Contains(got, "more") {
+		t.Errorf("Expected String not to truncate")
+	}
+}
+
+func Test_WithStringConfig(t *testing.T) {
+	s := NewSet(WithStringConfig(SetStringConfig{
+		Prefix:     "[",
+		Suffix:     "]",
+		Separator:  " ",
+		MaxElems:   2,
+		FormatElem: func(elem interface{}) string { return fmt.Sprintf("#%v", elem) },
+	}), 3, 1, 2)
+	if got := fmt.Sprintf("%+v", s); got != "[#1 #2 … +1 more]" {
+		t.Errorf("Expected Format to follow the config, got %s", got)
+	}
+	if got := s.Difference(NewSet(3)).String(); got != "[#1 #2]" && got != "[#2 #1]" {
+		t.Errorf("Expected derived sets to keep the config, got %s", got)
+	}
+	if got := fmt.Sprintf("%+.0v", s); got != "[… +3 more]" {
+		t.Errorf("Expected the precision to override MaxElems, got %s", got)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "sync"
+
+// FrozenSet is an immutable set. Since it never changes, it needs no
+// locking and can be shared freely between goroutines. Operations that
+// would modify a set return a new FrozenSet instead.
+//
+// Frozen sets are Hashable, so they can be elements of other sets, and
+// their Hash can be used as a map key. The zero value is the empty set.
+type FrozenSet struct {
+	f *frozenSet
+}
+
+type frozenSet struct {
+	unsafeSet ThreadUnsafeSet
+	hashOnce  sync.Once
+	hash      string
+}
+
+// NewFrozenSet creates and returns a new immutable set with the given
+// elements.
+func NewFrozenSet(vals ...interface{}) FrozenSet {
+	s := newThreadUnsafeSet()
+	for _, item := range vals {
+		s.Add(item)
+	}
+	return freeze(s)
+}
+
+// freeze returns a FrozenSet that takes ownership of s.
+func freeze(s ThreadUnsafeSet) FrozenSet {
+	return FrozenSet{&frozenSet{unsafeSet: s}}
+}
+
+// elems returns the elements of the set, which must not be modified.
+func (set FrozenSet) elems() *ThreadUnsafeSet {
+	if set.f == nil {
+		s := newThreadUnsafeSet()
+		return &s
+	}
+	return &set.f.unsafeSet
+}
+
+// Contains returns whether the given items
+// are all in the set.
+func (set FrozenSet) Contains(val ...interface{}) bool {
+	return set.elems().Contains(val...)
+}
+
+// Cardinality returns how many items are currently in the set.
+func (set FrozenSet) Cardinality() int {
+	return set.elems().Cardinality()
+}
+
+// Size Returns the number of elements in the set.
+func (set FrozenSet) Size() int {
+	return set.elems().Size()
+}
+
+// Each iterates over elements and executes the passed func against each element.
+// If passed func returns true, stop iteration at the time.
+func (set FrozenSet) Each(f func(elem interface{}) bool) {
+	set.elems().Each(f)
+}
+
+// Iter returns a channel of elements that you can
+// range over.
+func (set FrozenSet) Iter() <-chan interface{} {
+	return set.elems().Iter()
+}
+
+// Iterator returns an Iterator object that you can
+// use to range over the set.
+func (set FrozenSet) Iterator() *Iterator {
+	return set.elems().Iterator()
+}
+
+// Cursor returns a pull-style Cursor over the set.
+func (set FrozenSet) Cursor() *Cursor {
+	return set.elems().Cursor()
+}
+
+// ToSlice returns the members of the set as a slice.
+func (set FrozenSet) ToSlice() []interface{} {
+	return set.elems().ToSlice()
+}
+
+// ToSortedSlice returns the members of the set as a slice sorted
+// by less, or in the order of a nil less of Set.ToSortedSlice.
+func (set FrozenSet) ToSortedSlice(less func(a, b interface{}) bool) []interface{} {
+	return set.elems().ToSortedSlice(less)
+}
+
+// Equal determines if two sets are equal to each
+// other.
+func (set FrozenSet) Equal(other FrozenSet) bool {
+	return set.f == other.f || set.elems().Equal(other.elems())
+}
+
+// IsSubset determines if every element in this set is in
+// the other set.
+func (set FrozenSet) IsSubset(other FrozenSet) bool {
+	return set.elems().IsSubset(other.elems())
+}
+
+// IsSuperset determines if every element in the other set
+// is in this set.
+func (set FrozenSet) IsSuperset(other FrozenSet) bool {
+	return set.elems().IsSuperset(other.elems())
+}
+
+// Union returns a new set with all elements in both sets.
+func (set FrozenSet) Union(other FrozenSet) FrozenSet {
+	return freeze(*set.elems().Union(other.elems()).(*ThreadUnsafeSet))
+}
+
+// Intersect returns a new set containing only the elements
+// that exist only in both sets.
+func (set FrozenSet) Intersect(other FrozenSet) FrozenSet {
+	return freeze(*set.elems().Intersect(other.elems()).(*ThreadUnsafeSet))
+}
+
+// Difference returns the difference between this set
+// and other.
+func (set FrozenSet) Difference(other FrozenSet) FrozenSet {
+	return freeze(*set.elems().Difference(other.elems()).(*ThreadUnsafeSet))
+}
+
+// SymmetricDifference returns a new set with all elements which are
+// in either this set or the other set but not in both.
+func (set FrozenSet) SymmetricDifference(other FrozenSet) FrozenSet {
+	return freeze(*set.elems().SymmetricDifference(other.elems()).(*ThreadUnsafeSet))
+}
+
+// With returns a new set with the elements of this set
+// and the given elements.
+func (set FrozenSet) With(vals ...interface{}) FrozenSet {
+	s := set.elems().Clone().(*ThreadUnsafeSet)
+	s.Append(vals...)
+	return freeze(*s)
+}
+
+// Without returns a new set with the elements of this set
+// except for the given elements.
+func (set FrozenSet) Without(vals ...interface{}) FrozenSet {
+	s := set.elems().Clone().(*ThreadUnsafeSet)
+	s.RemoveAll(vals...)
+	return freeze(*s)
+}
+
+// Thaw returns a mutable, thread-safe copy of the set.
+func (set FrozenSet) Thaw() Set {
+	return set.elems().Clone().(*ThreadUnsafeSet).ToThreadSafe()
+}
+
+// Hash returns a hash of the elements of the set, which makes
+// frozen sets Hashable. It is computed once and then cached.
+func (set FrozenSet) Hash() string {
+	if set.f == nil {
+		return setHash(nil)
+	}
+	set.f.hashOnce.Do(func() {
+		set.f.hash = set.f.unsafeSet.Hash()
+	})
+	return set.f.hash
+}
+
+// String provides a convenient string representation
+// of the set.
+func (set FrozenSet) String() string {
+	return setString(set.elems(), "goset.FrozenSet")
+}
+
+// MarshalJSON will marshal the set into a JSON-based representation.
+func (set FrozenSet) MarshalJSON() ([]byte, error) {
+	return set.elems().MarshalJSON()
+}
+
+// MarshalJSONSorted is like MarshalJSON, but emits the elements
+// in a deterministic order, see Set.MarshalJSONSorted.
+func (set FrozenSet) MarshalJSONSorted() ([]byte, error) {
+	return set.elems().MarshalJSONSorted()
+}
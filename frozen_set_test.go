@@ -0,0 +1,50 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "testing"
+
+func Test_FrozenSet(t *testing.T) {
+	s := NewSet(1, 2, 3)
+	frozen := s.Freeze()
+	s.Add(4)
+	if frozen.Size() != 3 || frozen.Contains(4) {
+		t.Errorf("Expected the frozen set to be a copy, got %v", frozen)
+	}
+
+	bigger := frozen.With(4, 5)
+	if frozen.Size() != 3 || bigger.Size() != 5 {
+		t.Errorf("Expected With to leave the set unchanged, got %v and %v", frozen, bigger)
+	}
+	if smaller := bigger.Without(1, 5); !smaller.Equal(NewFrozenSet(2, 3, 4)) {
+		t.Errorf("Expected [2 3 4], got %v", smaller)
+	}
+	if union := frozen.Union(NewFrozenSet(9)); union.Size() != 4 || !union.IsSuperset(frozen) {
+		t.Errorf("Expected the union to contain both sets, got %v", union)
+	}
+
+	if frozen.Hash() != NewFrozenSet(3, 2, 1).Hash() {
+		t.Errorf("Expected equal sets to hash equally")
+	}
+	var empty FrozenSet
+	if empty.Size() != 0 || empty.Hash() != NewFrozenSet().Hash() || !NewSet(empty, frozen).Contains(frozen) {
+		t.Errorf("Expected frozen sets to be usable as elements")
+	}
+
+	thawed := frozen.Thaw()
+	thawed.Add(7)
+	if frozen.Contains(7) {
+		t.Errorf("Expected Thaw to return a copy")
+	}
+}
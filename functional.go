@@ -0,0 +1,53 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+// Map returns a new set with the results of applying f to every element
+// of s. Elements that f maps to the same value are merged, so the result
+// may be smaller than s.
+//
+// The result is thread-unsafe if s is a ThreadUnsafeSet, and thread-safe
+// otherwise.
+func Map(s Set, f func(elem interface{}) interface{}) Set {
+	mapped := newThreadUnsafeSet()
+	s.Each(func(elem interface{}) bool {
+		mapped.Add(f(elem))
+		return false
+	})
+	return resultFor(s, &mapped)
+}
+
+// resultFor returns ret as the result of an operation on s: as is if s
+// is thread-unsafe, and as a thread-safe set otherwise.
+func resultFor(s Set, ret *ThreadUnsafeSet) Set {
+	if _, ok := s.(*ThreadUnsafeSet); ok {
+		return ret
+	}
+	return ret.ToThreadSafe()
+}
+
+// Reduce folds the elements of s into a single value: f is called with
+// the accumulated value, starting with initial, and each element in
+// turn, and returns the new accumulated value.
+//
+// Sets are unordered, so f should not depend on the order in which the
+// elements are visited.
+func Reduce(s Set, initial interface{}, f func(acc, elem interface{}) interface{}) interface{} {
+	acc := initial
+	s.Each(func(elem interface{}) bool {
+		acc = f(acc, elem)
+		return false
+	})
+	return acc
+}
@@ -0,0 +1,374 @@
+//go:build ignore
+// +build ignore
+
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This program generates the sets keyed directly by their elements, like
+// StringSet, and the stores of their Set views from a single template,
+// as the package doesn't require a Go version with generics. Run it with
+// go generate.
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"text/template"
+)
+
+type keySet struct {
+	File  string // Output file
+	Name  string // Name of the set type
+	Elem  string // Element type
+	Zero  string // Zero value of the element type
+	Store string // Name of the store type of the Set view
+	Doc   string // Doc comment of the set type
+}
+
+var keySets = []keySet{
+	{
+		File: "string_set.go", Name: "StringSet", Elem: "string", Zero: `""`, Store: "stringMapStore",
+		Doc: `// StringSet is a set of strings stored directly as the keys of a map.
+// Unlike the general sets of this package it neither hashes nor boxes
+// its elements, which roughly halves its memory use and removes the
+// allocations from Add and Contains.
+//
+// Like a map, a StringSet is not thread-safe. Use AsSet for a Set view
+// of it.`,
+	},
+	{
+		File: "int_set.go", Name: "IntSet", Elem: "int", Zero: "int(0)", Store: "intMapStore",
+		Doc: `// IntSet is a set of ints stored directly as the keys of a map. Unlike
+// the general sets of this package it doesn't format its elements as
+// strings to hash them, so Add and Contains don't allocate.
+//
+// Like a map, an IntSet is not thread-safe. Use AsSet for a Set view
+// of it.`,
+	},
+	{
+		File: "int64_set.go", Name: "Int64Set", Elem: "int64", Zero: "int64(0)", Store: "int64MapStore",
+		Doc: `// Int64Set is a set of int64s stored directly as the keys of a map, see
+// IntSet.`,
+	},
+}
+
+var keySetTemplate = template.Must(template.New("").Parse(`// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by gen_keysets.go; DO NOT EDIT.
+
+package goset
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+{{.Doc}}
+type {{.Name}} map[{{.Elem}}]struct{}
+
+// New{{.Name}} creates and returns a new set with the given elements.
+func New{{.Name}}(vals ...{{.Elem}}) {{.Name}} {
+	set := make({{.Name}}, len(vals))
+	for _, v := range vals {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+// Add adds an element to the set. Returns whether
+// the item was added.
+func (set {{.Name}}) Add(val {{.Elem}}) bool {
+	if _, ok := set[val]; ok {
+		return false
+	}
+	set[val] = struct{}{}
+	return true
+}
+
+// Append adds all given elements to the set. Returns the
+// number of items that were added.
+func (set {{.Name}}) Append(vals ...{{.Elem}}) int {
+	n := 0
+	for _, v := range vals {
+		if set.Add(v) {
+			n++
+		}
+	}
+	return n
+}
+
+// Contains returns whether the given items
+// are all in the set.
+func (set {{.Name}}) Contains(vals ...{{.Elem}}) bool {
+	for _, v := range vals {
+		if _, ok := set[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Remove remove a single element from the set.
+func (set {{.Name}}) Remove(val {{.Elem}}) {
+	delete(set, val)
+}
+
+// Size Returns the number of elements in the set.
+func (set {{.Name}}) Size() int {
+	return len(set)
+}
+
+// Each iterates over elements and executes the passed func against each element.
+// If passed func returns true, stop iteration at the time.
+func (set {{.Name}}) Each(f func(elem {{.Elem}}) bool) {
+	for v := range set {
+		if f(v) {
+			break
+		}
+	}
+}
+
+// ToSlice returns the members of the set as a slice.
+func (set {{.Name}}) ToSlice() []{{.Elem}} {
+	vals := make([]{{.Elem}}, 0, len(set))
+	for v := range set {
+		vals = append(vals, v)
+	}
+	return vals
+}
+
+// ToSortedSlice returns the members of the set as a sorted slice.
+func (set {{.Name}}) ToSortedSlice() []{{.Elem}} {
+	vals := set.ToSlice()
+	sort.Slice(vals, func(i, j int) bool { return vals[i] < vals[j] })
+	return vals
+}
+
+// Clone returns a copy of the set.
+func (set {{.Name}}) Clone() {{.Name}} {
+	ret := make({{.Name}}, len(set))
+	for v := range set {
+		ret[v] = struct{}{}
+	}
+	return ret
+}
+
+// Equal determines if two sets are equal to each
+// other.
+func (set {{.Name}}) Equal(other {{.Name}}) bool {
+	return len(set) == len(other) && set.IsSubset(other)
+}
+
+// IsSubset determines if every element in this set is in
+// the other set.
+func (set {{.Name}}) IsSubset(other {{.Name}}) bool {
+	if len(set) > len(other) {
+		return false
+	}
+	for v := range set {
+		if _, ok := other[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Union returns a new set with all elements in both sets.
+func (set {{.Name}}) Union(other {{.Name}}) {{.Name}} {
+	ret := make({{.Name}}, len(set)+len(other))
+	for v := range set {
+		ret[v] = struct{}{}
+	}
+	for v := range other {
+		ret[v] = struct{}{}
+	}
+	return ret
+}
+
+// Intersect returns a new set containing only the elements
+// that exist only in both sets.
+func (set {{.Name}}) Intersect(other {{.Name}}) {{.Name}} {
+	small, big := set, other
+	if len(small) > len(big) {
+		small, big = big, small
+	}
+	ret := {{.Name}}{}
+	for v := range small {
+		if _, ok := big[v]; ok {
+			ret[v] = struct{}{}
+		}
+	}
+	return ret
+}
+
+// Difference returns the difference between this set
+// and other.
+func (set {{.Name}}) Difference(other {{.Name}}) {{.Name}} {
+	ret := {{.Name}}{}
+	for v := range set {
+		if _, ok := other[v]; !ok {
+			ret[v] = struct{}{}
+		}
+	}
+	return ret
+}
+
+// SymmetricDifference returns a new set with all elements which are
+// in either this set or the other set but not in both.
+func (set {{.Name}}) SymmetricDifference(other {{.Name}}) {{.Name}} {
+	ret := set.Difference(other)
+	for v := range other {
+		if _, ok := set[v]; !ok {
+			ret[v] = struct{}{}
+		}
+	}
+	return ret
+}
+
+// AsSet returns a Set view of the set. Elements added or removed
+// through the view show up in the set and vice versa.
+func (set {{.Name}}) AsSet() Set {
+	return &ThreadUnsafeSet{store: {{.Store}}(set), typ: reflect.TypeOf({{.Zero}})}
+}
+
+// String provides a convenient string representation
+// of the current state of the set.
+func (set {{.Name}}) String() string {
+	if len(set) == 0 {
+		return "goset.{{.Name}}{ }"
+	}
+	items := make([]string, 0, len(set))
+	for v := range set {
+		items = append(items, fmt.Sprint(v))
+	}
+	return fmt.Sprintf("goset.{{.Name}}{ %s }", strings.Join(items, ", "))
+}
+
+// MarshalJSON will marshal the set into a JSON array.
+func (set {{.Name}}) MarshalJSON() ([]byte, error) {
+	return json.Marshal(set.ToSlice())
+}
+
+// UnmarshalJSON will unmarshal a JSON array of {{.Elem}}s into the set,
+// adding to its elements.
+func (set *{{.Name}}) UnmarshalJSON(b []byte) error {
+	var vals []{{.Elem}}
+	if err := json.Unmarshal(b, &vals); err != nil {
+		return err
+	}
+	if *set == nil {
+		*set = make({{.Name}}, len(vals))
+	}
+	set.Append(vals...)
+	return nil
+}
+
+// {{.Store}} is a store over the map of a {{.Name}}, or any other
+// map[{{.Elem}}]struct{}.
+type {{.Store}} map[{{.Elem}}]struct{}
+
+func (s {{.Store}}) add(val interface{}) (bool, error) {
+	v, ok := val.({{.Elem}})
+	if !ok {
+		return false, wrongType(val, "%T can't be stored in a view of a map[{{.Elem}}]struct{}", val)
+	}
+	if _, ok := s[v]; ok {
+		return false, nil
+	}
+	s[v] = struct{}{}
+	return true, nil
+}
+
+func (s {{.Store}}) get(val interface{}) (interface{}, bool) {
+	v, ok := val.({{.Elem}})
+	if !ok {
+		return nil, false
+	}
+	_, ok = s[v]
+	return v, ok
+}
+
+func (s {{.Store}}) remove(val interface{}) (bool, error) {
+	v, ok := val.({{.Elem}})
+	if !ok {
+		return false, wrongType(val, "%T can't be stored in a view of a map[{{.Elem}}]struct{}", val)
+	}
+	if _, ok := s[v]; !ok {
+		return false, nil
+	}
+	delete(s, v)
+	return true, nil
+}
+
+func (s {{.Store}}) len() int {
+	return len(s)
+}
+
+func (s {{.Store}}) each(f func(val interface{}) bool) {
+	for v := range s {
+		if f(v) {
+			break
+		}
+	}
+}
+
+func (s {{.Store}}) clear() {
+	for v := range s {
+		delete(s, v)
+	}
+}
+
+// grow is a no-op, the map belongs to the caller, who sized it.
+func (s {{.Store}}) grow(n int) {}
+
+func (s {{.Store}}) empty(capacity int) store {
+	return make({{.Store}}, capacity)
+}
+
+// external keeps the set on the caller's map, see externalStore.
+func (s {{.Store}}) external() {}
+`))
+
+func main() {
+	for _, set := range keySets {
+		var buf bytes.Buffer
+		if err := keySetTemplate.Execute(&buf, set); err != nil {
+			log.Fatal(err)
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			log.Fatalf("%s: %v", set.File, err)
+		}
+		if err := ioutil.WriteFile(set.File, src, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
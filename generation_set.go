@@ -0,0 +1,148 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "sync"
+
+// GenerationSet is a thread-safe set whose elements are tagged with the
+// generation in which they were last added. It supports the classic
+// mark-and-sweep pattern for reconciling a set against a periodically
+// rebuilt source:
+//
+//	gen := set.Mark()
+//	for _, v := range source {
+//		set.Add(v)
+//	}
+//	stale := set.SweepBefore(gen)
+type GenerationSet struct {
+	sync.RWMutex
+	unsafeSet ThreadUnsafeSet
+	gens      map[string]uint64 // Store {$hash: $generation} of elem
+	gen       uint64
+}
+
+// NewGenerationSet creates and returns a new generation set with the
+// given elements tagged with the first generation.
+func NewGenerationSet(vals ...interface{}) *GenerationSet {
+	set := &GenerationSet{unsafeSet: newThreadUnsafeSet(), gens: map[string]uint64{}}
+	for _, item := range vals {
+		set.Add(item)
+	}
+	return set
+}
+
+// Mark starts a new generation and returns it.
+func (set *GenerationSet) Mark() uint64 {
+	set.Lock()
+	defer set.Unlock()
+	set.gen++
+	return set.gen
+}
+
+// Generation returns the current generation.
+func (set *GenerationSet) Generation() uint64 {
+	set.RLock()
+	defer set.RUnlock()
+	return set.gen
+}
+
+// Add adds an element to the set and tags it with the current generation.
+// Adding an element that is already present moves it to the current
+// generation. Returns whether the item was newly added.
+func (set *GenerationSet) Add(val interface{}) bool {
+	hash, err := calcHash(val)
+	if err != nil {
+		panic(err)
+	}
+	set.Lock()
+	defer set.Unlock()
+	added := set.unsafeSet.Add(val)
+	set.gens[hash] = set.gen
+	return added
+}
+
+// GenerationOf returns the generation the element was last added in.
+func (set *GenerationSet) GenerationOf(val interface{}) (uint64, bool) {
+	hash, err := calcHash(val)
+	if err != nil {
+		return 0, false
+	}
+	set.RLock()
+	defer set.RUnlock()
+	gen, ok := set.gens[hash]
+	return gen, ok
+}
+
+// Contains returns whether the given items
+// are all in the set.
+func (set *GenerationSet) Contains(val ...interface{}) bool {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.Contains(val...)
+}
+
+// Remove remove a single element from the set.
+func (set *GenerationSet) Remove(i interface{}) {
+	hash, err := calcHash(i)
+	if err != nil {
+		panic(err)
+	}
+	set.Lock()
+	set.unsafeSet.Remove(i)
+	delete(set.gens, hash)
+	set.Unlock()
+}
+
+// SweepBefore removes every element last added in a generation older
+// than gen and returns the removed elements.
+func (set *GenerationSet) SweepBefore(gen uint64) []interface{} {
+	set.Lock()
+	defer set.Unlock()
+	var swept []interface{}
+	set.unsafeSet.Each(func(obj interface{}) bool {
+		if hash, _ := calcHash(obj); set.gens[hash] < gen {
+			swept = append(swept, obj)
+		}
+		return false
+	})
+	for _, obj := range swept {
+		hash, _ := calcHash(obj)
+		set.unsafeSet.Remove(obj)
+		delete(set.gens, hash)
+	}
+	return swept
+}
+
+// Size Returns the number of elements in the set.
+func (set *GenerationSet) Size() int {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.Size()
+}
+
+// ToSet returns a thread-safe snapshot of the elements in the set.
+func (set *GenerationSet) ToSet() Set {
+	set.RLock()
+	defer set.RUnlock()
+	unsafeClone := set.unsafeSet.Clone().(*ThreadUnsafeSet)
+	return &ThreadSafeSet{unsafeSet: *unsafeClone}
+}
+
+// String provides a convenient string representation
+// of the current state of the set.
+func (set *GenerationSet) String() string {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.String()
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "testing"
+
+func Test_GenerationSetSweep(t *testing.T) {
+	s := NewGenerationSet(1, 2, 3)
+
+	gen := s.Mark()
+	s.Add(2)
+	s.Add(4)
+
+	swept := s.SweepBefore(gen)
+	if len(swept) != 2 {
+		t.Errorf("Expected 2 swept elements, got %v", swept)
+	}
+	if s.Contains(1) || s.Contains(3) {
+		t.Errorf("Stale elements survived the sweep: %v", s)
+	}
+	if !s.Contains(2, 4) || s.Size() != 2 {
+		t.Errorf("Current elements were swept: %v", s)
+	}
+	if g, ok := s.GenerationOf(2); !ok || g != gen {
+		t.Errorf("Expected element to be tagged with generation %v, got %v", gen, g)
+	}
+}
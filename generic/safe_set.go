@@ -0,0 +1,263 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package generic
+
+import (
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// ThreadSafeSet is a set of elements of type T that is safe for
+// concurrent use.
+type ThreadSafeSet[T comparable] struct {
+	sync.RWMutex
+	unsafeSet ThreadUnsafeSet[T]
+}
+
+// withOther read-locks set and calls f with a read-locked view of other.
+// If other is a ThreadSafeSet, f gets its unlocked core, so that f can
+// use it freely while set is locked as well.
+//
+// The locks of the two sets are taken in the order of their addresses,
+// so that a.Equal(b) and b.Equal(a) running at once can't deadlock: a
+// writer waiting for the lock of a set blocks new readers of it, so two
+// goroutines each holding the read lock of one set could otherwise wait
+// for each other behind writers.
+func (set *ThreadSafeSet[T]) withOther(other Set[T], f func(o Set[T])) {
+	o, ok := other.(*ThreadSafeSet[T])
+	if !ok || o == set {
+		set.RLock()
+		defer set.RUnlock()
+		if ok {
+			f(&o.unsafeSet)
+		} else {
+			f(other)
+		}
+		return
+	}
+	first, second := set, o
+	if uintptr(unsafe.Pointer(o)) < uintptr(unsafe.Pointer(set)) {
+		first, second = o, set
+	}
+	first.RLock()
+	defer first.RUnlock()
+	second.RLock()
+	defer second.RUnlock()
+	f(&o.unsafeSet)
+}
+
+// Add adds an element to the set. Returns whether
+// the item was added.
+func (set *ThreadSafeSet[T]) Add(val T) bool {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.Add(val)
+}
+
+// Cardinality Returns the number of elements in the set.
+func (set *ThreadSafeSet[T]) Cardinality() int {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.Cardinality()
+}
+
+// Size Returns the number of elements in the set.
+func (set *ThreadSafeSet[T]) Size() int {
+	return set.Cardinality()
+}
+
+// Clear removes all elements from the set, leaving
+// the empty set.
+func (set *ThreadSafeSet[T]) Clear() {
+	set.Lock()
+	set.unsafeSet.Clear()
+	set.Unlock()
+}
+
+// Clone returns a clone of the set using the same
+// implementation, duplicating all keys.
+func (set *ThreadSafeSet[T]) Clone() Set[T] {
+	set.RLock()
+	defer set.RUnlock()
+	return &ThreadSafeSet[T]{unsafeSet: set.unsafeSet.clone()}
+}
+
+// Contains returns whether the given items
+// are all in the set.
+func (set *ThreadSafeSet[T]) Contains(val ...T) bool {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.Contains(val...)
+}
+
+// Difference returns the difference between this set
+// and other. The returned set will contain
+// all elements of this set that are not also
+// elements of other.
+func (set *ThreadSafeSet[T]) Difference(other Set[T]) Set[T] {
+	ret := &ThreadSafeSet[T]{}
+	set.withOther(other, func(o Set[T]) {
+		ret.unsafeSet = set.unsafeSet.difference(o)
+	})
+	return ret
+}
+
+// Equal determines if two sets are equal to each
+// other. If they have the same cardinality
+// and contain the same elements, they are
+// considered equal. The order in which
+// the elements were added is irrelevant.
+func (set *ThreadSafeSet[T]) Equal(other Set[T]) bool {
+	var ret bool
+	set.withOther(other, func(o Set[T]) {
+		ret = set.unsafeSet.Equal(o)
+	})
+	return ret
+}
+
+// Intersect returns a new set containing only the elements
+// that exist only in both sets.
+func (set *ThreadSafeSet[T]) Intersect(other Set[T]) Set[T] {
+	ret := &ThreadSafeSet[T]{}
+	set.withOther(other, func(o Set[T]) {
+		ret.unsafeSet = set.unsafeSet.intersect(o)
+	})
+	return ret
+}
+
+// IsProperSubset determines if every element in this set is in
+// the other set but the two sets are not equal.
+func (set *ThreadSafeSet[T]) IsProperSubset(other Set[T]) bool {
+	var ret bool
+	set.withOther(other, func(o Set[T]) {
+		ret = set.unsafeSet.IsProperSubset(o)
+	})
+	return ret
+}
+
+// IsProperSuperset determines if every element in the other set
+// is in this set but the two sets are not
+// equal.
+func (set *ThreadSafeSet[T]) IsProperSuperset(other Set[T]) bool {
+	var ret bool
+	set.withOther(other, func(o Set[T]) {
+		ret = set.unsafeSet.IsProperSuperset(o)
+	})
+	return ret
+}
+
+// IsSubset determines if every element in this set is in
+// the other set.
+func (set *ThreadSafeSet[T]) IsSubset(other Set[T]) bool {
+	var ret bool
+	set.withOther(other, func(o Set[T]) {
+		ret = set.unsafeSet.IsSubset(o)
+	})
+	return ret
+}
+
+// IsSuperset determines if every element in the other set
+// is in this set.
+func (set *ThreadSafeSet[T]) IsSuperset(other Set[T]) bool {
+	var ret bool
+	set.withOther(other, func(o Set[T]) {
+		ret = set.unsafeSet.IsSuperset(o)
+	})
+	return ret
+}
+
+// Each iterates over elements and executes the passed func against each element.
+// If passed func returns true, stop iteration at the time.
+func (set *ThreadSafeSet[T]) Each(cb func(elem T) bool) {
+	set.RLock()
+	defer set.RUnlock()
+	set.unsafeSet.Each(cb)
+}
+
+// Iter returns a channel of elements that you can
+// range over. The elements are snapshotted before
+// Iter returns, so the set is not locked while the
+// channel is consumed.
+func (set *ThreadSafeSet[T]) Iter() <-chan T {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.Iter()
+}
+
+// Remove remove a single element from the set.
+func (set *ThreadSafeSet[T]) Remove(i T) {
+	set.Lock()
+	set.unsafeSet.Remove(i)
+	set.Unlock()
+}
+
+// String provides a convenient string representation
+// of the current state of the set.
+func (set *ThreadSafeSet[T]) String() string {
+	set.RLock()
+	defer set.RUnlock()
+	return strings.Replace(set.unsafeSet.String(), "ThreadUnsafeSet", "ThreadSafeSet", 1)
+}
+
+// SymmetricDifference returns a new set with all elements which are
+// in either this set or the other set but not in both.
+func (set *ThreadSafeSet[T]) SymmetricDifference(other Set[T]) Set[T] {
+	ret := &ThreadSafeSet[T]{}
+	set.withOther(other, func(o Set[T]) {
+		ret.unsafeSet = set.unsafeSet.symmetricDifference(o)
+	})
+	return ret
+}
+
+// Union returns a new set with all elements in both sets.
+func (set *ThreadSafeSet[T]) Union(other Set[T]) Set[T] {
+	ret := &ThreadSafeSet[T]{}
+	set.withOther(other, func(o Set[T]) {
+		ret.unsafeSet = set.unsafeSet.union(o)
+	})
+	return ret
+}
+
+// Pop removes and returns an arbitrary item from the set.
+func (set *ThreadSafeSet[T]) Pop() (T, bool) {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.Pop()
+}
+
+// ToSlice returns the members of the set as a slice.
+func (set *ThreadSafeSet[T]) ToSlice() []T {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.ToSlice()
+}
+
+// MarshalJSON will marshal the set into a JSON-based representation.
+func (set *ThreadSafeSet[T]) MarshalJSON() ([]byte, error) {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.MarshalJSON()
+}
+
+// UnmarshalJSON will unmarshal a JSON array into the set,
+// decoding every item as a T.
+func (set *ThreadSafeSet[T]) UnmarshalJSON(b []byte) error {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.UnmarshalJSON(b)
+}
@@ -0,0 +1,139 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package generic provides sets with typed elements. Its sets have the same
+// methods as the sets of package goset, but elements are of type T and the
+// operations between two sets accept any Set[T] implementation, so neither
+// type assertions nor the panics of mismatched sets are left to callers.
+//
+// Elements are identified by Go equality, so T must be comparable.
+package generic
+
+// Set is a set of elements of type T.
+type Set[T comparable] interface {
+	// Add adds an element to the set. Returns whether
+	// the item was added.
+	Add(val T) bool
+
+	// Cardinality Returns the number of elements in the set.
+	Cardinality() int
+
+	// Size Returns the number of elements in the set.
+	Size() int
+
+	// Clear removes all elements from the set, leaving
+	// the empty set.
+	Clear()
+
+	// Clone returns a clone of the set using the same
+	// implementation, duplicating all keys.
+	Clone() Set[T]
+
+	// Contains returns whether the given items
+	// are all in the set.
+	Contains(val ...T) bool
+
+	// Difference returns the difference between this set
+	// and other. The returned set will contain
+	// all elements of this set that are not also
+	// elements of other.
+	Difference(other Set[T]) Set[T]
+
+	// Equal determines if two sets are equal to each
+	// other. If they have the same cardinality
+	// and contain the same elements, they are
+	// considered equal. The order in which
+	// the elements were added is irrelevant.
+	Equal(other Set[T]) bool
+
+	// Intersect returns a new set containing only the elements
+	// that exist only in both sets.
+	Intersect(other Set[T]) Set[T]
+
+	// IsProperSubset determines if every element in this set is in
+	// the other set but the two sets are not equal.
+	IsProperSubset(other Set[T]) bool
+
+	// IsProperSuperset determines if every element in the other set
+	// is in this set but the two sets are not
+	// equal.
+	IsProperSuperset(other Set[T]) bool
+
+	// IsSubset determines if every element in this set is in
+	// the other set.
+	IsSubset(other Set[T]) bool
+
+	// IsSuperset determines if every element in the other set
+	// is in this set.
+	IsSuperset(other Set[T]) bool
+
+	// Each iterates over elements and executes the passed func against each element.
+	// If passed func returns true, stop iteration at the time.
+	Each(func(elem T) bool)
+
+	// Iter returns a channel of elements that you can
+	// range over. The elements are snapshotted before
+	// Iter returns.
+	Iter() <-chan T
+
+	// Remove remove a single element from the set.
+	Remove(i T)
+
+	// String provides a convenient string representation
+	// of the current state of the set.
+	String() string
+
+	// SymmetricDifference returns a new set with all elements which are
+	// in either this set or the other set but not in both.
+	SymmetricDifference(other Set[T]) Set[T]
+
+	// Union returns a new set with all elements in both sets.
+	Union(other Set[T]) Set[T]
+
+	// Pop removes and returns an arbitrary item from the set.
+	Pop() (T, bool)
+
+	// ToSlice returns the members of the set as a slice.
+	ToSlice() []T
+
+	// MarshalJSON will marshal the set into a JSON-based representation.
+	MarshalJSON() ([]byte, error)
+
+	// UnmarshalJSON will unmarshal a JSON array into the set,
+	// decoding every item as a T.
+	UnmarshalJSON(b []byte) error
+}
+
+// NewSet creates and returns a new set with the given elements.
+// Operations on the resulting set are thread-safe.
+func NewSet[T comparable](vals ...T) Set[T] {
+	s := &ThreadSafeSet[T]{unsafeSet: newThreadUnsafeSet[T](len(vals))}
+	for _, item := range vals {
+		s.unsafeSet.Add(item)
+	}
+	return s
+}
+
+// NewThreadUnsafeSet creates and returns a new set with the given elements.
+// Operations on the resulting set are not thread-safe.
+func NewThreadUnsafeSet[T comparable](vals ...T) Set[T] {
+	s := newThreadUnsafeSet[T](len(vals))
+	for _, item := range vals {
+		s.Add(item)
+	}
+	return &s
+}
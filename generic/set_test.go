@@ -0,0 +1,90 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package generic
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+func Test_TypedOperations(t *testing.T) {
+	s := NewSet(1, 2, 3)
+	ss := NewThreadUnsafeSet(3, 4)
+
+	if union := s.Union(ss); union.Size() != 4 || !union.Contains(1, 2, 3, 4) {
+		t.Errorf("Unexpected union: %v", union)
+	}
+	if inter := s.Intersect(ss); !inter.Equal(NewSet(3)) {
+		t.Errorf("Unexpected intersection: %v", inter)
+	}
+	if diff := s.Difference(ss); !diff.Equal(NewThreadUnsafeSet(1, 2)) {
+		t.Errorf("Unexpected difference: %v", diff)
+	}
+	if sym := s.SymmetricDifference(s); sym.Size() != 0 {
+		t.Errorf("Unexpected symmetric difference with itself: %v", sym)
+	}
+}
+
+func Test_TypedJSON(t *testing.T) {
+	type point struct{ X, Y int }
+
+	b, err := json.Marshal(NewSet(point{1, 2}))
+	if err != nil {
+		t.Fatalf("Error should be nil: %v", err)
+	}
+	actual := NewSet[point]()
+	if err := json.Unmarshal(b, actual); err != nil {
+		t.Fatalf("Error should be nil: %v", err)
+	}
+	if !actual.Contains(point{1, 2}) {
+		t.Errorf("Expected the set to round-trip, got %v", actual)
+	}
+}
+
+func Test_TypedOppositeOperationsConcurrent(t *testing.T) {
+	const n = 1000
+	a, b := NewSet[int](), NewSet[int]()
+	for i := 0; i < n; i++ {
+		a.Add(i)
+		b.Add(i)
+	}
+
+	// Writers wedged between the read locks of the two sets
+	// deadlock this unless the locks are taken in a fixed order.
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(4)
+		go func() {
+			a.Intersect(b)
+			wg.Done()
+		}()
+		go func() {
+			b.Intersect(a)
+			wg.Done()
+		}()
+		go func(i int) {
+			a.Add(i + n)
+			wg.Done()
+		}(i)
+		go func(i int) {
+			b.Add(i + n)
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,250 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package generic
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ThreadUnsafeSet is a set of elements of type T that is not safe for
+// concurrent use.
+type ThreadUnsafeSet[T comparable] struct {
+	dat map[T]struct{}
+}
+
+func newThreadUnsafeSet[T comparable](capacity int) ThreadUnsafeSet[T] {
+	return ThreadUnsafeSet[T]{dat: make(map[T]struct{}, capacity)}
+}
+
+func (set *ThreadUnsafeSet[T]) Add(val T) bool {
+	if _, ok := set.dat[val]; ok {
+		return false
+	}
+	set.dat[val] = struct{}{}
+	return true
+}
+
+func (set *ThreadUnsafeSet[T]) Cardinality() int {
+	return len(set.dat)
+}
+
+func (set *ThreadUnsafeSet[T]) Size() int {
+	return len(set.dat)
+}
+
+func (set *ThreadUnsafeSet[T]) Clear() {
+	set.dat = map[T]struct{}{}
+}
+
+func (set *ThreadUnsafeSet[T]) Clone() Set[T] {
+	cloned := set.clone()
+	return &cloned
+}
+
+func (set *ThreadUnsafeSet[T]) clone() ThreadUnsafeSet[T] {
+	cloned := newThreadUnsafeSet[T](len(set.dat))
+	for elem := range set.dat {
+		cloned.dat[elem] = struct{}{}
+	}
+	return cloned
+}
+
+func (set *ThreadUnsafeSet[T]) Contains(val ...T) bool {
+	for _, v := range val {
+		if _, ok := set.dat[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (set *ThreadUnsafeSet[T]) Difference(other Set[T]) Set[T] {
+	diff := set.difference(other)
+	return &diff
+}
+
+func (set *ThreadUnsafeSet[T]) difference(other Set[T]) ThreadUnsafeSet[T] {
+	diff := newThreadUnsafeSet[T](0)
+	for elem := range set.dat {
+		if !other.Contains(elem) {
+			diff.dat[elem] = struct{}{}
+		}
+	}
+	return diff
+}
+
+func (set *ThreadUnsafeSet[T]) Equal(other Set[T]) bool {
+	return set.Size() == other.Size() && set.IsSubset(other)
+}
+
+func (set *ThreadUnsafeSet[T]) Intersect(other Set[T]) Set[T] {
+	intersection := set.intersect(other)
+	return &intersection
+}
+
+func (set *ThreadUnsafeSet[T]) intersect(other Set[T]) ThreadUnsafeSet[T] {
+	intersection := newThreadUnsafeSet[T](0)
+	if set.Size() < other.Size() {
+		for elem := range set.dat {
+			if other.Contains(elem) {
+				intersection.dat[elem] = struct{}{}
+			}
+		}
+	} else {
+		other.Each(func(elem T) bool {
+			if _, ok := set.dat[elem]; ok {
+				intersection.dat[elem] = struct{}{}
+			}
+			return false
+		})
+	}
+	return intersection
+}
+
+func (set *ThreadUnsafeSet[T]) IsProperSubset(other Set[T]) bool {
+	return set.Size() < other.Size() && set.IsSubset(other)
+}
+
+func (set *ThreadUnsafeSet[T]) IsProperSuperset(other Set[T]) bool {
+	return set.Size() > other.Size() && set.IsSuperset(other)
+}
+
+func (set *ThreadUnsafeSet[T]) IsSubset(other Set[T]) bool {
+	if set.Size() > other.Size() {
+		return false
+	}
+	for elem := range set.dat {
+		if !other.Contains(elem) {
+			return false
+		}
+	}
+	return true
+}
+
+func (set *ThreadUnsafeSet[T]) IsSuperset(other Set[T]) bool {
+	if set.Size() < other.Size() {
+		return false
+	}
+	ret := true
+	other.Each(func(elem T) bool {
+		_, ret = set.dat[elem]
+		return !ret
+	})
+	return ret
+}
+
+func (set *ThreadUnsafeSet[T]) Each(f func(elem T) bool) {
+	for elem := range set.dat {
+		if f(elem) {
+			break
+		}
+	}
+}
+
+func (set *ThreadUnsafeSet[T]) Iter() <-chan T {
+	objs := set.ToSlice()
+	ch := make(chan T)
+	go func() {
+		for _, obj := range objs {
+			ch <- obj
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+func (set *ThreadUnsafeSet[T]) Remove(i T) {
+	delete(set.dat, i)
+}
+
+func (set *ThreadUnsafeSet[T]) String() string {
+	items := make([]string, 0, len(set.dat))
+	for elem := range set.dat {
+		items = append(items, fmt.Sprintf("%v", elem))
+	}
+	if len(items) == 0 {
+		return "generic.ThreadUnsafeSet{  }"
+	}
+	return fmt.Sprintf("generic.ThreadUnsafeSet{ %s }", strings.Join(items, ", "))
+}
+
+func (set *ThreadUnsafeSet[T]) SymmetricDifference(other Set[T]) Set[T] {
+	diff := set.symmetricDifference(other)
+	return &diff
+}
+
+func (set *ThreadUnsafeSet[T]) symmetricDifference(other Set[T]) ThreadUnsafeSet[T] {
+	diff := set.difference(other)
+	other.Each(func(elem T) bool {
+		if _, ok := set.dat[elem]; !ok {
+			diff.dat[elem] = struct{}{}
+		}
+		return false
+	})
+	return diff
+}
+
+func (set *ThreadUnsafeSet[T]) Union(other Set[T]) Set[T] {
+	union := set.union(other)
+	return &union
+}
+
+func (set *ThreadUnsafeSet[T]) union(other Set[T]) ThreadUnsafeSet[T] {
+	union := set.clone()
+	other.Each(func(elem T) bool {
+		union.dat[elem] = struct{}{}
+		return false
+	})
+	return union
+}
+
+func (set *ThreadUnsafeSet[T]) Pop() (T, bool) {
+	for elem := range set.dat {
+		delete(set.dat, elem)
+		return elem, true
+	}
+	var zero T
+	return zero, false
+}
+
+func (set *ThreadUnsafeSet[T]) ToSlice() []T {
+	objs := make([]T, 0, len(set.dat))
+	for elem := range set.dat {
+		objs = append(objs, elem)
+	}
+	return objs
+}
+
+func (set *ThreadUnsafeSet[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(set.ToSlice())
+}
+
+func (set *ThreadUnsafeSet[T]) UnmarshalJSON(b []byte) error {
+	var items []T
+	if err := json.Unmarshal(b, &items); err != nil {
+		return err
+	}
+	if set.dat == nil {
+		set.dat = make(map[T]struct{}, len(items))
+	}
+	for _, item := range items {
+		set.dat[item] = struct{}{}
+	}
+	return nil
+}
@@ -14,19 +14,44 @@
 package goset
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
+	"net"
 	"reflect"
 	"strconv"
+	"time"
 )
 
+// Hashable is implemented by custom types to be stored in a set. Values
+// with equal hashes are the same element, unless the type also implements
+// Equaler, which then tells apart values whose hashes collide.
 type Hashable interface {
 	Hash() string
 }
 
+// Equaler is implemented by Hashable types whose hashes may collide for
+// different values, to tell those values apart. Equal is only called
+// with stored elements whose hash equals the receiver's.
+type Equaler interface {
+	Equal(other interface{}) bool
+}
+
+// sameElem reports whether the stored element, whose hash string is
+// storedHash, is the same element as val, whose hash string is hash.
+func sameElem(stored interface{}, storedHash string, val interface{}, hash string) bool {
+	if storedHash != hash {
+		return false
+	}
+	if e, ok := val.(Equaler); ok {
+		return e.Equal(stored)
+	}
+	return true
+}
+
 func isHashableObj(obj interface{}) bool {
 	switch obj.(type) {
-	case Hashable, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64, complex64, complex128, string:
+	case Hashable, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64, complex64, complex128, string,
+		time.Time, time.Duration, net.IP, [16]byte, []byte, bool, json.Number:
 		return true
 	default:
 		return false
@@ -44,9 +69,24 @@ func isNativeHashableObj(obj interface{}) bool {
 		return true
 	}
 }
+
+// isComparableNative reports whether a native hashable obj is identified
+// by ==. time.Time is identified by its instant, which == doesn't do for
+// times in different locations, and net.IP is a slice, identified by its
+// 16-byte form so that an IPv4 address and its IPv4-in-IPv6 form are one
+// element. []byte is identified by its contents.
+func isComparableNative(obj interface{}) bool {
+	switch obj.(type) {
+	case time.Time, net.IP, []byte:
+		return false
+	default:
+		return isNativeHashableObj(obj)
+	}
+}
+
 func calcHash(obj interface{}) (string, error) {
 	if !isHashableObj(obj) {
-		return "", errors.New("obj is not a hashable object, can't calculate its hash")
+		return "", &NotHashableError{Elem: obj}
 	}
 	if !isNativeHashableObj(obj) {
 		return obj.(Hashable).Hash(), nil
@@ -66,6 +106,8 @@ func calcHash(obj interface{}) (string, error) {
 		return strconv.FormatInt(o, 10), nil
 	case uint:
 		return strconv.FormatUint(uint64(o), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(o), 10), nil
 	case uint16:
 		return strconv.FormatUint(uint64(o), 10), nil
 	case uint32:
@@ -75,13 +117,30 @@ func calcHash(obj interface{}) (string, error) {
 	case uintptr:
 		return fmt.Sprintf("%v", o), nil
 	case float32:
-		return strconv.FormatFloat(float64(o), 'f', 8, 32), nil
+		return formatFloat(float64(o), 32), nil
 	case float64:
-		return strconv.FormatFloat(o, 'f', 8, 64), nil
+		return formatFloat(o, 64), nil
 	case complex64:
-		return strconv.FormatComplex(complex128(o), 'b', 8, 64), nil
+		return formatComplex(complex128(o), 64), nil
 	case complex128:
-		return strconv.FormatComplex(o, 'b', 8, 128), nil
+		return formatComplex(o, 128), nil
+	case time.Duration:
+		return strconv.FormatInt(int64(o), 10), nil
+	case time.Time:
+		return strconv.FormatInt(o.Unix(), 10) + "." + strconv.Itoa(o.Nanosecond()), nil
+	case net.IP:
+		if ip := o.To16(); ip != nil {
+			return string(ip), nil
+		}
+		return string(o), nil
+	case [16]byte:
+		return string(o[:]), nil
+	case []byte:
+		return string(o), nil
+	case bool:
+		return strconv.FormatBool(o), nil
+	case json.Number:
+		return string(o), nil
 	default:
 		return "", fmt.Errorf("%s is not a hashable native object, but the ret of isNativeHashableObj seems be true", reflect.TypeOf(obj).String())
 	}
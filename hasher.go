@@ -0,0 +1,108 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+// Hasher decides how the elements of a set are identified: two elements
+// are the same element if Hash returns the same string for them. A
+// Hasher lets a set use a faster or more compact hash than the built-in
+// one, or treat different values as the same element, e.g. strings that
+// only differ in case.
+type Hasher interface {
+	Hash(elem interface{}) string
+}
+
+// HasherFunc adapts a func to a Hasher.
+type HasherFunc func(elem interface{}) string
+
+// Hash returns f(elem).
+func (f HasherFunc) Hash(elem interface{}) string {
+	return f(elem)
+}
+
+// WithHasher identifies the elements of the set by h instead of the
+// built-in hash, which lets the set hold elements of any type h can
+// hash, Hashable or not.
+func WithHasher(h Hasher) Option {
+	return func(opts *setOptions) {
+		opts.newStore = func() store { return newHasherStore(h, 0) }
+	}
+}
+
+// NewSetWithHasher creates and returns a new, empty set whose elements
+// are identified by h, see WithHasher.
+// Operations on the resulting set are thread-safe.
+func NewSetWithHasher(h Hasher) Set {
+	return NewSet(WithHasher(h))
+}
+
+// hasherStore is a store that identifies elements by a Hasher.
+type hasherStore struct {
+	h   Hasher
+	dat map[string]interface{} // Store {$hash: $value} of elem
+}
+
+func newHasherStore(h Hasher, capacity int) *hasherStore {
+	return &hasherStore{h: h, dat: make(map[string]interface{}, capacity)}
+}
+
+func (s *hasherStore) add(val interface{}) (bool, error) {
+	hash := s.h.Hash(val)
+	if _, ok := s.dat[hash]; ok {
+		return false, nil
+	}
+	s.dat[hash] = val
+	return true, nil
+}
+
+func (s *hasherStore) get(val interface{}) (interface{}, bool) {
+	obj, ok := s.dat[s.h.Hash(val)]
+	return obj, ok
+}
+
+func (s *hasherStore) remove(val interface{}) (bool, error) {
+	hash := s.h.Hash(val)
+	if _, ok := s.dat[hash]; !ok {
+		return false, nil
+	}
+	delete(s.dat, hash)
+	return true, nil
+}
+
+func (s *hasherStore) len() int {
+	return len(s.dat)
+}
+
+func (s *hasherStore) each(f func(val interface{}) bool) {
+	for _, obj := range s.dat {
+		if f(obj) {
+			break
+		}
+	}
+}
+
+func (s *hasherStore) clear() {
+	s.dat = map[string]interface{}{}
+}
+
+func (s *hasherStore) grow(n int) {
+	dat := make(map[string]interface{}, len(s.dat)+n)
+	for hash, obj := range s.dat {
+		dat[hash] = obj
+	}
+	s.dat = dat
+}
+
+func (s *hasherStore) empty(capacity int) store {
+	return newHasherStore(s.h, capacity)
+}
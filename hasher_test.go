@@ -0,0 +1,114 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_NewSetWithHasher(t *testing.T) {
+	fold := HasherFunc(func(elem interface{}) string {
+		return strings.ToLower(elem.(string))
+	})
+	s := NewSetWithHasher(fold)
+	s.Add("Go")
+	if s.Add("GO") || !s.Contains("go") {
+		t.Errorf("Expected elements to be identified by the hasher, got %v", s)
+	}
+	if union := s.Union(NewSetWithHasher(fold)); !union.Contains("gO") {
+		t.Errorf("Expected results of operations to use the hasher, got %v", union)
+	}
+
+	type point struct{ X, Y []int }
+	points := NewThreadUnsafeSet(WithHasher(HasherFunc(func(elem interface{}) string {
+		p := elem.(point)
+		return fmt.Sprint(p.X, p.Y)
+	})))
+	points.Add(point{[]int{1}, []int{2}})
+	if !points.Contains(point{[]int{1}, []int{2}}) {
+		t.Errorf("Expected a hasher to make unhashable elements storable")
+	}
+}
+
+func Test_WithDeepHashing(t *testing.T) {
+	type node struct {
+		Name string
+		Tags []string
+		Next *node
+	}
+	s := NewSet(WithDeepHashing())
+	s.Add(node{Name: "a", Tags: []string{"x"}, Next: &node{Name: "b"}})
+	if s.Add(node{Name: "a", Tags: []string{"x"}, Next: &node{Name: "b"}}) {
+		t.Errorf("Expected deeply equal structs to be the same element")
+	}
+	if s.Contains(node{Name: "a", Tags: []string{"y"}}) {
+		t.Errorf("Expected structs with different contents to differ")
+	}
+
+	maps := NewThreadUnsafeSet(WithDeepHashing(), map[string]int{"a": 1, "b": 2})
+	if !maps.Contains(map[string]int{"b": 2, "a": 1}) {
+		t.Errorf("Expected maps to be hashed independently of their order")
+	}
+
+	cyclic := &node{Name: "c"}
+	cyclic.Next = cyclic
+	if DeepHash(cyclic) == "" || DeepHash([]int{1}) == DeepHash([]int64{1}) {
+		t.Errorf("Expected cycles to terminate and types to be told apart")
+	}
+}
+
+func Test_WithTaggedHashing(t *testing.T) {
+	type person struct {
+		ID      int `goset:"key"`
+		Name    string
+		Hobbies []string
+	}
+	s := NewSet(WithTaggedHashing(), person{ID: 1, Name: "James"}, person{ID: 2, Name: "Briant"})
+	if s.Add(person{ID: 1, Name: "Jim", Hobbies: []string{"basketball"}}) || !s.Contains(person{ID: 2}) {
+		t.Errorf("Expected people to be identified by their ID, got %v", s)
+	}
+	if TagHash(&person{ID: 1}) != TagHash(person{ID: 1, Name: "Jim"}) {
+		t.Errorf("Expected pointers to be hashed by the struct they point to")
+	}
+	if TagHash([]int{1}) != DeepHash([]int{1}) {
+		t.Errorf("Expected values without key fields to be deep hashed")
+	}
+}
+
+func Test_UpdateWithHasher(t *testing.T) {
+	fold := HasherFunc(func(elem interface{}) string {
+		return strings.ToLower(elem.(string))
+	})
+	s := NewSet(WithHasher(fold), "a")
+	s.Update(func(tx SetTx) {
+		tx.Remove("A")
+		if !tx.Add("a") || !tx.Contains("a") {
+			t.Errorf("Expected a to be added back")
+		}
+	})
+	if !s.Contains("a") || s.Size() != 1 {
+		t.Errorf("Expected the transaction to identify elements by the hasher, got %v", s)
+	}
+
+	deep := NewSet(WithDeepHashing(), []int{1})
+	deep.Update(func(tx SetTx) {
+		tx.Add([]int{2})
+		tx.Remove([]int{1})
+	})
+	if !deep.Contains([]int{2}) || deep.Size() != 1 {
+		t.Errorf("Expected the transaction to hold unhashable elements, got %v", deep)
+	}
+}
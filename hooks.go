@@ -0,0 +1,203 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "sync/atomic"
+
+// OnAdd registers f to be called with each element added to the set, by
+// Add, Append, UnmarshalJSON, the in-place algebra like UnionWith, and
+// every other method. It may be given several times, the callbacks are
+// called in order.
+//
+// The callbacks of a ThreadSafeSet are called after its lock is released,
+// in the order of the changes, so they may use the set. Those of a
+// ThreadUnsafeSet are called during the change and must not modify the
+// set. Sets derived from the set, like the results of Union or Clone,
+// have no callbacks.
+func OnAdd(f func(elem interface{})) Option {
+	return func(opts *setOptions) {
+		opts.onAdd = append(opts.onAdd, f)
+	}
+}
+
+// OnRemove registers f to be called with each element removed from the
+// set, by Remove, Pop, Clear, the in-place algebra like IntersectWith,
+// and every other method, see OnAdd.
+func OnRemove(f func(elem interface{})) Option {
+	return func(opts *setOptions) {
+		opts.onRemove = append(opts.onRemove, f)
+	}
+}
+
+// hookEvent is a change to a set, to be passed to its callbacks and
+// watchers.
+type hookEvent struct {
+	ChangeEvent
+	removed []interface{} // The elements removed by a Clear
+}
+
+// hookStore is a store that calls the callbacks of its set, and informs
+// its watchers, on the changes to the store it wraps.
+type hookStore struct {
+	store
+	onAdd, onRemove []func(elem interface{})
+	watchers        []*watcher   // See Watch
+	counters        *setCounters // See InstrumentedSet
+
+	// deferred is set for the store of a ThreadSafeSet, which holds the
+	// events for the callbacks in pending until its lock is released.
+	deferred bool
+	pending  []hookEvent
+}
+
+func (s *hookStore) external() {}
+
+// hooks returns the hookStore of the set, wrapping its store in one if
+// necessary.
+func (set *ThreadUnsafeSet) hooks() *hookStore {
+	hs, ok := set.store.(*hookStore)
+	if !ok {
+		hs = &hookStore{store: set.store}
+		set.store = hs
+	}
+	return hs
+}
+
+// hooks is like ThreadUnsafeSet.hooks, the caller must hold the write
+// lock.
+func (set *ThreadSafeSet) hooks() *hookStore {
+	hs := set.unsafeSet.hooks()
+	hs.deferred = true
+	return hs
+}
+
+// emit passes e to the watchers, and runs the callbacks of e or holds it
+// for later if s is deferred.
+func (s *hookStore) emit(e hookEvent) {
+	for _, w := range s.watchers {
+		w.push(e.ChangeEvent)
+	}
+	switch {
+	case e.Op == OpAdd && len(s.onAdd) == 0,
+		e.Op != OpAdd && len(s.onRemove) == 0:
+	case s.deferred:
+		s.pending = append(s.pending, e)
+	default:
+		s.run([]hookEvent{e})
+	}
+}
+
+func (s *hookStore) run(events []hookEvent) {
+	for _, e := range events {
+		switch e.Op {
+		case OpAdd:
+			for _, f := range s.onAdd {
+				f(e.Elem)
+			}
+		case OpRemove:
+			for _, f := range s.onRemove {
+				f(e.Elem)
+			}
+		case OpClear:
+			for _, elem := range e.removed {
+				for _, f := range s.onRemove {
+					f(elem)
+				}
+			}
+		}
+	}
+}
+
+// takePending returns the events held by s, and forgets them.
+func (s *hookStore) takePending() []hookEvent {
+	events := s.pending
+	s.pending = nil
+	return events
+}
+
+// observed returns whether anything is interested in the removals.
+func (s *hookStore) observed() bool {
+	return len(s.onRemove) > 0 || len(s.watchers) > 0
+}
+
+func (s *hookStore) add(val interface{}) (bool, error) {
+	added, err := s.store.add(val)
+	if added && err == nil {
+		if s.counters != nil {
+			atomic.AddUint64(&s.counters.adds, 1)
+		}
+		s.emit(hookEvent{ChangeEvent: ChangeEvent{Op: OpAdd, Elem: val}})
+	}
+	return added, err
+}
+
+func (s *hookStore) remove(val interface{}) (bool, error) {
+	if !s.observed() {
+		removed, err := s.store.remove(val)
+		if removed && err == nil && s.counters != nil {
+			atomic.AddUint64(&s.counters.removes, 1)
+		}
+		return removed, err
+	}
+	stored, ok := s.store.get(val)
+	if !ok {
+		return false, nil
+	}
+	removed, err := s.store.remove(val)
+	if removed && err == nil {
+		if s.counters != nil {
+			atomic.AddUint64(&s.counters.removes, 1)
+		}
+		s.emit(hookEvent{ChangeEvent: ChangeEvent{Op: OpRemove, Elem: stored}})
+	}
+	return removed, err
+}
+
+func (s *hookStore) clear() {
+	var removed []interface{}
+	if len(s.onRemove) > 0 {
+		removed = make([]interface{}, 0, s.store.len())
+		s.store.each(func(val interface{}) bool {
+			removed = append(removed, val)
+			return false
+		})
+	}
+	if s.counters != nil {
+		atomic.AddUint64(&s.counters.removes, uint64(s.store.len()))
+	}
+	s.store.clear()
+	s.emit(hookEvent{ChangeEvent: ChangeEvent{Op: OpClear}, removed: removed})
+}
+
+// Unlock unlocks the set, like sync.RWMutex.Unlock, and then calls the
+// callbacks registered by OnAdd and OnRemove on the changes made while
+// the set was locked. If a watcher of the set is too far behind, it
+// waits for the watcher to catch up, see Watch.
+func (set *ThreadSafeSet) Unlock() {
+	if set.reader != nil {
+		set.reader.publish()
+	}
+	hs, ok := set.unsafeSet.store.(*hookStore)
+	if !ok {
+		set.RWMutex.Unlock()
+		return
+	}
+	events := hs.takePending()
+	watchers := hs.watchers
+	set.RWMutex.Unlock()
+	hs.run(events)
+	for _, w := range watchers {
+		w.wait()
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "testing"
+
+func Test_Hooks(t *testing.T) {
+	var added, removed []interface{}
+	var s Set
+	s = NewSet(
+		OnAdd(func(elem interface{}) {
+			// The callbacks run outside the lock, so they may use the set.
+			if !s.Contains(elem) {
+				t.Errorf("Expected %v to be in the set when its callback runs", elem)
+			}
+			added = append(added, elem)
+		}),
+		OnRemove(func(elem interface{}) {
+			removed = append(removed, elem)
+		}),
+	)
+	s.Add(1)
+	s.Add(1)
+	s.Append(2, 3)
+	s.Remove(4)
+	s.Remove(1)
+	s.UnionWith(NewSet(3, 4))
+	s.IntersectWith(NewSet(2, 4, 5))
+	s.Clone().Add(6)
+	if len(added) != 4 || added[0] != 1 || added[3] != 4 {
+		t.Errorf("Expected 1, 2, 3 and 4 to be added, got %v", added)
+	}
+	if len(removed) != 2 || removed[0] != 1 || removed[1] != 3 {
+		t.Errorf("Expected 1 and 3 to be removed, got %v", removed)
+	}
+
+	removed = nil
+	s.Clear()
+	if len(removed) != 2 {
+		t.Errorf("Expected Clear to remove 2 and 4, got %v", removed)
+	}
+}
+
+func Test_HooksThreadUnsafe(t *testing.T) {
+	n := 0
+	s := NewThreadUnsafeSet(OnAdd(func(interface{}) { n++ }), OnRemove(func(interface{}) { n-- }), "a")
+	s.Append("b", "c")
+	s.RemoveIf(func(elem interface{}) bool { return elem != "a" })
+	if n != 1 {
+		t.Errorf("Expected the callbacks to track the size, got %d", n)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// labelEscaper escapes the values of labels in the Prometheus text format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// setCounters counts the changes to a set and the time spent waiting for
+// its lock.
+type setCounters struct {
+	adds, removes uint64
+	lockWait      int64 // Nanoseconds
+}
+
+// instrumentable is implemented by the sets an InstrumentedSet can count
+// the changes of.
+type instrumentable interface {
+	instrument(c *setCounters)
+}
+
+func (set *ThreadUnsafeSet) instrument(c *setCounters) {
+	set.hooks().counters = c
+}
+
+func (set *ThreadSafeSet) instrument(c *setCounters) {
+	set.Lock()
+	set.hooks().counters = c
+	set.counters.Store(c)
+	set.Unlock()
+}
+
+// Lock locks the set for writing, like sync.RWMutex.Lock. The time it
+// waits is measured for an InstrumentedSet.
+func (set *ThreadSafeSet) Lock() {
+	c, _ := set.counters.Load().(*setCounters)
+	if c == nil {
+		set.RWMutex.Lock()
+		return
+	}
+	start := time.Now()
+	set.RWMutex.Lock()
+	atomic.AddInt64(&c.lockWait, int64(time.Since(start)))
+}
+
+// RLock locks the set for reading, like sync.RWMutex.RLock. The time it
+// waits is measured for an InstrumentedSet.
+func (set *ThreadSafeSet) RLock() {
+	c, _ := set.counters.Load().(*setCounters)
+	if c == nil {
+		set.RWMutex.RLock()
+		return
+	}
+	start := time.Now()
+	set.RWMutex.RLock()
+	atomic.AddInt64(&c.lockWait, int64(time.Since(start)))
+}
+
+// SetMetrics are the metrics of an InstrumentedSet.
+type SetMetrics struct {
+	Size          int
+	Adds, Removes uint64 // Number of elements added and removed so far
+	// AddRate and RemoveRate are the elements added and removed per
+	// second, between the two latest calls of Metrics at least a second
+	// apart, or since the set was instrumented.
+	AddRate, RemoveRate float64
+	LockWait            time.Duration // Total time spent waiting for the lock
+}
+
+// InstrumentedSet is a Set that keeps metrics of how it is used: its
+// size, the number of elements added and removed, by any method, and the
+// time spent waiting for its lock. They are available from Metrics,
+// through expvar with Publish, and in the Prometheus text format with
+// WritePrometheus.
+type InstrumentedSet struct {
+	Set
+	name     string
+	counters *setCounters
+
+	mu      sync.Mutex // Guards the fields below
+	sampled time.Time
+	adds    uint64
+	removes uint64
+	addRate float64
+	remRate float64
+}
+
+// NewInstrumentedSet returns set instrumented under the given name, which
+// labels its metrics. The changes to set are counted whether they are
+// made through the InstrumentedSet or not.
+//
+// The lock wait is measured for a ThreadSafeSet and the sets built on it,
+// like SortedSet. The changes are counted for the sets of this package
+// except TieredSet, the metrics of other sets only have their size.
+func NewInstrumentedSet(name string, set Set) *InstrumentedSet {
+	c := &setCounters{}
+	if s, ok := set.(instrumentable); ok {
+		s.instrument(c)
+	}
+	return &InstrumentedSet{Set: set, name: name, counters: c, sampled: time.Now()}
+}
+
+// Name returns the name of the set.
+func (set *InstrumentedSet) Name() string {
+	return set.name
+}
+
+// Metrics returns the current metrics of the set.
+func (set *InstrumentedSet) Metrics() SetMetrics {
+	m := SetMetrics{
+		Size:     set.Size(),
+		Adds:     atomic.LoadUint64(&set.counters.adds),
+		Removes:  atomic.LoadUint64(&set.counters.removes),
+		LockWait: time.Duration(atomic.LoadInt64(&set.counters.lockWait)),
+	}
+
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	now := time.Now()
+	if elapsed := now.Sub(set.sampled).Seconds(); elapsed >= 1 {
+		set.addRate = float64(m.Adds-set.adds) / elapsed
+		set.remRate = float64(m.Removes-set.removes) / elapsed
+		set.sampled, set.adds, set.removes = now, m.Adds, m.Removes
+	}
+	m.AddRate, m.RemoveRate = set.addRate, set.remRate
+	return m
+}
+
+// Publish exports the metrics of the set as an expvar variable under its
+// name, a map of size, adds, removes, add_rate, remove_rate and
+// lock_wait_seconds. Like expvar.Publish, it panics if the name is
+// already in use.
+func (set *InstrumentedSet) Publish() {
+	expvar.Publish(set.name, expvar.Func(func() interface{} {
+		m := set.Metrics()
+		return map[string]interface{}{
+			"size":              m.Size,
+			"adds":              m.Adds,
+			"removes":           m.Removes,
+			"add_rate":          m.AddRate,
+			"remove_rate":       m.RemoveRate,
+			"lock_wait_seconds": m.LockWait.Seconds(),
+		}
+	}))
+}
+
+// WritePrometheus writes the metrics of sets to w in the Prometheus text
+// exposition format, labeled by their names, e.g. to serve them from a
+// /metrics handler without depending on the Prometheus client library:
+//
+//	goset_set_size{set="users"} 42
+//	goset_set_adds_total{set="users"} 50
+//	goset_set_removes_total{set="users"} 8
+//	goset_set_lock_wait_seconds_total{set="users"} 0.0012
+//
+// The rates of adds and removes are left to Prometheus, as rate() of the
+// counters.
+func WritePrometheus(w io.Writer, sets ...*InstrumentedSet) error {
+	metrics := make([]SetMetrics, len(sets))
+	for i, set := range sets {
+		metrics[i] = set.Metrics()
+	}
+	families := []struct {
+		name, typ, help string
+		value           func(m SetMetrics) float64
+	}{
+		{"goset_set_size", "gauge", "Number of elements in the set.",
+			func(m SetMetrics) float64 { return float64(m.Size) }},
+		{"goset_set_adds_total", "counter", "Number of elements added to the set.",
+			func(m SetMetrics) float64 { return float64(m.Adds) }},
+		{"goset_set_removes_total", "counter", "Number of elements removed from the set.",
+			func(m SetMetrics) float64 { return float64(m.Removes) }},
+		{"goset_set_lock_wait_seconds_total", "counter", "Time spent waiting for the lock of the set.",
+			func(m SetMetrics) float64 { return m.LockWait.Seconds() }},
+	}
+	for _, f := range families {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ); err != nil {
+			return err
+		}
+		for i, set := range sets {
+			_, err := fmt.Fprintf(w, "%s{set=\"%s\"} %s\n", f.name, labelEscaper.Replace(set.name),
+				strconv.FormatFloat(f.value(metrics[i]), 'g', -1, 64))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"bytes"
+	"expvar"
+	"strings"
+	"testing"
+)
+
+func Test_InstrumentedSet(t *testing.T) {
+	s := NewInstrumentedSet("test_users", NewSet("a"))
+	s.Add("b")
+	s.Append("c", "d", "a")
+	s.Remove("a")
+	s.IntersectWith(NewSet("b", "c"))
+	s.Clear()
+
+	m := s.Metrics()
+	if m.Size != 0 || m.Adds != 3 || m.Removes != 4 {
+		t.Errorf("Expected 3 adds and 4 removes, got %+v", m)
+	}
+
+	s.Publish()
+	if v := expvar.Get("test_users").String(); !strings.Contains(v, `"adds":3`) {
+		t.Errorf("Expected the published metrics to count 3 adds, got %s", v)
+	}
+
+	var b bytes.Buffer
+	if err := WritePrometheus(&b, s, NewInstrumentedSet(`odd"name`, NewThreadUnsafeSet(1))); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"# TYPE goset_set_adds_total counter\n",
+		`goset_set_removes_total{set="test_users"} 4` + "\n",
+		`goset_set_size{set="odd\"name"} 1` + "\n",
+	} {
+		if !strings.Contains(b.String(), line) {
+			t.Errorf("Expected %q in\n%s", line, b.String())
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "testing"
+
+func Test_IntSet(t *testing.T) {
+	a := NewIntSet(3, 1, 2)
+	if !a.Difference(NewIntSet(2)).Equal(NewIntSet(1, 3)) {
+		t.Errorf("Unexpected difference %v", a.Difference(NewIntSet(2)))
+	}
+	if sorted := a.ToSortedSlice(); sorted[0] != 1 || sorted[2] != 3 {
+		t.Errorf("Expected sorted elements, got %v", sorted)
+	}
+	view := a.AsSet()
+	view.Remove(3)
+	if a.Contains(3) || !view.Contains(1) {
+		t.Errorf("Expected the view to write through to the set")
+	}
+
+	b := NewInt64Set(1<<40, 7)
+	if !b.Union(NewInt64Set(8)).Contains(1<<40, 7, 8) {
+		t.Errorf("Unexpected union %v", b.Union(NewInt64Set(8)))
+	}
+	if b.AsSet().Contains(7) {
+		t.Errorf("Expected an Int64Set view to only contain int64s")
+	}
+}
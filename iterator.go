@@ -35,6 +35,36 @@ func (i *Iterator) Stop() {
 	}
 }
 
+// Cursor is a pull-style iterator over a snapshot of a Set's elements:
+//
+//	c := set.Cursor()
+//	for elem, ok := c.Next(); ok; elem, ok = c.Next() {
+//		...
+//	}
+//
+// A Cursor is not safe for concurrent use.
+type Cursor struct {
+	objs []interface{}
+	pos  int
+}
+
+// Next returns the next element, or false once all elements have
+// been returned.
+func (c *Cursor) Next() (interface{}, bool) {
+	if c.pos >= len(c.objs) {
+		return nil, false
+	}
+	obj := c.objs[c.pos]
+	c.objs[c.pos] = nil // let the element be collected
+	c.pos++
+	return obj, true
+}
+
+// Remaining returns the number of elements Next has yet to return.
+func (c *Cursor) Remaining() int {
+	return len(c.objs) - c.pos
+}
+
 // newIterator returns a new Iterator instance together with its item and stop channels.
 func newIterator() (*Iterator, chan<- interface{}, <-chan struct{}) {
 	itemChan := make(chan interface{})
@@ -44,3 +74,38 @@ func newIterator() (*Iterator, chan<- interface{}, <-chan struct{}) {
 		stop: stopChan,
 	}, itemChan, stopChan
 }
+
+// iterSlice returns a channel buffered for n elements that yields objs,
+// unbuffered if n is not positive. The elements are sent from a new
+// goroutine that exits once all of them have been received.
+func iterSlice(objs []interface{}, n int) <-chan interface{} {
+	if n < 0 {
+		n = 0
+	}
+	ch := make(chan interface{}, n)
+	go func() {
+		for _, obj := range objs {
+			ch <- obj
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// sliceIterator returns a new Iterator over objs.
+func sliceIterator(objs []interface{}) *Iterator {
+	iterator, ch, stopCh := newIterator()
+
+	go func() {
+	L:
+		for _, obj := range objs {
+			select {
+			case <-stopCh:
+				break L
+			case ch <- obj:
+			}
+		}
+		close(ch)
+	}()
+	return iterator
+}
@@ -0,0 +1,42 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "encoding/json"
+
+// UnmarshalJSONInto decodes the JSON array b into a new thread-safe set
+// configured by opts, unmarshaling each element into a T first, so that
+// sets of structs and other custom types round-trip through JSON:
+//
+//	people, err := goset.UnmarshalJSONInto[Person](b)
+func UnmarshalJSONInto[T any](b []byte, opts ...Option) (Set, error) {
+	var elems []T
+	if err := json.Unmarshal(b, &elems); err != nil {
+		return nil, err
+	}
+	vals := make([]interface{}, len(opts))
+	for i, opt := range opts {
+		vals[i] = opt
+	}
+	s := newSetFrom(vals)
+	for _, elem := range elems {
+		if _, err := s.TryAdd(elem); err != nil {
+			return nil, err
+		}
+	}
+	return s.ToThreadSafe(), nil
+}
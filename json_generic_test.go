@@ -0,0 +1,52 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type jsonPerson struct {
+	Name    string
+	Hobbies []string
+}
+
+func (p jsonPerson) Hash() string {
+	return p.Name
+}
+
+func Test_UnmarshalJSONInto(t *testing.T) {
+	expected := NewSet(jsonPerson{"James", []string{"basketball"}}, jsonPerson{"Briant", nil})
+	b, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := UnmarshalJSONInto[jsonPerson](b)
+	if err != nil || !actual.Equal(expected) {
+		t.Errorf("Expected %v to round-trip, got %v, %v", expected, actual, err)
+	}
+
+	ints, err := UnmarshalJSONInto[[]int]([]byte(`[[1], [1], [2]]`), WithDeepHashing())
+	if err != nil || ints.Size() != 2 {
+		t.Errorf("Expected the options to configure the set, got %v, %v", ints, err)
+	}
+	if _, err := UnmarshalJSONInto[[]int]([]byte(`[[1]]`)); !errors.Is(err, ErrNotHashable) {
+		t.Errorf("Expected ErrNotHashable, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// KV is a key-value store, like a Bolt bucket or a Badger database, that
+// keeps the elements of a set opened by OpenKVSet. Each method stands on
+// its own, so an adapter runs each in a transaction of its own. Get and
+// ForEach may be called concurrently with each other.
+type KV interface {
+	// Get returns the value of key, or nil if there is none.
+	Get(key []byte) ([]byte, error)
+	// Put sets the value of key.
+	Put(key, value []byte) error
+	// Delete removes key, if it is present.
+	Delete(key []byte) error
+	// ForEach calls f for every key and value until f returns an error,
+	// which ForEach then returns.
+	ForEach(f func(key, value []byte) error) error
+}
+
+// OpenKVSet returns a thread-safe set whose elements are kept in kv
+// instead of in memory, so that they survive restarts and can outgrow
+// the memory of the process. The elements stored in kv by a set opened
+// earlier are the elements of the set.
+//
+// Elements are keyed by their type and hash and gob-encoded, so custom
+// types must be registered with gob.Register. Add and Remove write
+// through to kv; methods that can't return an error, like Contains,
+// panic if kv fails. Sets derived from the set, like the results of
+// Union or Clone, are held in memory. The set must be the only writer
+// of kv.
+func OpenKVSet(kv KV) (Set, error) {
+	s := &kvStore{kv: kv}
+	var typ reflect.Type
+	err := kv.ForEach(func(key, value []byte) error {
+		if typ == nil {
+			elem, err := decodeGobElem(value)
+			if err != nil {
+				return err
+			}
+			typ = reflect.TypeOf(elem)
+		}
+		s.n++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ThreadSafeSet{unsafeSet: ThreadUnsafeSet{store: s, typ: typ}}, nil
+}
+
+// errStopEach stops a KV.ForEach early.
+var errStopEach = errors.New("stop")
+
+// kvStore is a store over a KV. It only counts the elements in memory.
+type kvStore struct {
+	kv KV
+	n  int
+}
+
+// kvKey returns the key of val, which identifies it like the hash
+// identifies elements of a hashStore, but also tells types apart.
+func kvKey(val interface{}) ([]byte, error) {
+	hash, err := calcHash(val)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(reflect.TypeOf(val).String() + "\x00" + hash), nil
+}
+
+func (s *kvStore) add(val interface{}) (bool, error) {
+	key, err := kvKey(val)
+	if err != nil {
+		return false, err
+	}
+	if old, err := s.kv.Get(key); err != nil {
+		return false, err
+	} else if old != nil {
+		return false, nil
+	}
+	value, err := encodeGobElem(val)
+	if err != nil {
+		return false, notStorable(val, "%T can't be stored in a key-value store: %v", val, err)
+	}
+	if err := s.kv.Put(key, value); err != nil {
+		return false, err
+	}
+	s.n++
+	return true, nil
+}
+
+func (s *kvStore) get(val interface{}) (interface{}, bool) {
+	key, err := kvKey(val)
+	if err != nil {
+		return nil, false
+	}
+	value, err := s.kv.Get(key)
+	if err != nil {
+		panic(fmt.Errorf("goset: reading the key-value store: %v", err))
+	}
+	if value == nil {
+		return nil, false
+	}
+	elem, err := decodeGobElem(value)
+	if err != nil {
+		panic(fmt.Errorf("goset: decoding %q: %v", key, err))
+	}
+	return elem, true
+}
+
+func (s *kvStore) remove(val interface{}) (bool, error) {
+	key, err := kvKey(val)
+	if err != nil {
+		return false, err
+	}
+	if old, err := s.kv.Get(key); err != nil || old == nil {
+		return false, err
+	}
+	if err := s.kv.Delete(key); err != nil {
+		return false, err
+	}
+	s.n--
+	return true, nil
+}
+
+func (s *kvStore) len() int {
+	return s.n
+}
+
+func (s *kvStore) each(f func(val interface{}) bool) {
+	err := s.kv.ForEach(func(key, value []byte) error {
+		elem, err := decodeGobElem(value)
+		if err != nil {
+			return fmt.Errorf("decoding %q: %v", key, err)
+		}
+		if f(elem) {
+			return errStopEach
+		}
+		return nil
+	})
+	if err != nil && err != errStopEach {
+		panic(fmt.Errorf("goset: reading the key-value store: %v", err))
+	}
+}
+
+// clear collects the keys before deleting them, as stores like Bolt
+// can't be modified during ForEach.
+func (s *kvStore) clear() {
+	var keys [][]byte
+	err := s.kv.ForEach(func(key, value []byte) error {
+		keys = append(keys, append([]byte(nil), key...))
+		return nil
+	})
+	for _, key := range keys {
+		if err == nil {
+			err = s.kv.Delete(key)
+		}
+	}
+	if err != nil {
+		panic(fmt.Errorf("goset: clearing the key-value store: %v", err))
+	}
+	s.n = 0
+}
+
+func (s *kvStore) grow(n int) {}
+
+func (s *kvStore) external() {}
+
+// empty returns an in-memory store, derived sets don't go to disk.
+func (s *kvStore) empty(capacity int) store {
+	return newHashStore(capacity)
+}
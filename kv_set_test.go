@@ -0,0 +1,97 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"errors"
+	"sort"
+	"testing"
+)
+
+// mapKV is a KV in memory.
+type mapKV map[string][]byte
+
+func (kv mapKV) Get(key []byte) ([]byte, error) {
+	return kv[string(key)], nil
+}
+
+func (kv mapKV) Put(key, value []byte) error {
+	kv[string(key)] = append([]byte(nil), value...)
+	return nil
+}
+
+func (kv mapKV) Delete(key []byte) error {
+	delete(kv, string(key))
+	return nil
+}
+
+func (kv mapKV) ForEach(f func(key, value []byte) error) error {
+	keys := make([]string, 0, len(kv))
+	for k := range kv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := f([]byte(k), kv[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// failingKV fails every write.
+type failingKV struct{ mapKV }
+
+func (kv failingKV) Put(key, value []byte) error {
+	return errors.New("disk full")
+}
+
+func Test_KVSet(t *testing.T) {
+	kv := mapKV{}
+	s, err := OpenKVSet(kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Append(1, 2, 3)
+	s.Remove(2)
+	if s.Add(1) || len(kv) != 2 {
+		t.Errorf("Expected the elements to be written to the store, got %v", kv)
+	}
+
+	reopened, err := OpenKVSet(kv)
+	if err != nil || !reopened.Equal(NewSet(1, 3)) {
+		t.Fatalf("Expected the elements to survive reopening, got %v, %v", reopened, err)
+	}
+	if _, err := reopened.TryAdd("a"); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected the element type to be restored, got %v", err)
+	}
+	if union := reopened.Union(NewSet(5)); !union.Equal(NewSet(1, 3, 5)) || len(kv) != 2 {
+		t.Errorf("Expected derived sets to be held in memory, got %v", union)
+	}
+
+	clone := reopened.Clone()
+	reopened.Add(7)
+	if clone.Contains(7) || len(kv) != 3 {
+		t.Errorf("Expected changes after a clone to go to the store, got %v", kv)
+	}
+	reopened.Clear()
+	if len(kv) != 0 || reopened.Size() != 0 {
+		t.Errorf("Expected Clear to empty the store, got %v", kv)
+	}
+
+	failing, _ := OpenKVSet(failingKV{mapKV{}})
+	if _, err := failing.TryAdd(1); err == nil || failing.Size() != 0 {
+		t.Errorf("Expected write errors to be returned")
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// The states of a slot of a lockFreeTable that is not an element. The
+// elements with these values are kept apart, see LockFreeUint64Set.small.
+const (
+	slotEmpty   uint64 = iota // Never used
+	slotDeleted               // Held an element that was removed
+	slotMoved                 // Copied to the next table by a resize
+	lockFreeReserved
+)
+
+// lockFreeMinSlots is the smallest number of slots of a lockFreeTable.
+const lockFreeMinSlots = 16
+
+// LockFreeUint64Set is a thread-safe set of uint64s for membership checks
+// on many cores at once, where even the locks of a ShardedSet are too
+// slow. Add, Remove and Contains are a few atomic operations on an open
+// addressing hash table and never lock the set.
+//
+// Only a resize of the table, when it fills up, takes a lock: the
+// operations that run into a slot the resize has already copied wait for
+// it to finish. Removed elements leave a mark in their slot until the
+// next resize, so a set with many removals is resized from time to time
+// even if it doesn't grow.
+//
+// The zero value is an empty set ready to use.
+type LockFreeUint64Set struct {
+	table  atomic.Value             // *lockFreeTable
+	small  [lockFreeReserved]uint32 // Whether 0, 1 and 2 are elements, as those are slot states
+	resize sync.Mutex               // Held while the table is replaced
+}
+
+// lockFreeTable is an open addressing hash table with linear probing.
+// Slots only ever change from empty to an element to deleted, and from
+// any state to moved, so an element is never in two slots of a table.
+type lockFreeTable struct {
+	slots []uint64
+	used  int64 // Slots that are not empty, updated atomically
+	n     int64 // Elements in the slots, updated atomically
+}
+
+// NewLockFreeUint64Set creates and returns a new set with the given
+// elements.
+// Operations on the resulting set are thread-safe.
+func NewLockFreeUint64Set(vals ...uint64) *LockFreeUint64Set {
+	set := &LockFreeUint64Set{}
+	set.table.Store(newLockFreeTable(2 * len(vals)))
+	set.Append(vals...)
+	return set
+}
+
+// newLockFreeTable returns an empty table with room for n elements, at
+// least lockFreeMinSlots.
+func newLockFreeTable(n int) *lockFreeTable {
+	size := lockFreeMinSlots
+	for size < n {
+		size *= 2
+	}
+	return &lockFreeTable{slots: make([]uint64, size)}
+}
+
+// mix64 scrambles the bits of v, so that consecutive elements don't end
+// up in consecutive slots.
+func mix64(v uint64) uint64 {
+	v ^= v >> 33
+	v *= 0xff51afd7ed558ccd
+	v ^= v >> 33
+	v *= 0xc4ceb9fe1a85ec53
+	v ^= v >> 33
+	return v
+}
+
+// add adds v to the table and returns whether it was added. ok is false
+// if the table is being resized or has no room for v.
+func (t *lockFreeTable) add(v uint64) (added, ok bool) {
+	mask := uint64(len(t.slots) - 1)
+	i := mix64(v) & mask
+	for probes := 0; probes < len(t.slots); probes++ {
+		slot := &t.slots[i]
+		s := atomic.LoadUint64(slot)
+		for s == slotEmpty {
+			if atomic.CompareAndSwapUint64(slot, slotEmpty, v) {
+				atomic.AddInt64(&t.used, 1)
+				atomic.AddInt64(&t.n, 1)
+				return true, true
+			}
+			s = atomic.LoadUint64(slot)
+		}
+		switch s {
+		case v:
+			return false, true
+		case slotMoved:
+			return false, false
+		}
+		i = (i + 1) & mask
+	}
+	return false, false
+}
+
+// contains returns whether v is in the table. ok is false if the table
+// is being resized.
+func (t *lockFreeTable) contains(v uint64) (found, ok bool) {
+	mask := uint64(len(t.slots) - 1)
+	i := mix64(v) & mask
+	for probes := 0; probes < len(t.slots); probes++ {
+		switch atomic.LoadUint64(&t.slots[i]) {
+		case v:
+			return true, true
+		case slotEmpty:
+			return false, true
+		case slotMoved:
+			return false, false
+		}
+		i = (i + 1) & mask
+	}
+	return false, true
+}
+
+// remove removes v from the table and returns whether it was there. ok
+// is false if the table is being resized.
+func (t *lockFreeTable) remove(v uint64) (removed, ok bool) {
+	mask := uint64(len(t.slots) - 1)
+	i := mix64(v) & mask
+	for probes := 0; probes < len(t.slots); probes++ {
+		slot := &t.slots[i]
+		s := atomic.LoadUint64(slot)
+		for s == v {
+			if atomic.CompareAndSwapUint64(slot, v, slotDeleted) {
+				atomic.AddInt64(&t.n, -1)
+				return true, true
+			}
+			s = atomic.LoadUint64(slot)
+		}
+		switch s {
+		case slotEmpty:
+			return false, true
+		case slotMoved:
+			return false, false
+		}
+		i = (i + 1) & mask
+	}
+	return false, true
+}
+
+// full reports whether the table should be resized.
+func (t *lockFreeTable) full() bool {
+	return atomic.LoadInt64(&t.used)*4 >= int64(len(t.slots))*3
+}
+
+// load returns the current table, making the first one of a zero
+// LockFreeUint64Set under the resize lock, which the caller must not
+// hold then.
+func (set *LockFreeUint64Set) load() *lockFreeTable {
+	if t, ok := set.table.Load().(*lockFreeTable); ok {
+		return t
+	}
+	set.resize.Lock()
+	defer set.resize.Unlock()
+	if t, ok := set.table.Load().(*lockFreeTable); ok {
+		return t
+	}
+	t := newLockFreeTable(0)
+	set.table.Store(t)
+	return t
+}
+
+// grow replaces t by a table with room to spare for its elements,
+// unless another goroutine has replaced it already. Either way, the
+// current table is not being resized when grow returns.
+func (set *LockFreeUint64Set) grow(t *lockFreeTable) {
+	set.resize.Lock()
+	defer set.resize.Unlock()
+	if set.load() == t {
+		set.replace(t, true)
+	}
+}
+
+// replace marks every slot of old as moved and makes a new table the
+// current one, holding the elements of old if keep is set. The caller
+// must hold the resize lock.
+func (set *LockFreeUint64Set) replace(old *lockFreeTable, keep bool) {
+	var vals []uint64
+	for k := range old.slots {
+		slot := &old.slots[k]
+		s := atomic.LoadUint64(slot)
+		for !atomic.CompareAndSwapUint64(slot, s, slotMoved) {
+			s = atomic.LoadUint64(slot)
+		}
+		if keep && s >= lockFreeReserved {
+			vals = append(vals, s)
+		}
+	}
+	// The new table is not shared until it is stored below, so it is
+	// filled without atomic operations.
+	next := newLockFreeTable(4 * len(vals))
+	mask := uint64(len(next.slots) - 1)
+	for _, v := range vals {
+		i := mix64(v) & mask
+		for next.slots[i] != slotEmpty {
+			i = (i + 1) & mask
+		}
+		next.slots[i] = v
+	}
+	next.used, next.n = int64(len(vals)), int64(len(vals))
+	set.table.Store(next)
+}
+
+// Add adds an element to the set. Returns whether
+// the item was added.
+func (set *LockFreeUint64Set) Add(val uint64) bool {
+	if val < lockFreeReserved {
+		return atomic.CompareAndSwapUint32(&set.small[val], 0, 1)
+	}
+	for {
+		t := set.load()
+		added, ok := t.add(val)
+		if ok {
+			if added && t.full() {
+				set.grow(t)
+			}
+			return added
+		}
+		set.grow(t)
+	}
+}
+
+// Append adds all given elements to the set. Returns the
+// number of items that were added.
+func (set *LockFreeUint64Set) Append(vals ...uint64) int {
+	n := 0
+	for _, v := range vals {
+		if set.Add(v) {
+			n++
+		}
+	}
+	return n
+}
+
+// Contains returns whether the given items
+// are all in the set.
+func (set *LockFreeUint64Set) Contains(vals ...uint64) bool {
+	for _, v := range vals {
+		if !set.contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (set *LockFreeUint64Set) contains(val uint64) bool {
+	if val < lockFreeReserved {
+		return atomic.LoadUint32(&set.small[val]) == 1
+	}
+	for {
+		t := set.load()
+		if found, ok := t.contains(val); ok {
+			return found
+		}
+		set.grow(t)
+	}
+}
+
+// Remove remove a single element from the set.
+func (set *LockFreeUint64Set) Remove(val uint64) {
+	if val < lockFreeReserved {
+		atomic.StoreUint32(&set.small[val], 0)
+		return
+	}
+	for {
+		t := set.load()
+		if _, ok := t.remove(val); ok {
+			return
+		}
+		set.grow(t)
+	}
+}
+
+// Size Returns the number of elements in the set.
+func (set *LockFreeUint64Set) Size() int {
+	n := int(atomic.LoadInt64(&set.load().n))
+	for i := range set.small {
+		n += int(atomic.LoadUint32(&set.small[i]))
+	}
+	return n
+}
+
+// Clear removes all elements from the set. Elements added
+// while it runs may or may not be removed.
+func (set *LockFreeUint64Set) Clear() {
+	set.load()
+	set.resize.Lock()
+	defer set.resize.Unlock()
+	set.replace(set.load(), false)
+	for i := range set.small {
+		atomic.StoreUint32(&set.small[i], 0)
+	}
+}
+
+// ToSlice returns the members of the set as a slice. Elements
+// added or removed while it runs may or may not be included.
+func (set *LockFreeUint64Set) ToSlice() []uint64 {
+	for {
+		var vals []uint64
+		for i := range set.small {
+			if atomic.LoadUint32(&set.small[i]) == 1 {
+				vals = append(vals, uint64(i))
+			}
+		}
+		t := set.load()
+		moved := false
+		for k := range t.slots {
+			s := atomic.LoadUint64(&t.slots[k])
+			if s == slotMoved {
+				moved = true
+				break
+			}
+			if s >= lockFreeReserved {
+				vals = append(vals, s)
+			}
+		}
+		if !moved {
+			return vals
+		}
+		set.grow(t)
+	}
+}
+
+// ToSortedSlice returns the members of the set as a sorted slice.
+func (set *LockFreeUint64Set) ToSortedSlice() []uint64 {
+	vals := set.ToSlice()
+	sort.Slice(vals, func(i, j int) bool { return vals[i] < vals[j] })
+	return vals
+}
+
+// Each iterates over a snapshot of the elements, see ToSlice, and
+// executes the passed func against each element. If passed func
+// returns true, stop iteration at the time.
+func (set *LockFreeUint64Set) Each(f func(elem uint64) bool) {
+	for _, v := range set.ToSlice() {
+		if f(v) {
+			break
+		}
+	}
+}
+
+// String provides a convenient string representation
+// of the current state of the set.
+func (set *LockFreeUint64Set) String() string {
+	vals := set.ToSlice()
+	if len(vals) == 0 {
+		return "goset.LockFreeUint64Set{ }"
+	}
+	items := make([]string, 0, len(vals))
+	for _, v := range vals {
+		items = append(items, fmt.Sprint(v))
+	}
+	return fmt.Sprintf("goset.LockFreeUint64Set{ %s }", strings.Join(items, ", "))
+}
+
+// MarshalJSON will marshal the set into a JSON array.
+func (set *LockFreeUint64Set) MarshalJSON() ([]byte, error) {
+	return json.Marshal(set.ToSlice())
+}
+
+// UnmarshalJSON will unmarshal a JSON array of uint64s into the
+// set, adding to its elements.
+func (set *LockFreeUint64Set) UnmarshalJSON(b []byte) error {
+	var vals []uint64
+	if err := json.Unmarshal(b, &vals); err != nil {
+		return err
+	}
+	set.Append(vals...)
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func Test_LockFreeUint64Set(t *testing.T) {
+	s := NewLockFreeUint64Set(0, 1, 2, 3, math.MaxUint64)
+	if s.Size() != 5 || !s.Contains(0, 1, 2, 3, math.MaxUint64) || s.Contains(4) {
+		t.Errorf("Expected 0 to 3 and MaxUint64, got %v", s)
+	}
+	if s.Add(1) || s.Add(3) || !s.Add(4) {
+		t.Errorf("Expected only 4 to be added")
+	}
+	s.Remove(1)
+	s.Remove(3)
+	if s.Contains(1) || s.Contains(3) || s.Size() != 4 {
+		t.Errorf("Expected 1 and 3 to be removed, got %v", s)
+	}
+	if got := s.ToSortedSlice(); !reflect.DeepEqual(got, []uint64{0, 2, 4, math.MaxUint64}) {
+		t.Errorf("Expected [0 2 4 MaxUint64], got %v", got)
+	}
+
+	var z LockFreeUint64Set
+	if err := json.Unmarshal([]byte("[5, 6]"), &z); err != nil || !z.Contains(5, 6) {
+		t.Errorf("Expected the zero value to take 5 and 6, got %v, %v", &z, err)
+	}
+	z.Clear()
+	if z.Size() != 0 || z.Contains(5) {
+		t.Errorf("Expected an empty set after Clear, got %v", &z)
+	}
+	var cleared LockFreeUint64Set
+	cleared.Clear()
+	if !cleared.Add(7) || cleared.Size() != 1 {
+		t.Errorf("Expected a cleared zero value to be usable, got %v", &cleared)
+	}
+}
+
+func Test_LockFreeUint64SetConcurrent(t *testing.T) {
+	var s LockFreeUint64Set
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g uint64) {
+			defer wg.Done()
+			for i := uint64(0); i < N; i++ {
+				v := g*N + i
+				s.Add(v)
+				if !s.Contains(v) {
+					t.Errorf("Expected %d right after adding it", v)
+				}
+				if i%2 == 0 {
+					s.Remove(v)
+				}
+			}
+		}(uint64(g))
+	}
+	wg.Wait()
+	if s.Size() != 8*N/2 {
+		t.Errorf("Expected %d elements, got %d", 8*N/2, s.Size())
+	}
+	for v := uint64(0); v < 8*N; v++ {
+		if s.Contains(v) != (v%N%2 == 1) {
+			t.Errorf("Unexpected membership of %d", v)
+		}
+	}
+}
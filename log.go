@@ -0,0 +1,182 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Operations recorded in a log.
+const (
+	logAdd byte = iota + 1
+	logRemove
+	logClear
+)
+
+// WithLog appends every change to the set to w, as a record that
+// ReplayLog reads to reconstruct the set: each element added or removed
+// by Add, Remove and the other methods, and each Clear. The elements are
+// encoded like by Save.
+//
+// Records are checksummed and written by one call to w.Write each, so a
+// log cut short by a crash replays up to its last complete record. A
+// change is undone if its record can't be written, and the Try methods
+// return the error. Sets derived from the set, like the results of
+// Union or Clone, are not logged.
+func WithLog(w io.Writer) Option {
+	return func(opts *setOptions) {
+		opts.log = w
+	}
+}
+
+// logStore is a store that logs the changes to the store it wraps.
+type logStore struct {
+	store
+	w io.Writer // nil while replaying
+}
+
+func (s *logStore) external() {}
+
+// record writes the record of op on elem to the log.
+func (s *logStore) record(op byte, elem interface{}) error {
+	if s.w == nil {
+		return nil
+	}
+	var payload bytes.Buffer
+	payload.WriteByte(op)
+	if op != logClear {
+		if err := (&snapshotWriter{w: &payload}).elem(elem); err != nil {
+			return err
+		}
+	}
+	var rec bytes.Buffer
+	w := &snapshotWriter{w: &rec}
+	w.bytes(payload.Bytes())
+	binary.Write(&rec, binary.LittleEndian, crc32.ChecksumIEEE(payload.Bytes()))
+	_, err := s.w.Write(rec.Bytes())
+	return err
+}
+
+func (s *logStore) add(val interface{}) (bool, error) {
+	added, err := s.store.add(val)
+	if !added || err != nil {
+		return added, err
+	}
+	if err := s.record(logAdd, val); err != nil {
+		s.store.remove(val)
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *logStore) remove(val interface{}) (bool, error) {
+	stored, ok := s.store.get(val)
+	if !ok {
+		return false, nil
+	}
+	removed, err := s.store.remove(val)
+	if !removed || err != nil {
+		return removed, err
+	}
+	if err := s.record(logRemove, stored); err != nil {
+		s.store.add(stored)
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *logStore) clear() {
+	if err := s.record(logClear, nil); err != nil {
+		panic(fmt.Errorf("goset: writing the log: %v", err))
+	}
+	s.store.clear()
+}
+
+// ReplayLog reconstructs a set from the log written by a set created
+// WithLog, and returns it as a thread-safe set configured by opts. If
+// opts include WithLog, the set goes on logging, e.g. to the end of the
+// file r reads, after the replayed changes, which are not logged again.
+//
+// An incomplete or corrupt last record, as left by a crash while it was
+// written, is ignored. A corrupt record followed by others is an error.
+func ReplayLog(r io.Reader, opts ...Option) (Set, error) {
+	vals := make([]interface{}, len(opts))
+	for i, opt := range opts {
+		vals[i] = opt
+	}
+	set := newSetFrom(vals)
+	ls, _ := set.store.(*logStore)
+	if ls != nil {
+		w := ls.w
+		ls.w = nil
+		defer func() { ls.w = w }()
+	}
+
+	lr := &snapshotReader{r: bufio.NewReader(r)}
+	for {
+		payload, err := lr.bytes()
+		var sum uint32
+		if err == nil {
+			sum, err = lr.uint32()
+		}
+		if err == io.EOF || err == errSnapshotTruncated {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if crc32.ChecksumIEEE(payload) != sum {
+			if _, err := lr.r.ReadByte(); err == io.EOF {
+				break
+			}
+			return nil, errors.New("goset: corrupt record in log")
+		}
+		if err := replayRecord(&set, payload); err != nil {
+			return nil, err
+		}
+	}
+	return set.ToThreadSafe(), nil
+}
+
+// replayRecord applies the logged change in payload to set.
+func replayRecord(set *ThreadUnsafeSet, payload []byte) error {
+	pr := &snapshotReader{r: bytes.NewReader(payload)}
+	op, err := pr.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if op == logClear {
+		set.Clear()
+		return nil
+	}
+	elem, err := pr.elem()
+	if err != nil {
+		return err
+	}
+	switch op {
+	case logAdd:
+		_, err = set.TryAdd(elem)
+	case logRemove:
+		err = set.TryRemove(elem)
+	default:
+		err = fmt.Errorf("goset: unknown operation %d in log", op)
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func Test_Log(t *testing.T) {
+	var log bytes.Buffer
+	s := NewSet(WithLog(&log), "a", "b")
+	s.Add("c")
+	s.Add("a")
+	s.Remove("b")
+	s.Clone().Add("cloned")
+	before := log.Len()
+	s.Remove("missing")
+	if log.Len() != before {
+		t.Errorf("Expected no record for a change that didn't happen")
+	}
+
+	replayed, err := ReplayLog(bytes.NewReader(log.Bytes()))
+	if err != nil || !replayed.Equal(NewSet("a", "c")) {
+		t.Errorf("Expected {a, c} to be replayed, got %v, %v", replayed, err)
+	}
+
+	s.Clear()
+	s.Add("d")
+	replayed, err = ReplayLog(bytes.NewReader(log.Bytes()))
+	if err != nil || !replayed.Equal(NewSet("d")) {
+		t.Errorf("Expected Clear to be replayed, got %v, %v", replayed, err)
+	}
+
+	torn := log.Bytes()[:log.Len()-2]
+	replayed, err = ReplayLog(bytes.NewReader(torn))
+	if err != nil || replayed.Size() != 0 {
+		t.Errorf("Expected a torn last record to be ignored, got %v, %v", replayed, err)
+	}
+	corrupt := append([]byte(nil), log.Bytes()...)
+	corrupt[2]++
+	if _, err := ReplayLog(bytes.NewReader(corrupt)); err == nil {
+		t.Errorf("Expected an error for a corrupt record followed by others")
+	}
+
+	var more bytes.Buffer
+	resumed, err := ReplayLog(bytes.NewReader(log.Bytes()), WithLog(&more))
+	if err != nil || more.Len() != 0 {
+		t.Fatalf("Expected replayed changes not to be logged again, got %d bytes, %v", more.Len(), err)
+	}
+	resumed.Add("e")
+	log.Write(more.Bytes())
+	if replayed, _ := ReplayLog(&log); !replayed.Equal(NewSet("d", "e")) {
+		t.Errorf("Expected the resumed log to continue the old one, got %v", replayed)
+	}
+
+	failing := NewSet(WithLog(failingWriter{}))
+	if _, err := failing.TryAdd(1); err == nil || failing.Size() != 0 {
+		t.Errorf("Expected a change to be undone if it can't be logged")
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+//go:generate go run gen_keysets.go
+
+// AsSet returns a Set view of m. The view is backed directly by m instead
+// of a copy of it: elements added or removed through the Set show up in
+// m and vice versa, which lets code built around a raw map adopt the Set
+// API incrementally.
+//
+// Like m itself, the view is not thread-safe. Operations returning a new
+// set, such as Union or Clone, return views over new maps.
+func AsSet(m map[string]struct{}) Set {
+	return StringSet(m).AsSet()
+}
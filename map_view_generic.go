@@ -0,0 +1,179 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "reflect"
+
+// AsSetOf is like AsSet for maps of any comparable element type. Elements
+// are identified by Go equality, so they don't need to be hashable.
+func AsSetOf[T comparable](m map[T]struct{}) Set {
+	return &ThreadUnsafeSet{store: mapStore[T](m), typ: elemType[T]()}
+}
+
+// AsBoolSetOf returns a Set view of m, in which the keys mapped to true
+// are the elements of the set. Adding an element maps it to true,
+// removing it deletes it from m. Size is linear in the size of m.
+func AsBoolSetOf[T comparable](m map[T]bool) Set {
+	return &ThreadUnsafeSet{store: boolMapStore[T](m), typ: elemType[T]()}
+}
+
+// elemType returns the type that pins the element type of a view, which
+// is nil if T is an interface type, as its values have various types.
+func elemType[T any]() reflect.Type {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() == reflect.Interface {
+		return nil
+	}
+	return typ
+}
+
+func viewTypeError[T any](val interface{}) error {
+	return wrongType(val, "%T can't be stored in a view of a map of %s", val, reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// mapStore is a store over a caller owned map[T]struct{}.
+type mapStore[T comparable] map[T]struct{}
+
+func (s mapStore[T]) add(val interface{}) (bool, error) {
+	v, ok := val.(T)
+	if !ok {
+		return false, viewTypeError[T](val)
+	}
+	if _, ok := s[v]; ok {
+		return false, nil
+	}
+	s[v] = struct{}{}
+	return true, nil
+}
+
+func (s mapStore[T]) get(val interface{}) (interface{}, bool) {
+	v, ok := val.(T)
+	if !ok {
+		return nil, false
+	}
+	_, ok = s[v]
+	return v, ok
+}
+
+func (s mapStore[T]) remove(val interface{}) (bool, error) {
+	v, ok := val.(T)
+	if !ok {
+		return false, viewTypeError[T](val)
+	}
+	if _, ok := s[v]; !ok {
+		return false, nil
+	}
+	delete(s, v)
+	return true, nil
+}
+
+func (s mapStore[T]) len() int {
+	return len(s)
+}
+
+func (s mapStore[T]) each(f func(val interface{}) bool) {
+	for v := range s {
+		if f(v) {
+			break
+		}
+	}
+}
+
+func (s mapStore[T]) clear() {
+	for v := range s {
+		delete(s, v)
+	}
+}
+
+// grow is a no-op, the map is owned by the caller.
+func (s mapStore[T]) grow(n int) {}
+
+func (s mapStore[T]) empty(capacity int) store {
+	return make(mapStore[T], capacity)
+}
+
+// external keeps the set on the caller's map, see externalStore.
+func (s mapStore[T]) external() {}
+
+// boolMapStore is a store over a caller owned map[T]bool.
+type boolMapStore[T comparable] map[T]bool
+
+func (s boolMapStore[T]) add(val interface{}) (bool, error) {
+	v, ok := val.(T)
+	if !ok {
+		return false, viewTypeError[T](val)
+	}
+	if s[v] {
+		return false, nil
+	}
+	s[v] = true
+	return true, nil
+}
+
+func (s boolMapStore[T]) get(val interface{}) (interface{}, bool) {
+	v, ok := val.(T)
+	if !ok {
+		return nil, false
+	}
+	return v, s[v]
+}
+
+func (s boolMapStore[T]) remove(val interface{}) (bool, error) {
+	v, ok := val.(T)
+	if !ok {
+		return false, viewTypeError[T](val)
+	}
+	if !s[v] {
+		return false, nil
+	}
+	delete(s, v)
+	return true, nil
+}
+
+func (s boolMapStore[T]) len() int {
+	n := 0
+	for _, member := range s {
+		if member {
+			n++
+		}
+	}
+	return n
+}
+
+func (s boolMapStore[T]) each(f func(val interface{}) bool) {
+	for v, member := range s {
+		if member && f(v) {
+			break
+		}
+	}
+}
+
+func (s boolMapStore[T]) clear() {
+	for v := range s {
+		delete(s, v)
+	}
+}
+
+// grow is a no-op, the map is owned by the caller.
+func (s boolMapStore[T]) grow(n int) {}
+
+func (s boolMapStore[T]) empty(capacity int) store {
+	return make(boolMapStore[T], capacity)
+}
+
+// external keeps the set on the caller's map, see externalStore.
+func (s boolMapStore[T]) external() {}
@@ -0,0 +1,272 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Layout of the files written by WriteMappedSet, in little endian:
+//
+//	header  magic "GSMM", version, flags, 2 bytes padding,
+//	        number of elements and number of slots as uint64
+//	slots   per slot, the hash of the key of an element as uint64 and
+//	        the offset of its entry in the file as uint64, 0 if empty
+//	entries per element, the length of its key as uvarint, its key and
+//	        the element encoded like by Save
+//
+// The slots form an open-addressed hash table with linear probing,
+// which is at most half full.
+const (
+	mappedMagic      = "GSMM"
+	mappedVersion    = 1
+	mappedHeaderSize = 24
+	mappedSlotSize   = 16
+)
+
+var errMappedCorrupt = errors.New("goset: corrupt mapped set")
+
+// mappedHash returns the hash of key that places it in the table. Unlike
+// hashKey, it is the same in all processes.
+func mappedHash(key []byte) uint64 {
+	hash := uint64(14695981039346656037)
+	for _, b := range key {
+		hash ^= uint64(b)
+		hash *= 1099511628211
+	}
+	return hash
+}
+
+// WriteMappedSet writes the elements of set to w in the layout that
+// OpenMappedSet maps into memory. Elements of the natively supported
+// types are written as they are, others are gob-encoded, so their types
+// must be registered with gob.Register. The entries are built in memory
+// before they are written.
+func WriteMappedSet(w io.Writer, set Set) error {
+	n := set.Size()
+	slots := 2
+	for slots < 2*n {
+		slots *= 2
+	}
+	table := make([]byte, slots*mappedSlotSize)
+	var entries bytes.Buffer
+	ew := &snapshotWriter{w: &entries}
+	base := uint64(mappedHeaderSize + len(table))
+	mixed := false
+	var typ reflect.Type
+	var err error
+	set.Each(func(elem interface{}) bool {
+		var key []byte
+		if key, err = kvKey(elem); err != nil {
+			return true
+		}
+		if t := reflect.TypeOf(elem); typ == nil {
+			typ = t
+		} else if t != typ {
+			mixed = true
+		}
+		hash := mappedHash(key)
+		i := hash & uint64(slots-1)
+		for binary.LittleEndian.Uint64(table[i*mappedSlotSize+8:]) != 0 {
+			i = (i + 1) & uint64(slots-1)
+		}
+		binary.LittleEndian.PutUint64(table[i*mappedSlotSize:], hash)
+		binary.LittleEndian.PutUint64(table[i*mappedSlotSize+8:], base+uint64(entries.Len()))
+		ew.bytes(key)
+		err = ew.elem(elem)
+		return err != nil
+	})
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, mappedHeaderSize)
+	copy(header, mappedMagic)
+	header[4] = mappedVersion
+	if mixed {
+		header[5] |= snapshotMixed
+	}
+	binary.LittleEndian.PutUint64(header[8:], uint64(n))
+	binary.LittleEndian.PutUint64(header[16:], uint64(slots))
+	for _, b := range [][]byte{header, table, entries.Bytes()} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MappedSet is a read-only set over a file written by WriteMappedSet,
+// which is mapped into memory rather than read, where the platform
+// supports it. Opening it takes no time regardless of its size, and
+// processes mapping the same file share its pages.
+//
+// Lookups hash the element and decode the entries it probes, so they
+// are slower than in a set in memory. Changes fail with ErrReadOnly:
+// TryAdd and TryRemove return it, and the methods without an error
+// result, like Add, Remove and Clear, panic with it. Sets derived from
+// a MappedSet, like the results of Union or Clone, are held in memory. A MappedSet can be used concurrently, but not
+// after Close.
+type MappedSet struct {
+	*ThreadSafeSet
+	data []byte
+}
+
+// OpenMappedSet maps the file at path, written by WriteMappedSet, into
+// memory and returns the set it holds.
+func OpenMappedSet(path string) (*MappedSet, error) {
+	data, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newMappedStore(data)
+	if err != nil {
+		unmapFile(data)
+		return nil, err
+	}
+	set := ThreadUnsafeSet{store: s}
+	set.conf.mixed = data[5]&snapshotMixed != 0
+	s.each(func(elem interface{}) bool {
+		set.typ = reflect.TypeOf(elem)
+		return true
+	})
+	return &MappedSet{ThreadSafeSet: set.ToThreadSafe(), data: data}, nil
+}
+
+// Close unmaps the file of the set.
+func (set *MappedSet) Close() error {
+	set.Lock()
+	defer set.Unlock()
+	if set.data == nil {
+		return nil
+	}
+	err := unmapFile(set.data)
+	set.data = nil
+	set.unsafeSet = newThreadUnsafeSet()
+	return err
+}
+
+// Clear panics with ErrReadOnly, as the set can't be changed.
+func (set *MappedSet) Clear() {
+	panic(ErrReadOnly)
+}
+
+// mappedStore is a read-only store over the layout of WriteMappedSet.
+type mappedStore struct {
+	data  []byte
+	n     int
+	slots uint64
+}
+
+func newMappedStore(data []byte) (*mappedStore, error) {
+	if len(data) < mappedHeaderSize || string(data[:4]) != mappedMagic {
+		return nil, errors.New("goset: not a mapped set")
+	}
+	if data[4] != mappedVersion {
+		return nil, fmt.Errorf("goset: unsupported mapped set version %d", data[4])
+	}
+	n := binary.LittleEndian.Uint64(data[8:])
+	slots := binary.LittleEndian.Uint64(data[16:])
+	if slots == 0 || slots&(slots-1) != 0 || n >= slots ||
+		uint64(len(data)-mappedHeaderSize)/mappedSlotSize < slots {
+		return nil, errMappedCorrupt
+	}
+	return &mappedStore{data: data, n: int(n), slots: slots}, nil
+}
+
+// entry returns the key and the reader of the element of the entry of
+// slot i, or false if the slot is empty.
+func (s *mappedStore) entry(i uint64) (hash uint64, key []byte, r *snapshotReader, ok bool) {
+	slot := s.data[mappedHeaderSize+i*mappedSlotSize:]
+	hash = binary.LittleEndian.Uint64(slot)
+	off := binary.LittleEndian.Uint64(slot[8:])
+	if off == 0 {
+		return 0, nil, nil, false
+	}
+	if off >= uint64(len(s.data)) {
+		panic(errMappedCorrupt)
+	}
+	entry := s.data[off:]
+	n, size := binary.Uvarint(entry)
+	if size <= 0 || n > uint64(len(entry)-size) {
+		panic(errMappedCorrupt)
+	}
+	key = entry[size : size+int(n)]
+	return hash, key, &snapshotReader{r: bytes.NewReader(entry[size+int(n):])}, true
+}
+
+// elem decodes the element r reads.
+func (s *mappedStore) elem(r *snapshotReader) interface{} {
+	elem, err := r.elem()
+	if err != nil {
+		panic(fmt.Errorf("%v: %v", errMappedCorrupt, err))
+	}
+	return elem
+}
+
+func (s *mappedStore) get(val interface{}) (interface{}, bool) {
+	key, err := kvKey(val)
+	if err != nil {
+		return nil, false
+	}
+	hash := mappedHash(key)
+	for i := hash & (s.slots - 1); ; i = (i + 1) & (s.slots - 1) {
+		h, k, r, ok := s.entry(i)
+		if !ok {
+			return nil, false
+		}
+		if h == hash && bytes.Equal(k, key) {
+			return s.elem(r), true
+		}
+	}
+}
+
+func (s *mappedStore) add(val interface{}) (bool, error) {
+	return false, ErrReadOnly
+}
+
+func (s *mappedStore) remove(val interface{}) (bool, error) {
+	return false, ErrReadOnly
+}
+
+func (s *mappedStore) len() int {
+	return s.n
+}
+
+func (s *mappedStore) each(f func(val interface{}) bool) {
+	for i := uint64(0); i < s.slots; i++ {
+		if _, _, r, ok := s.entry(i); ok && f(s.elem(r)) {
+			return
+		}
+	}
+}
+
+// clear does nothing: MappedSet.Clear panics before it gets here, and
+// the callers that refill the store, like Scan, get ErrReadOnly
+// from add.
+func (s *mappedStore) clear() {}
+
+func (s *mappedStore) grow(n int) {}
+
+// empty returns an in-memory store, derived sets aren't mapped.
+func (s *mappedStore) empty(capacity int) store {
+	return newHashStore(capacity)
+}
+
+func (s *mappedStore) external() {}
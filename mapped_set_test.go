@@ -0,0 +1,87 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_MappedSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := NewSet()
+	for i := 0; i < 1000; i++ {
+		src.Add(i * 3)
+	}
+	var buf bytes.Buffer
+	if err := WriteMappedSet(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "ids")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := OpenMappedSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Equal(src) || !s.Contains(2997) || s.Contains(1) {
+		t.Errorf("Expected the mapped set to hold the elements it was written with")
+	}
+	if _, err := s.TryAdd(1); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if _, err := s.TryAdd("a"); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected the element type to be restored, got %v", err)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != ErrReadOnly {
+				t.Errorf("Expected Clear to panic with ErrReadOnly, got %v", r)
+			}
+		}()
+		s.Clear()
+	}()
+	if err := s.Scan("{1}"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if s.Size() != 1000 {
+		t.Errorf("Expected Clear and Scan to leave the mapped set intact, got %v elements", s.Size())
+	}
+	clone := s.Clone()
+	clone.Add(1)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if clone.Size() != 1001 || !clone.Contains(0) {
+		t.Errorf("Expected clones to be usable after Close")
+	}
+
+	if err := ioutil.WriteFile(path, buf.Bytes()[:40], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenMappedSet(path); err == nil {
+		t.Errorf("Expected an error for a truncated file")
+	}
+}
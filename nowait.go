@@ -0,0 +1,138 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// The bounds of the pause between two attempts of lockContext to take
+// the lock of a set.
+const (
+	minLockBackoff = time.Microsecond
+	maxLockBackoff = time.Millisecond
+)
+
+// AddNoWait is like Add, but returns right away with ok set to false,
+// leaving the set untouched, if the set is locked.
+func (set *ThreadSafeSet) AddNoWait(val interface{}) (added, ok bool) {
+	if !set.TryLock() {
+		return false, false
+	}
+	added = set.unsafeSet.Add(val)
+	set.Unlock()
+	return added, true
+}
+
+// RemoveNoWait is like Remove, but returns false right away, leaving the
+// set untouched, if the set is locked.
+func (set *ThreadSafeSet) RemoveNoWait(val interface{}) bool {
+	if !set.TryLock() {
+		return false
+	}
+	set.unsafeSet.Remove(val)
+	set.Unlock()
+	return true
+}
+
+// ContainsNoWait is like Contains, but returns right away with ok set to
+// false if the set is locked for writing. A set with lock-free reads,
+// see WithSyncMapBackend and WithReadMostly, is never busy.
+func (set *ThreadSafeSet) ContainsNoWait(vals ...interface{}) (found, ok bool) {
+	if set.reader != nil {
+		return set.Contains(vals...), true
+	}
+	if !set.TryRLock() {
+		return false, false
+	}
+	found = set.unsafeSet.Contains(vals...)
+	set.RUnlock()
+	return found, true
+}
+
+// AddContext is like TryAdd, but gives up waiting for the lock of the
+// set when ctx is done, and returns an *OperationError then.
+func (set *ThreadSafeSet) AddContext(ctx context.Context, val interface{}) (bool, error) {
+	if err := set.lockContext(ctx, "add", true); err != nil {
+		return false, err
+	}
+	defer set.Unlock()
+	return set.unsafeSet.TryAdd(val)
+}
+
+// RemoveContext is like TryRemove, but gives up waiting for the lock of
+// the set when ctx is done, and returns an *OperationError then.
+func (set *ThreadSafeSet) RemoveContext(ctx context.Context, val interface{}) error {
+	if err := set.lockContext(ctx, "remove", true); err != nil {
+		return err
+	}
+	defer set.Unlock()
+	return set.unsafeSet.TryRemove(val)
+}
+
+// ContainsContext is like Contains, but gives up waiting for the lock of
+// the set when ctx is done, and returns an *OperationError then.
+func (set *ThreadSafeSet) ContainsContext(ctx context.Context, vals ...interface{}) (bool, error) {
+	if set.reader != nil {
+		return set.Contains(vals...), nil
+	}
+	if err := set.lockContext(ctx, "contains", false); err != nil {
+		return false, err
+	}
+	defer set.RUnlock()
+	return set.unsafeSet.Contains(vals...), nil
+}
+
+// lockContext locks set for writing if write is set, or else for
+// reading, polling the lock with a growing pause between attempts. It
+// returns an *OperationError for op, without the lock, if ctx is done
+// first. The time it waits is measured for an InstrumentedSet.
+func (set *ThreadSafeSet) lockContext(ctx context.Context, op string, write bool) error {
+	try := set.TryRLock
+	if write {
+		try = set.TryLock
+	}
+	if err := ctx.Err(); err != nil {
+		return &OperationError{Op: op, Err: err}
+	}
+	if try() {
+		return nil
+	}
+	start := time.Now()
+	if c, _ := set.counters.Load().(*setCounters); c != nil {
+		defer func() { atomic.AddInt64(&c.lockWait, int64(time.Since(start))) }()
+	}
+	backoff := minLockBackoff
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return &OperationError{Op: op, Err: ctx.Err()}
+		case <-timer.C:
+		}
+		if try() {
+			return nil
+		}
+		if backoff *= 2; backoff > maxLockBackoff {
+			backoff = maxLockBackoff
+		}
+		timer.Reset(backoff)
+	}
+}
@@ -0,0 +1,87 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_NoWaitBusy(t *testing.T) {
+	s := NewSet(1).(*ThreadSafeSet)
+
+	s.Lock()
+	if _, ok := s.AddNoWait(2); ok {
+		t.Errorf("Expected AddNoWait to fail on a locked set")
+	}
+	if s.RemoveNoWait(1) {
+		t.Errorf("Expected RemoveNoWait to fail on a locked set")
+	}
+	if _, ok := s.ContainsNoWait(1); ok {
+		t.Errorf("Expected ContainsNoWait to fail on a locked set")
+	}
+	s.Unlock()
+
+	if added, ok := s.AddNoWait(2); !added || !ok {
+		t.Errorf("Expected 2 to be added, got %v, %v", added, ok)
+	}
+	s.RLock()
+	if found, ok := s.ContainsNoWait(1, 2); !found || !ok {
+		t.Errorf("Expected 1 and 2 to be found under a read lock, got %v, %v", found, ok)
+	}
+	s.RUnlock()
+	if !s.RemoveNoWait(1) || s.Contains(1) {
+		t.Errorf("Expected 1 to be removed, got %v", s)
+	}
+}
+
+func Test_AddContextDeadline(t *testing.T) {
+	s := NewSet(1).(*ThreadSafeSet)
+
+	s.RLock()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	added, err := s.AddContext(ctx, 2)
+	opErr, ok := err.(*OperationError)
+	if added || !ok || opErr.Err != context.DeadlineExceeded {
+		t.Errorf("Expected a deadline *OperationError, got %v, %v", added, err)
+	}
+	if found, err := s.ContainsContext(ctx, 1); found || err == nil {
+		t.Errorf("Expected ContainsContext to fail with a done context, got %v, %v", found, err)
+	}
+	s.RUnlock()
+	if s.Contains(2) {
+		t.Errorf("Set was modified by an aborted add")
+	}
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		s.Unlock()
+	}()
+	s.Lock()
+	added, err = s.AddContext(context.Background(), 2)
+	if !added || err != nil {
+		t.Errorf("Expected 2 to be added once the lock is free, got %v, %v", added, err)
+	}
+	if _, err := s.AddContext(context.Background(), []int{}); err == nil {
+		t.Errorf("Expected an error adding an unhashable element")
+	}
+	if err := s.RemoveContext(context.Background(), 1); err != nil || s.Contains(1) {
+		t.Errorf("Expected 1 to be removed, got %v (%v)", s, err)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "io"
+
+// Option configures a set created by NewSet or NewThreadUnsafeSet.
+// Options are passed along with the elements of the set, in any
+// position:
+//
+//	set := goset.NewSet(goset.WithRoaringBitmap(), uint32(1), uint32(2))
+type Option func(*setOptions)
+
+type setOptions struct {
+	newStore func() store
+	conf     setConf
+	log      io.Writer // See WithLog
+
+	onAdd, onRemove []func(elem interface{}) // See OnAdd and OnRemove
+}
+
+// newSetFrom returns a new set configured by the Options among vals,
+// with the other values of vals as its elements.
+func newSetFrom(vals []interface{}) ThreadUnsafeSet {
+	opts := setOptions{
+		newStore: func() store { return newHashStore(0) },
+	}
+	for _, v := range vals {
+		if opt, ok := v.(Option); ok {
+			opt(&opts)
+		}
+	}
+	s := ThreadUnsafeSet{store: opts.newStore(), conf: opts.conf}
+	if opts.log != nil {
+		s.store = &logStore{store: s.store, w: opts.log}
+	}
+	if opts.onAdd != nil || opts.onRemove != nil {
+		s.store = &hookStore{store: s.store, onAdd: opts.onAdd, onRemove: opts.onRemove}
+	}
+	for _, v := range vals {
+		if _, ok := v.(Option); !ok {
+			s.Add(v)
+		}
+	}
+	return s
+}
+
+// WithRoaringBitmap stores the elements of the set, which must be all
+// uint32 or all uint64, in a compressed bitmap, see roaringStore.
+func WithRoaringBitmap() Option {
+	return func(opts *setOptions) {
+		opts.newStore = func() store { return &roaringStore{} }
+	}
+}
+
+// WithMixedTypes lets the set hold elements of different types, like the
+// numbers, strings and bools of a decoded JSON array. Elements of
+// different types are always different elements, so 1 and int64(1) are
+// both stored. By default, the type of the first element added to a set
+// is the type of all of its elements.
+func WithMixedTypes() Option {
+	return func(opts *setOptions) {
+		opts.conf.mixed = true
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"container/list"
+	"reflect"
+)
+
+// NewOrderedSet creates and returns a new set with the given elements
+// that remembers the order elements were first added in. Each, Iter,
+// Iterator, ToSlice, String and MarshalJSON visit elements in that
+// order, and sets returned by its operations, such as Union or Clone,
+// are ordered as well.
+// Operations on the resulting set are thread-safe.
+func NewOrderedSet(vals ...interface{}) Set {
+	s := &ThreadSafeSet{unsafeSet: ThreadUnsafeSet{store: newOrderedStore()}}
+	for _, item := range vals {
+		s.Add(item)
+	}
+	return s
+}
+
+// NewThreadUnsafeOrderedSet creates and returns a new insertion-ordered
+// set with the given elements, see NewOrderedSet.
+// Operations on the resulting set are not thread-safe.
+func NewThreadUnsafeOrderedSet(vals ...interface{}) Set {
+	s := &ThreadUnsafeSet{store: newOrderedStore()}
+	for _, item := range vals {
+		s.Add(item)
+	}
+	return s
+}
+
+// orderedStore is a store that keeps its elements in a list in
+// insertion order, next to a hash index into the list.
+type orderedStore struct {
+	index listIndex
+	order *list.List
+}
+
+func newOrderedStore() *orderedStore {
+	return &orderedStore{index: listIndex{}, order: list.New()}
+}
+
+func (s *orderedStore) add(val interface{}) (bool, error) {
+	hash, err := calcHash(val)
+	if err != nil {
+		return false, err
+	}
+	if s.index.find(val, hash) != nil {
+		return false, nil
+	}
+	s.index.insert(hash, s.order.PushBack(val))
+	return true, nil
+}
+
+func (s *orderedStore) get(val interface{}) (interface{}, bool) {
+	hash, err := calcHash(val)
+	if err != nil {
+		return nil, false
+	}
+	node := s.index.find(val, hash)
+	if node == nil {
+		return nil, false
+	}
+	return node.Value, true
+}
+
+func (s *orderedStore) remove(val interface{}) (bool, error) {
+	hash, err := calcHash(val)
+	if err != nil {
+		return false, err
+	}
+	node := s.index.find(val, hash)
+	if node == nil {
+		return false, nil
+	}
+	s.order.Remove(node)
+	s.index.delete(hash, node)
+	return true, nil
+}
+
+func (s *orderedStore) len() int {
+	return s.order.Len()
+}
+
+func (s *orderedStore) each(f func(val interface{}) bool) {
+	for node := s.order.Front(); node != nil; {
+		// Fetch the successor first, f may remove the current node.
+		next := node.Next()
+		if f(node.Value) {
+			break
+		}
+		node = next
+	}
+}
+
+func (s *orderedStore) clear() {
+	s.index = listIndex{}
+	s.order.Init()
+}
+
+func (s *orderedStore) grow(n int) {
+	index := make(listIndex, len(s.index)+n)
+	for key, bucket := range s.index {
+		index[key] = bucket
+	}
+	s.index = index
+}
+
+func (s *orderedStore) empty(capacity int) store {
+	return &orderedStore{index: make(listIndex, capacity), order: list.New()}
+}
+
+// listIndex indexes the nodes of a list by the hashes of their values.
+// Like the buckets of a hashStore, it tells values with the same hash
+// apart by their types and Equal methods.
+type listIndex map[uint64][]listEntry // Store {hashKey($hash): [{$node, $hash}...]} of elem
+
+type listEntry struct {
+	node *list.Element
+	hash string
+}
+
+// find returns the node of val, or nil if it isn't indexed.
+func (idx listIndex) find(val interface{}, hash string) *list.Element {
+	typ := reflect.TypeOf(val)
+	for _, e := range idx[hashKey(hash)] {
+		if reflect.TypeOf(e.node.Value) == typ && sameElem(e.node.Value, e.hash, val, hash) {
+			return e.node
+		}
+	}
+	return nil
+}
+
+func (idx listIndex) insert(hash string, node *list.Element) {
+	key := hashKey(hash)
+	idx[key] = append(idx[key], listEntry{node: node, hash: hash})
+}
+
+func (idx listIndex) delete(hash string, node *list.Element) {
+	key := hashKey(hash)
+	bucket := idx[key]
+	for i, e := range bucket {
+		if e.node != node {
+			continue
+		}
+		if len(bucket) == 1 {
+			delete(idx, key)
+		} else {
+			bucket[i] = bucket[len(bucket)-1]
+			bucket[len(bucket)-1] = listEntry{}
+			idx[key] = bucket[:len(bucket)-1]
+		}
+		return
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "testing"
+
+func Test_OrderedSet(t *testing.T) {
+	s := NewOrderedSet(5, 3, 9, 3, 1)
+	s.Remove(9)
+	s.Add(7)
+
+	expected := []interface{}{5, 3, 1, 7}
+	for i, obj := range s.ToSlice() {
+		if obj != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, s.ToSlice())
+		}
+	}
+	if b, _ := s.MarshalJSON(); string(b) != "[5,3,1,7]" {
+		t.Errorf("Expected JSON in insertion order, got %s", b)
+	}
+	if union := s.Union(NewOrderedSet(2, 5)); union.String() != "goset.ThreadUnsafeSet{ 5, 3, 1, 7, 2 }" {
+		t.Errorf("Expected an ordered union, got %v", union)
+	}
+	if obj, _ := NewThreadUnsafeOrderedSet(4, 2).Pop(); obj != 4 {
+		t.Errorf("Expected Pop to remove the oldest element, got %v", obj)
+	}
+}
+
+func Test_OrderedSetHashCollisions(t *testing.T) {
+	s := NewOrderedSet(parityInt(1), parityInt(3), parityInt(3), parityInt(2))
+	if s.Size() != 3 || !s.Contains(parityInt(3)) || s.Contains(parityInt(5)) {
+		t.Errorf("Expected colliding elements to be kept apart, got %v", s)
+	}
+	s.Remove(parityInt(1))
+	if s.Size() != 2 || !s.Contains(parityInt(3)) || s.Contains(parityInt(1)) {
+		t.Errorf("Expected only parityInt(1) to be removed, got %v", s)
+	}
+	if elem, ok := s.Get(parityInt(2)); !ok || elem != parityInt(2) {
+		t.Errorf("Expected to get parityInt(2), got %v", elem)
+	}
+}
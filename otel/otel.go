@@ -0,0 +1,250 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otel traces the bulk operations of goset sets with
+// OpenTelemetry, to find slow operations on large sets in distributed
+// traces.
+//
+//	set := otel.Wrap(goset.NewSet())
+//	union := set.WithContext(ctx).Union(other) // a goset.Union span in the trace of ctx
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/b1tkeeper/goset"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans of the package.
+const instrumentationName = "github.com/b1tkeeper/goset/otel"
+
+// Attributes of the spans.
+const (
+	SizeKey       = attribute.Key("goset.size")        // Size of the set before the operation
+	OtherSizeKey  = attribute.Key("goset.other.size")  // Size of the other set, or number of elements passed
+	ResultSizeKey = attribute.Key("goset.result.size") // Size of the result, or of the set after the operation
+)
+
+// Option configures a TracedSet.
+type Option func(*TracedSet)
+
+// WithTracerProvider sets the TracerProvider creating the spans, instead
+// of the global one.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(set *TracedSet) {
+		set.tracer = tp.Tracer(instrumentationName)
+	}
+}
+
+// TracedSet is a goset.Set that records a span for each bulk operation:
+// the set algebra, Append, RemoveAll, Clone, Diff and Apply. The spans
+// have the sizes of the operands and of the result as attributes, and
+// errors, including panics, as their status. The other methods are not
+// traced.
+//
+// The spans are children of the span of the context of the set, see
+// WithContext, or of the context passed to the operations taking one,
+// like UnionContext.
+type TracedSet struct {
+	goset.Set
+	ctx    context.Context
+	tracer trace.Tracer
+}
+
+// Wrap returns set traced as configured by opts.
+func Wrap(set goset.Set, opts ...Option) *TracedSet {
+	ts := &TracedSet{Set: set, ctx: context.Background()}
+	for _, opt := range opts {
+		opt(ts)
+	}
+	if ts.tracer == nil {
+		ts.tracer = otel.GetTracerProvider().Tracer(instrumentationName)
+	}
+	return ts
+}
+
+// WithContext returns a copy of the set, sharing its elements, whose
+// spans are children of the span of ctx.
+func (set *TracedSet) WithContext(ctx context.Context) *TracedSet {
+	ts := *set
+	ts.ctx = ctx
+	return &ts
+}
+
+// Unwrap returns the traced set.
+func (set *TracedSet) Unwrap() goset.Set {
+	return set.Set
+}
+
+// trace runs op in a span named after it, in the trace of ctx. other is
+// the size of the other operand, if any, and result returns the size of
+// the result. Errors and panics are recorded before being passed on.
+func (set *TracedSet) trace(ctx context.Context, name string, other int, op func() error, result func() int) {
+	attrs := []attribute.KeyValue{SizeKey.Int(set.Set.Size())}
+	if other >= 0 {
+		attrs = append(attrs, OtherSizeKey.Int(other))
+	}
+	_, span := set.tracer.Start(ctx, "goset."+name, trace.WithAttributes(attrs...))
+	defer span.End()
+	defer func() {
+		if r := recover(); r != nil {
+			span.SetStatus(codes.Error, "panic")
+			span.AddEvent("panic", trace.WithAttributes(attribute.String("goset.panic", fmt.Sprint(r))))
+			panic(r)
+		}
+	}()
+	if err := op(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	span.SetAttributes(ResultSizeKey.Int(result()))
+}
+
+// setOp traces op, which combines the set with other into a new set.
+func (set *TracedSet) setOp(ctx context.Context, name string, other goset.Set, op func() (goset.Set, error)) (goset.Set, error) {
+	var ret goset.Set
+	var err error
+	set.trace(ctx, name, other.Size(), func() error {
+		ret, err = op()
+		return err
+	}, func() int { return ret.Size() })
+	return ret, err
+}
+
+// inPlace traces op, which changes the set, with n the size of its
+// other operand.
+func (set *TracedSet) inPlace(name string, n int, op func()) {
+	set.trace(set.ctx, name, n, func() error {
+		op()
+		return nil
+	}, set.Set.Size)
+}
+
+// mustSetOp is like setOp for operations that don't fail.
+func (set *TracedSet) mustSetOp(name string, other goset.Set, op func() goset.Set) goset.Set {
+	ret, _ := set.setOp(set.ctx, name, other, func() (goset.Set, error) {
+		return op(), nil
+	})
+	return ret
+}
+
+func (set *TracedSet) Union(other goset.Set) goset.Set {
+	return set.mustSetOp("Union", other, func() goset.Set { return set.Set.Union(other) })
+}
+
+func (set *TracedSet) Intersect(other goset.Set) goset.Set {
+	return set.mustSetOp("Intersect", other, func() goset.Set { return set.Set.Intersect(other) })
+}
+
+func (set *TracedSet) Difference(other goset.Set) goset.Set {
+	return set.mustSetOp("Difference", other, func() goset.Set { return set.Set.Difference(other) })
+}
+
+func (set *TracedSet) SymmetricDifference(other goset.Set) goset.Set {
+	return set.mustSetOp("SymmetricDifference", other, func() goset.Set { return set.Set.SymmetricDifference(other) })
+}
+
+func (set *TracedSet) TryUnion(other goset.Set) (goset.Set, error) {
+	return set.setOp(set.ctx, "TryUnion", other, func() (goset.Set, error) { return set.Set.TryUnion(other) })
+}
+
+func (set *TracedSet) TryIntersect(other goset.Set) (goset.Set, error) {
+	return set.setOp(set.ctx, "TryIntersect", other, func() (goset.Set, error) { return set.Set.TryIntersect(other) })
+}
+
+func (set *TracedSet) TryDifference(other goset.Set) (goset.Set, error) {
+	return set.setOp(set.ctx, "TryDifference", other, func() (goset.Set, error) { return set.Set.TryDifference(other) })
+}
+
+func (set *TracedSet) TrySymmetricDifference(other goset.Set) (goset.Set, error) {
+	return set.setOp(set.ctx, "TrySymmetricDifference", other, func() (goset.Set, error) { return set.Set.TrySymmetricDifference(other) })
+}
+
+// UnionContext traces the union in the trace of ctx.
+func (set *TracedSet) UnionContext(ctx context.Context, other goset.Set) (goset.Set, error) {
+	return set.setOp(ctx, "UnionContext", other, func() (goset.Set, error) { return set.Set.UnionContext(ctx, other) })
+}
+
+// IntersectContext traces the intersection in the trace of ctx.
+func (set *TracedSet) IntersectContext(ctx context.Context, other goset.Set) (goset.Set, error) {
+	return set.setOp(ctx, "IntersectContext", other, func() (goset.Set, error) { return set.Set.IntersectContext(ctx, other) })
+}
+
+// DifferenceContext traces the difference in the trace of ctx.
+func (set *TracedSet) DifferenceContext(ctx context.Context, other goset.Set) (goset.Set, error) {
+	return set.setOp(ctx, "DifferenceContext", other, func() (goset.Set, error) { return set.Set.DifferenceContext(ctx, other) })
+}
+
+func (set *TracedSet) UnionWith(other goset.Set) {
+	set.inPlace("UnionWith", other.Size(), func() { set.Set.UnionWith(other) })
+}
+
+func (set *TracedSet) IntersectWith(other goset.Set) {
+	set.inPlace("IntersectWith", other.Size(), func() { set.Set.IntersectWith(other) })
+}
+
+func (set *TracedSet) DifferenceWith(other goset.Set) {
+	set.inPlace("DifferenceWith", other.Size(), func() { set.Set.DifferenceWith(other) })
+}
+
+func (set *TracedSet) SymmetricDifferenceWith(other goset.Set) {
+	set.inPlace("SymmetricDifferenceWith", other.Size(), func() { set.Set.SymmetricDifferenceWith(other) })
+}
+
+func (set *TracedSet) Append(vals ...interface{}) int {
+	var n int
+	set.inPlace("Append", len(vals), func() { n = set.Set.Append(vals...) })
+	return n
+}
+
+func (set *TracedSet) RemoveAll(vals ...interface{}) {
+	set.inPlace("RemoveAll", len(vals), func() { set.Set.RemoveAll(vals...) })
+}
+
+// Apply traces the application of cs, with the number of elements it
+// adds and removes as the size of the other operand.
+func (set *TracedSet) Apply(cs goset.ChangeSet) {
+	n := 0
+	for _, s := range []goset.Set{cs.Adds, cs.Removes} {
+		if s != nil {
+			n += s.Size()
+		}
+	}
+	set.inPlace("Apply", n, func() { set.Set.Apply(cs) })
+}
+
+func (set *TracedSet) Clone() goset.Set {
+	var ret goset.Set
+	set.trace(set.ctx, "Clone", -1, func() error {
+		ret = set.Set.Clone()
+		return nil
+	}, func() int { return ret.Size() })
+	return ret
+}
+
+// Diff traces the diff, with the number of elements added and removed as
+// the size of the result.
+func (set *TracedSet) Diff(other goset.Set) (added goset.Set, removed goset.Set) {
+	set.trace(set.ctx, "Diff", other.Size(), func() error {
+		added, removed = set.Set.Diff(other)
+		return nil
+	}, func() int { return added.Size() + removed.Size() })
+	return added, removed
+}
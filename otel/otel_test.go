@@ -0,0 +1,63 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package otel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/b1tkeeper/goset"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_TracedSet(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+
+	set := Wrap(goset.NewSet(1, 2, 3), WithTracerProvider(tp)).WithContext(ctx)
+	if u := set.Union(goset.NewSet(3, 4)); u.Size() != 4 {
+		t.Errorf("Expected the union of 4 elements, got %v", u)
+	}
+	set.Append(5, 6)
+	set.Add(7)
+	if _, err := set.TryUnion(goset.NewSet("a")); err == nil {
+		t.Errorf("Expected an error for the union with strings")
+	}
+	parent.End()
+
+	spans := rec.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("Expected spans for Union, Append, TryUnion and the parent, got %d", len(spans))
+	}
+	union := spans[0]
+	if union.Name() != "goset.Union" || union.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("Expected a goset.Union span under the parent, got %s", union.Name())
+	}
+	attrs := map[string]int64{}
+	for _, kv := range union.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.AsInt64()
+	}
+	if attrs["goset.size"] != 3 || attrs["goset.other.size"] != 2 || attrs["goset.result.size"] != 4 {
+		t.Errorf("Expected the sizes 3, 2 and 4, got %v", attrs)
+	}
+	if spans[1].Name() != "goset.Append" {
+		t.Errorf("Expected a goset.Append span, got %s", spans[1].Name())
+	}
+	if spans[2].Status().Code != codes.Error {
+		t.Errorf("Expected the TryUnion span to record the error")
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"fmt"
+	"math/bits"
+	"reflect"
+	"strings"
+)
+
+// PersistentSet is an immutable set whose Add and Remove return a new
+// version of the set, leaving the old one untouched. Versions share all
+// of their structure except the O(log n) nodes on the path to the changed
+// element, which makes keeping many near-identical versions of a large
+// set cheap, where full copies made by Clone would not be.
+//
+// Like FrozenSet, a PersistentSet needs no locking and can be shared
+// freely between goroutines. The zero value is the empty set.
+type PersistentSet struct {
+	root *hamtNode
+	size int
+	typ  reflect.Type
+}
+
+const (
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+)
+
+// hamtNode is a node of a hash array mapped trie. Below the last level
+// that can be indexed by the 64-bit hash, nodes are plain lists of the
+// entries whose hashes collide.
+type hamtNode struct {
+	bitmap  uint32
+	entries []hamtEntry
+}
+
+// hamtEntry is either an element or, if child is set, a subtree.
+type hamtEntry struct {
+	key   string // Store $hash of elem
+	hash  uint64
+	val   interface{}
+	child *hamtNode
+}
+
+// NewPersistentSet creates and returns a new persistent set with the
+// given elements.
+func NewPersistentSet(vals ...interface{}) PersistentSet {
+	var set PersistentSet
+	for _, item := range vals {
+		set = set.Add(item)
+	}
+	return set
+}
+
+// Add returns a new version of the set that also contains val, or
+// the set itself if val is in it already.
+func (set PersistentSet) Add(val interface{}) PersistentSet {
+	typ := reflect.ValueOf(val).Type()
+	if set.typ != nil && set.typ != typ {
+		panic(&TypeMismatchError{Want: set.typ, Got: typ})
+	}
+	key, err := calcHash(val)
+	if err != nil {
+		panic(err)
+	}
+	root, added := set.root.insert(hamtEntry{key: key, hash: hashKey(key), val: val}, 0)
+	if !added {
+		return set
+	}
+	return PersistentSet{root: root, size: set.size + 1, typ: typ}
+}
+
+// Remove returns a new version of the set without val, or the set
+// itself if val is not in it.
+func (set PersistentSet) Remove(val interface{}) PersistentSet {
+	key, err := calcHash(val)
+	if err != nil {
+		return set
+	}
+	root, removed := set.root.remove(key, hashKey(key), 0)
+	if !removed {
+		return set
+	}
+	if set.size == 1 {
+		return PersistentSet{}
+	}
+	return PersistentSet{root: root, size: set.size - 1, typ: set.typ}
+}
+
+// Contains returns whether the given items
+// are all in the set.
+func (set PersistentSet) Contains(val ...interface{}) bool {
+	for _, v := range val {
+		key, err := calcHash(v)
+		if err != nil || !set.root.contains(key, hashKey(key), 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// Size Returns the number of elements in the set.
+func (set PersistentSet) Size() int {
+	return set.size
+}
+
+// Each iterates over elements and executes the passed func against each element.
+// If passed func returns true, stop iteration at the time.
+func (set PersistentSet) Each(f func(elem interface{}) bool) {
+	set.root.each(f)
+}
+
+// ToSlice returns the members of the set as a slice.
+func (set PersistentSet) ToSlice() []interface{} {
+	objs := make([]interface{}, 0, set.size)
+	set.root.each(func(obj interface{}) bool {
+		objs = append(objs, obj)
+		return false
+	})
+	return objs
+}
+
+// ToSet returns a mutable, thread-safe copy of the set.
+func (set PersistentSet) ToSet() Set {
+	s := newThreadSafeSet()
+	s.unsafeSet.Grow(set.size)
+	set.root.each(func(obj interface{}) bool {
+		s.unsafeSet.Add(obj)
+		return false
+	})
+	return &s
+}
+
+// String provides a convenient string representation
+// of the set.
+func (set PersistentSet) String() string {
+	items := make([]string, 0, set.size)
+	set.root.each(func(obj interface{}) bool {
+		items = append(items, fmt.Sprintf("%v", obj))
+		return false
+	})
+	if len(items) == 0 {
+		return "goset.PersistentSet{ }"
+	}
+	return fmt.Sprintf("goset.PersistentSet{ %s }", strings.Join(items, ", "))
+}
+
+// index returns the bit of hash at shift in a node bitmap and the
+// position of its entry.
+func (n *hamtNode) index(hash uint64, shift uint) (uint32, int) {
+	bit := uint32(1) << ((hash >> shift) & hamtMask)
+	return bit, bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// insert returns a copy of n with e added, or n itself and false if
+// there is an element with the same key already. n may be nil.
+func (n *hamtNode) insert(e hamtEntry, shift uint) (*hamtNode, bool) {
+	if n == nil {
+		n = &hamtNode{}
+	}
+	if shift >= 64 {
+		for _, cur := range n.entries {
+			if cur.key == e.key {
+				return n, false
+			}
+		}
+		return &hamtNode{entries: append(n.entries[:len(n.entries):len(n.entries)], e)}, true
+	}
+
+	bit, i := n.index(e.hash, shift)
+	if n.bitmap&bit == 0 {
+		entries := make([]hamtEntry, len(n.entries)+1)
+		copy(entries, n.entries[:i])
+		entries[i] = e
+		copy(entries[i+1:], n.entries[i:])
+		return &hamtNode{bitmap: n.bitmap | bit, entries: entries}, true
+	}
+
+	cur := n.entries[i]
+	var child *hamtNode
+	switch {
+	case cur.child != nil:
+		var added bool
+		if child, added = cur.child.insert(e, shift+hamtBits); !added {
+			return n, false
+		}
+	case cur.key == e.key:
+		return n, false
+	default:
+		// Push the present element down next to the new one.
+		child, _ = (*hamtNode)(nil).insert(cur, shift+hamtBits)
+		child, _ = child.insert(e, shift+hamtBits)
+	}
+	return n.replace(i, hamtEntry{child: child}), true
+}
+
+// remove returns a copy of n without the element with the given key,
+// or n itself and false if there is no such element. It returns nil
+// instead of an empty node.
+func (n *hamtNode) remove(key string, hash uint64, shift uint) (*hamtNode, bool) {
+	if n == nil {
+		return nil, false
+	}
+	if shift >= 64 {
+		for i, cur := range n.entries {
+			if cur.key == key {
+				return n.without(0, i), true
+			}
+		}
+		return n, false
+	}
+
+	bit, i := n.index(hash, shift)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	cur := n.entries[i]
+	if cur.child == nil {
+		if cur.key != key {
+			return n, false
+		}
+		return n.without(bit, i), true
+	}
+
+	child, removed := cur.child.remove(key, hash, shift+hamtBits)
+	switch {
+	case !removed:
+		return n, false
+	case child == nil:
+		return n.without(bit, i), true
+	case len(child.entries) == 1 && child.entries[0].child == nil:
+		// Pull a lone element up in place of its subtree.
+		return n.replace(i, child.entries[0]), true
+	}
+	return n.replace(i, hamtEntry{child: child}), true
+}
+
+// replace returns a copy of n with the i-th entry set to e.
+func (n *hamtNode) replace(i int, e hamtEntry) *hamtNode {
+	entries := make([]hamtEntry, len(n.entries))
+	copy(entries, n.entries)
+	entries[i] = e
+	return &hamtNode{bitmap: n.bitmap, entries: entries}
+}
+
+// without returns a copy of n without the i-th entry and its bit, or
+// nil if that was the last entry.
+func (n *hamtNode) without(bit uint32, i int) *hamtNode {
+	if len(n.entries) == 1 {
+		return nil
+	}
+	entries := make([]hamtEntry, 0, len(n.entries)-1)
+	entries = append(entries, n.entries[:i]...)
+	entries = append(entries, n.entries[i+1:]...)
+	return &hamtNode{bitmap: n.bitmap &^ bit, entries: entries}
+}
+
+func (n *hamtNode) contains(key string, hash uint64, shift uint) bool {
+	for n != nil {
+		if shift >= 64 {
+			for _, cur := range n.entries {
+				if cur.key == key {
+					return true
+				}
+			}
+			return false
+		}
+		bit, i := n.index(hash, shift)
+		if n.bitmap&bit == 0 {
+			return false
+		}
+		cur := n.entries[i]
+		if cur.child == nil {
+			return cur.key == key
+		}
+		n, shift = cur.child, shift+hamtBits
+	}
+	return false
+}
+
+// each calls f for every element until f returns true, and returns
+// whether it did.
+func (n *hamtNode) each(f func(val interface{}) bool) bool {
+	if n == nil {
+		return false
+	}
+	for _, e := range n.entries {
+		if e.child != nil {
+			if e.child.each(f) {
+				return true
+			}
+		} else if f(e.val) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func Test_PersistentSet(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	versions := []PersistentSet{{}}
+	expected := []map[int]bool{{}}
+	for i := 0; i < N; i++ {
+		set, m := versions[len(versions)-1], map[int]bool{}
+		for k := range expected[len(expected)-1] {
+			m[k] = true
+		}
+		v := r.Intn(N / 4)
+		if r.Intn(3) == 0 {
+			set = set.Remove(v)
+			delete(m, v)
+		} else {
+			set = set.Add(v)
+			m[v] = true
+		}
+		versions, expected = append(versions, set), append(expected, m)
+	}
+
+	// Every version must still hold exactly its own elements.
+	for i, set := range versions {
+		if set.Size() != len(expected[i]) || len(set.ToSlice()) != len(expected[i]) {
+			t.Fatalf("Expected version %v to have %v elements, got %v", i, len(expected[i]), set.Size())
+		}
+		for k := range expected[i] {
+			if !set.Contains(k) {
+				t.Fatalf("Expected version %v to contain %v", i, k)
+			}
+		}
+	}
+
+	set := NewPersistentSet(1, 2)
+	if set.Add(2) != set || set.Remove(3) != set {
+		t.Errorf("Expected no new version for no-op changes")
+	}
+	if !set.ToSet().Equal(NewSet(1, 2)) {
+		t.Errorf("Expected ToSet to copy the elements, got %v", set.ToSet())
+	}
+}
+
+func Test_PersistentSetCollisions(t *testing.T) {
+	var root *hamtNode
+	for _, key := range []string{"a", "b", "c"} {
+		root, _ = root.insert(hamtEntry{key: key, hash: 42, val: key}, 0)
+	}
+	if !root.contains("b", 42, 0) || root.contains("d", 42, 0) {
+		t.Fatalf("Expected colliding keys to be told apart")
+	}
+	root, _ = root.remove("a", 42, 0)
+	root, _ = root.remove("c", 42, 0)
+	if !root.contains("b", 42, 0) || root.contains("a", 42, 0) {
+		t.Errorf("Expected only b to remain")
+	}
+}
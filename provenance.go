@@ -0,0 +1,64 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "sort"
+
+// ProvenanceSet is the result of UnionWithProvenance. It is a regular
+// thread-safe Set that additionally records which of the named source
+// sets contributed each element.
+type ProvenanceSet struct {
+	Set
+	sources map[string][]string // Store {$hash: $names} of elem
+}
+
+// UnionWithProvenance returns the union of the named sets, recording for
+// every element the names of the sets it was found in.
+func UnionWithProvenance(named map[string]Set) *ProvenanceSet {
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	union := NewSet()
+	sources := map[string][]string{}
+	for _, name := range names {
+		name := name
+		named[name].Each(func(elem interface{}) bool {
+			hash, err := calcHash(elem)
+			if err != nil {
+				panic(err)
+			}
+			union.Add(elem)
+			sources[hash] = append(sources[hash], name)
+			return false
+		})
+	}
+	return &ProvenanceSet{Set: union, sources: sources}
+}
+
+// Sources returns the sorted names of the source sets that contained
+// elem when the union was computed, or nil if none did.
+func (set *ProvenanceSet) Sources(elem interface{}) []string {
+	hash, err := calcHash(elem)
+	if err != nil {
+		return nil
+	}
+	names := set.sources[hash]
+	if names == nil {
+		return nil
+	}
+	return append([]string(nil), names...)
+}
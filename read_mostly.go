@@ -0,0 +1,139 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "sync/atomic"
+
+// WithReadMostly optimizes the set for thousands of reads per change.
+// The elements are kept in a map that is never changed once it is
+// published: the first change while the set is locked copies the map,
+// and the copy is published when the set is unlocked. Contains, Size,
+// Each and ToSlice of a thread-safe set read the published map without
+// locking, so they never wait, and each sees the set as it was after some
+// change, as if the set had been read-locked.
+//
+// Every locked section that changes the set copies all of its elements,
+// so batch changes, e.g. with Append or UnionWith, rather than adding
+// elements one by one.
+func WithReadMostly() Option {
+	return func(opts *setOptions) {
+		opts.newStore = func() store { return newReadMostlyStore(newHashStore(0)) }
+	}
+}
+
+// readMostlyStore is a store whose elements are read from a published
+// hashStore, which is replaced but never modified, and changed in a
+// draft copy of it until the draft is published.
+type readMostlyStore struct {
+	published atomic.Value // *hashStore
+	draft     *hashStore   // nil if there are no unpublished changes
+}
+
+func newReadMostlyStore(s *hashStore) *readMostlyStore {
+	ret := &readMostlyStore{}
+	ret.published.Store(s)
+	return ret
+}
+
+func (s *readMostlyStore) external() {}
+
+// view returns the published elements.
+func (s *readMostlyStore) view() store {
+	return s.published.Load().(*hashStore)
+}
+
+// publish replaces the published elements by the draft, if there is
+// one.
+func (s *readMostlyStore) publish() {
+	if s.draft != nil {
+		s.published.Store(s.draft)
+		s.draft = nil
+	}
+}
+
+// current returns the draft if there is one, or else the published
+// elements, which must not be modified.
+func (s *readMostlyStore) current() *hashStore {
+	if s.draft != nil {
+		return s.draft
+	}
+	return s.view().(*hashStore)
+}
+
+// edit returns the draft, copying the published elements into a new one
+// if necessary.
+func (s *readMostlyStore) edit() *hashStore {
+	if s.draft == nil {
+		s.draft = s.view().(*hashStore).clone()
+	}
+	return s.draft
+}
+
+func (s *readMostlyStore) add(val interface{}) (bool, error) {
+	if _, ok := s.current().get(val); ok {
+		return false, nil
+	}
+	return s.edit().add(val)
+}
+
+func (s *readMostlyStore) get(val interface{}) (interface{}, bool) {
+	return s.current().get(val)
+}
+
+func (s *readMostlyStore) remove(val interface{}) (bool, error) {
+	if _, ok := s.current().get(val); !ok {
+		_, err := s.current().direct(val)
+		return false, err
+	}
+	return s.edit().remove(val)
+}
+
+func (s *readMostlyStore) len() int {
+	return s.current().len()
+}
+
+func (s *readMostlyStore) each(f func(val interface{}) bool) {
+	s.current().each(f)
+}
+
+// clear starts a new, empty draft rather than copying the elements.
+func (s *readMostlyStore) clear() {
+	s.draft = s.current().empty(0).(*hashStore)
+}
+
+func (s *readMostlyStore) grow(n int) {
+	s.edit().grow(n)
+}
+
+func (s *readMostlyStore) empty(capacity int) store {
+	return newReadMostlyStore(s.current().empty(capacity).(*hashStore))
+}
+
+// clone returns a copy of s that shares no memory with it.
+func (s *hashStore) clone() *hashStore {
+	ret := &hashStore{nHashed: s.nHashed, capacity: s.capacity, floats: s.floats}
+	if s.vals != nil {
+		ret.vals = make(map[interface{}]struct{}, len(s.vals))
+		for obj := range s.vals {
+			ret.vals[obj] = struct{}{}
+		}
+	}
+	if s.hashed != nil {
+		ret.hashed = make(map[uint64][]hashedElem, len(s.hashed))
+		for key, bucket := range s.hashed {
+			ret.hashed[key] = append([]hashedElem(nil), bucket...)
+		}
+	}
+	return ret
+}
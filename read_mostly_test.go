@@ -0,0 +1,69 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"sync"
+	"testing"
+)
+
+func Test_ReadMostly(t *testing.T) {
+	s := NewSet(WithReadMostly(), 1, 2, 3)
+	if s.Size() != 3 || !s.Contains(1, 2, 3) {
+		t.Errorf("Expected the initial elements to be published, got %v", s)
+	}
+	s.Append(4, 5)
+	s.Remove(1)
+	if s.Contains(1) || !s.Contains(4, 5) || s.Size() != 4 {
+		t.Errorf("Expected 2 to 5, got %v", s)
+	}
+	c := s.Clone()
+	s.Clear()
+	if s.Size() != 0 || len(s.ToSlice()) != 0 || !c.Contains(2, 3, 4, 5) {
+		t.Errorf("Expected the clone to keep its elements, got %v and %v", s, c)
+	}
+
+	u := NewSet(WithReadMostly(), 1).(*ThreadSafeSet)
+	u.ToThreadUnsafe().Add(2)
+	if !u.Contains(2) {
+		t.Errorf("Expected changes through ToThreadUnsafe to be seen")
+	}
+}
+
+func Test_ReadMostlyConcurrent(t *testing.T) {
+	s := NewSet(WithReadMostly())
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < N; i += 2 {
+			s.Append(i, i+1)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < N; i++ {
+			// Pairs are added under one lock, so no snapshot
+			// holds half a pair.
+			if n := len(s.ToSlice()); n%2 != 0 {
+				t.Errorf("Expected whole pairs, got %d elements", n)
+			}
+			s.Contains(i)
+		}
+	}()
+	wg.Wait()
+	if s.Size() != N {
+		t.Errorf("Expected %d elements, got %d", N, s.Size())
+	}
+}
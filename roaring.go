@@ -0,0 +1,359 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// arrayMax is the largest number of elements a roaring container keeps
+// in a sorted array, beyond that a bitmap is smaller.
+const arrayMax = 4096
+
+// arrayMin is the number of elements below which a bitmap container
+// turns back into an array. It is well below arrayMax, so that adding
+// and removing elements around arrayMax doesn't convert the container
+// back and forth.
+const arrayMin = arrayMax / 2
+
+// roaringStore is a store of uint32 or uint64 elements in the layout of
+// a roaring bitmap: elements are grouped by their upper bits into chunks
+// of 65536, and each chunk holds the lower 16 bits of its elements in a
+// sorted array while it is sparse, or in a bitmap once it is dense. This
+// keeps sets of sparse integers small, and lets Union, Intersect,
+// Difference and SymmetricDifference between two such sets work chunk by
+// chunk.
+//
+// The containers are indexed by the upper bits of their elements in a
+// map, so that sparse uint64 elements, which get a container each, are
+// added in constant time.
+type roaringStore struct {
+	containers map[uint64]*container // Store {$upper bits: container} of the elements
+	n          int
+	wide       bool // whether elements are uint64 rather than uint32
+}
+
+// container holds the lower 16 bits of the elements of one chunk, in
+// array if bitmap is nil.
+type container struct {
+	array  []uint16
+	bitmap []uint64
+	n      int
+}
+
+func roaringValue(val interface{}) (v uint64, wide bool, err error) {
+	switch i := val.(type) {
+	case uint32:
+		return uint64(i), false, nil
+	case uint64:
+		return i, true, nil
+	}
+	return 0, false, wrongType(val, "%#v can't be stored in a roaring bitmap, elements must be uint32 or uint64", val)
+}
+
+func (s *roaringStore) add(val interface{}) (bool, error) {
+	v, wide, err := roaringValue(val)
+	if err != nil {
+		return false, err
+	}
+	if s.n == 0 {
+		s.wide = wide
+	} else if s.wide != wide {
+		return false, wrongType(val, "%#v can't be stored in a roaring bitmap of other integers", val)
+	}
+	c, ok := s.containers[v>>16]
+	if !ok {
+		if s.containers == nil {
+			s.containers = map[uint64]*container{}
+		}
+		c = &container{}
+		s.containers[v>>16] = c
+	}
+	if !c.add(uint16(v)) {
+		return false, nil
+	}
+	s.n++
+	return true, nil
+}
+
+func (s *roaringStore) get(val interface{}) (interface{}, bool) {
+	v, wide, err := roaringValue(val)
+	if err != nil || wide != s.wide {
+		return nil, false
+	}
+	if c, ok := s.containers[v>>16]; ok && c.has(uint16(v)) {
+		return val, true
+	}
+	return nil, false
+}
+
+func (s *roaringStore) remove(val interface{}) (bool, error) {
+	v, wide, err := roaringValue(val)
+	if err != nil {
+		return false, err
+	}
+	if wide != s.wide {
+		return false, nil
+	}
+	c, ok := s.containers[v>>16]
+	if !ok || !c.remove(uint16(v)) {
+		return false, nil
+	}
+	if c.n == 0 {
+		delete(s.containers, v>>16)
+	}
+	s.n--
+	return true, nil
+}
+
+func (s *roaringStore) len() int {
+	return s.n
+}
+
+func (s *roaringStore) each(f func(val interface{}) bool) {
+	for key, c := range s.containers {
+		high := key << 16
+		stop := c.each(func(low uint16) bool {
+			if s.wide {
+				return f(high | uint64(low))
+			}
+			return f(uint32(high | uint64(low)))
+		})
+		if stop {
+			return
+		}
+	}
+}
+
+func (s *roaringStore) clear() {
+	*s = roaringStore{}
+}
+
+func (s *roaringStore) grow(n int) {}
+
+func (s *roaringStore) empty(capacity int) store {
+	return &roaringStore{}
+}
+
+func (s *roaringStore) combine(op setOp, other store) (store, bool) {
+	o, ok := other.(*roaringStore)
+	if !ok || s.n > 0 && o.n > 0 && s.wide != o.wide {
+		return nil, false
+	}
+	ret := &roaringStore{containers: map[uint64]*container{}, wide: s.wide || s.n == 0 && o.wide}
+	push := func(key uint64, c *container) {
+		if c.n > 0 {
+			ret.containers[key] = c
+			ret.n += c.n
+		}
+	}
+	for key, c := range s.containers {
+		if oc, ok := o.containers[key]; ok {
+			push(key, combineContainers(op, c, oc))
+		} else if op != opIntersect {
+			// Only in this store.
+			push(key, c.clone())
+		}
+	}
+	if op == opUnion || op == opSymmetricDifference {
+		for key, oc := range o.containers {
+			if _, ok := s.containers[key]; !ok {
+				// Only in the other store.
+				push(key, oc.clone())
+			}
+		}
+	}
+	return ret, true
+}
+
+func (s *roaringStore) combineWith(op setOp, other store) bool {
+	ret, ok := s.combine(op, other)
+	if ok {
+		*s = *ret.(*roaringStore)
+	}
+	return ok
+}
+
+func (c *container) has(low uint16) bool {
+	if c.bitmap != nil {
+		return c.bitmap[low/64]&(1<<(low%64)) != 0
+	}
+	i := c.search(low)
+	return i < len(c.array) && c.array[i] == low
+}
+
+func (c *container) search(low uint16) int {
+	return sort.Search(len(c.array), func(i int) bool { return c.array[i] >= low })
+}
+
+func (c *container) add(low uint16) bool {
+	if c.bitmap != nil {
+		bit := uint64(1) << (low % 64)
+		if c.bitmap[low/64]&bit != 0 {
+			return false
+		}
+		c.bitmap[low/64] |= bit
+		c.n++
+		return true
+	}
+	i := c.search(low)
+	if i < len(c.array) && c.array[i] == low {
+		return false
+	}
+	c.array = append(c.array, 0)
+	copy(c.array[i+1:], c.array[i:])
+	c.array[i] = low
+	c.n++
+	if c.n > arrayMax {
+		c.toBitmap()
+	}
+	return true
+}
+
+func (c *container) remove(low uint16) bool {
+	if !c.has(low) {
+		return false
+	}
+	c.n--
+	if c.bitmap != nil {
+		c.bitmap[low/64] &^= 1 << (low % 64)
+		if c.n < arrayMin {
+			c.toArray()
+		}
+		return true
+	}
+	i := c.search(low)
+	c.array = append(c.array[:i], c.array[i+1:]...)
+	return true
+}
+
+func (c *container) each(f func(low uint16) bool) bool {
+	if c.bitmap == nil {
+		for _, low := range c.array {
+			if f(low) {
+				return true
+			}
+		}
+		return false
+	}
+	for w, word := range c.bitmap {
+		for word != 0 {
+			b := bits.TrailingZeros64(word)
+			if f(uint16(w*64 + b)) {
+				return true
+			}
+			word &^= 1 << uint(b)
+		}
+	}
+	return false
+}
+
+func (c *container) clone() *container {
+	ret := &container{n: c.n}
+	if c.bitmap != nil {
+		ret.bitmap = append([]uint64(nil), c.bitmap...)
+	} else {
+		ret.array = append([]uint16(nil), c.array...)
+	}
+	return ret
+}
+
+// words returns the elements of c as a bitmap, which must not be
+// modified.
+func (c *container) words() []uint64 {
+	if c.bitmap != nil {
+		return c.bitmap
+	}
+	words := make([]uint64, 1024)
+	for _, low := range c.array {
+		words[low/64] |= 1 << (low % 64)
+	}
+	return words
+}
+
+func (c *container) toBitmap() {
+	c.bitmap, c.array = c.words(), nil
+}
+
+func (c *container) toArray() {
+	array := make([]uint16, 0, c.n)
+	c.each(func(low uint16) bool {
+		array = append(array, low)
+		return false
+	})
+	c.array, c.bitmap = array, nil
+}
+
+// combineContainers returns a new container with the result of op
+// applied to a and b.
+func combineContainers(op setOp, a, b *container) *container {
+	if a.bitmap == nil && b.bitmap == nil {
+		return mergeArrays(op, a.array, b.array)
+	}
+	aw, bw := a.words(), b.words()
+	ret := &container{bitmap: make([]uint64, 1024)}
+	for w := range ret.bitmap {
+		switch op {
+		case opUnion:
+			ret.bitmap[w] = aw[w] | bw[w]
+		case opIntersect:
+			ret.bitmap[w] = aw[w] & bw[w]
+		case opDifference:
+			ret.bitmap[w] = aw[w] &^ bw[w]
+		case opSymmetricDifference:
+			ret.bitmap[w] = aw[w] ^ bw[w]
+		}
+		ret.n += bits.OnesCount64(ret.bitmap[w])
+	}
+	if ret.n <= arrayMax {
+		ret.toArray()
+	}
+	return ret
+}
+
+// mergeArrays returns a new container with the result of op applied to
+// the sorted arrays a and b.
+func mergeArrays(op setOp, a, b []uint16) *container {
+	var array []uint16
+	keepA := op != opIntersect
+	keepB := op == opUnion || op == opSymmetricDifference
+	keepBoth := op == opUnion || op == opIntersect
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || i < len(a) && a[i] < b[j]:
+			if keepA {
+				array = append(array, a[i])
+			}
+			i++
+		case i == len(a) || b[j] < a[i]:
+			if keepB {
+				array = append(array, b[j])
+			}
+			j++
+		default:
+			if keepBoth {
+				array = append(array, a[i])
+			}
+			i++
+			j++
+		}
+	}
+	ret := &container{array: array, n: len(array)}
+	if ret.n > arrayMax {
+		ret.toBitmap()
+	}
+	return ret
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func Test_RoaringBitmap(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	a, b := NewSet(WithRoaringBitmap()), NewSet(WithRoaringBitmap())
+	ha, hb := NewSet(), NewSet()
+	for i := 0; i < 20000; i++ {
+		// Dense runs in a few chunks become bitmaps, the rest of the
+		// elements are spread out sparsely.
+		v := uint64(r.Intn(2))<<16 | uint64(r.Intn(1<<16))
+		if i%2 == 0 {
+			v = r.Uint64()
+		}
+		a.Add(v)
+		ha.Add(v)
+		if i%3 == 0 {
+			b.Add(v)
+			hb.Add(v)
+		} else {
+			w := uint64(r.Intn(4))<<16 | uint64(r.Intn(1<<16))
+			b.Add(w)
+			hb.Add(w)
+		}
+	}
+
+	check := func(name string, got, expected Set) {
+		if !got.Equal(expected) || !expected.Equal(got) {
+			t.Errorf("Expected %v to match the hash set result", name)
+		}
+	}
+	check("Elements", a, ha)
+	check("Union", a.Union(b), ha.Union(hb))
+	check("Intersect", a.Intersect(b), ha.Intersect(hb))
+	check("Difference", a.Difference(b), ha.Difference(hb))
+	check("SymmetricDifference", a.SymmetricDifference(b), ha.SymmetricDifference(hb))
+
+	for _, elem := range ha.ToSlice() {
+		if elem.(uint64)%2 == 0 {
+			a.Remove(elem)
+			ha.Remove(elem)
+		}
+	}
+	check("Remove", a, ha)
+
+	small := NewThreadUnsafeSet(WithRoaringBitmap(), uint32(7), uint32(1)<<20)
+	if !small.Contains(uint32(7)) || small.Contains(uint64(7)) || small.Contains(7) {
+		t.Errorf("Expected only uint32 elements to be found, got %v", small)
+	}
+}
+
+func Test_RoaringContainerHysteresis(t *testing.T) {
+	c := &container{}
+	for low := 0; low <= arrayMax; low++ {
+		c.add(uint16(low))
+	}
+	if c.bitmap == nil {
+		t.Fatalf("Expected a bitmap beyond %v elements", arrayMax)
+	}
+	// Going back and forth around arrayMax keeps the bitmap.
+	for i := 0; i < 10; i++ {
+		c.remove(0)
+		c.add(0)
+		if c.bitmap == nil {
+			t.Fatalf("Expected the container to stay a bitmap around %v elements", arrayMax)
+		}
+	}
+	for low := 0; c.n >= arrayMin; low++ {
+		c.remove(uint16(low))
+	}
+	if c.bitmap != nil || len(c.array) != c.n {
+		t.Errorf("Expected an array below %v elements", arrayMin)
+	}
+	for low := arrayMax; low > arrayMax-c.n; low-- {
+		if !c.has(uint16(low)) {
+			t.Fatalf("Expected %v to be kept", low)
+		}
+	}
+}
@@ -13,17 +13,125 @@
 // limitations under the License.
 package goset
 
-import "sync"
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
 
 type ThreadSafeSet struct {
 	sync.RWMutex
 	unsafeSet ThreadUnsafeSet
+	counters  atomic.Value    // *setCounters timing the locks, see InstrumentedSet
+	reader    concurrentStore // Read without locking if set, see concurrentStore
 }
 
 func newThreadSafeSet() ThreadSafeSet {
 	return ThreadSafeSet{unsafeSet: newThreadUnsafeSet()}
 }
 
+// lockWith write-locks set and read-locks o, unless o is set itself,
+// and returns a func releasing both locks. The locks are taken in the
+// order of the addresses of the sets, see rlockWith.
+func (set *ThreadSafeSet) lockWith(o *ThreadSafeSet) func() {
+	if o == set {
+		set.Lock()
+		return set.Unlock
+	}
+	if lockedFirst(set, o) {
+		set.Lock()
+		o.RLock()
+	} else {
+		o.RLock()
+		set.Lock()
+	}
+	return func() {
+		o.RUnlock()
+		set.Unlock()
+	}
+}
+
+// rlockWith read-locks set and o, unless o is set itself, and returns a
+// func releasing both locks.
+//
+// The locks are taken in the order of the addresses of the sets, so that
+// a.Difference(b) and b.Difference(a) running at once can't deadlock: a
+// writer waiting for the lock of a set blocks new readers of it, so two
+// goroutines each holding the read lock of one set could otherwise wait
+// for each other behind writers.
+func (set *ThreadSafeSet) rlockWith(o *ThreadSafeSet) func() {
+	if o == set {
+		set.RLock()
+		return set.RUnlock
+	}
+	if lockedFirst(set, o) {
+		set.RLock()
+		o.RLock()
+	} else {
+		o.RLock()
+		set.RLock()
+	}
+	return func() {
+		o.RUnlock()
+		set.RUnlock()
+	}
+}
+
+// lockedFirst reports whether the lock of a is taken before that of b
+// when both are locked.
+func lockedFirst(a, b *ThreadSafeSet) bool {
+	return uintptr(unsafe.Pointer(a)) < uintptr(unsafe.Pointer(b))
+}
+
+// threadSafeOf returns other as a *ThreadSafeSet, unwrapping a
+// *SortedSet. A set of any other type is copied, through the Set
+// interface, into a set that identifies its elements like set does. It
+// panics if set can't hold the elements of other.
+func (set *ThreadSafeSet) threadSafeOf(other Set) *ThreadSafeSet {
+	o, err := set.tryThreadSafeOf(other)
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+// tryThreadSafeOf is like threadSafeOf, but returns an error instead
+// of panicking.
+func (set *ThreadSafeSet) tryThreadSafeOf(other Set) (*ThreadSafeSet, error) {
+	switch o := other.(type) {
+	case *ThreadSafeSet:
+		return o, nil
+	case *SortedSet:
+		return o.ThreadSafeSet, nil
+	case nil:
+		return nil, &IncompatibleSetError{Set: set, Other: other}
+	}
+	set.RLock()
+	like := set.unsafeSet.emptyLike(0)
+	set.RUnlock()
+	o, err := like.tryUnsafeOf(other)
+	if err != nil {
+		return nil, err
+	}
+	return &ThreadSafeSet{unsafeSet: *o}, nil
+}
+
+// ToThreadUnsafe returns the thread-unsafe set backing set, without
+// copying. Operations on the returned set skip locking entirely and are
+// visible through set, which is useful for phases where a single
+// goroutine has exclusive access to the set.
+//
+// The caller must ensure that set is not used concurrently for as long
+// as the returned set is in use. Changes through the returned set are
+// not published to the lock-free readers of WithSyncMapBackend and
+// WithReadMostly, so set takes its lock for all reads from then on.
+func (set *ThreadSafeSet) ToThreadUnsafe() *ThreadUnsafeSet {
+	set.reader = nil
+	return &set.unsafeSet
+}
+
 // Add adds an element to the set. Returns whether
 // the item was added.
 func (set *ThreadSafeSet) Add(val interface{}) bool {
@@ -33,124 +141,350 @@ func (set *ThreadSafeSet) Add(val interface{}) bool {
 	return ret
 }
 
+// Append adds all given elements to the set under a single
+// lock acquisition. Returns the number of items that were
+// added.
+func (set *ThreadSafeSet) Append(vals ...interface{}) int {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.Append(vals...)
+}
+
+// AddIf adds an element to the set only if pred, called with
+// the current contents of the set, returns true. The check and
+// the insertion happen atomically. Returns whether the item
+// was added.
+//
+// pred runs with the set locked, it must not modify or retain
+// current.
+func (set *ThreadSafeSet) AddIf(val interface{}, pred func(current Set) bool) bool {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.AddIf(val, pred)
+}
+
+// CompareAndAdd adds an element to the set only if none of
+// the expectedAbsent elements is in the set. The check and
+// the insertion happen atomically. Returns whether the item
+// was added.
+func (set *ThreadSafeSet) CompareAndAdd(val interface{}, expectedAbsent ...interface{}) bool {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.CompareAndAdd(val, expectedAbsent...)
+}
+
+// Replace removes old from the set and adds new in its place,
+// atomically, and returns whether old was in the set. new may
+// hash differently than old.
+func (set *ThreadSafeSet) Replace(old, new interface{}) bool {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.Replace(old, new)
+}
+
+// UpdateElem replaces the element of the set equal to val, if
+// any, by the result of f called with it, see Replace.
+//
+// f runs with the set locked, it must not access the set.
+func (set *ThreadSafeSet) UpdateElem(val interface{}, f func(cur interface{}) interface{}) {
+	set.Lock()
+	defer set.Unlock()
+	set.unsafeSet.UpdateElem(val, f)
+}
+
+// Update calls f with a view of the set and applies the changes
+// made through it at once when f returns, see SetTx. Nothing is
+// applied if f panics.
+//
+// f runs with the set locked, it must not access the set. Readers
+// of a set with WithSyncMapBackend may see part of the changes
+// while they are applied.
+func (set *ThreadSafeSet) Update(f func(tx SetTx)) {
+	set.Lock()
+	defer set.Unlock()
+	set.unsafeSet.Update(f)
+}
+
+// Any reports whether pred returns true for at least one
+// element of the set. It stops at the first such element.
+func (set *ThreadSafeSet) Any(pred func(elem interface{}) bool) bool {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.Any(pred)
+}
+
+// All reports whether pred returns true for every element
+// of the set. It stops at the first element for which pred
+// returns false.
+func (set *ThreadSafeSet) All(pred func(elem interface{}) bool) bool {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.All(pred)
+}
+
+// None reports whether pred returns false for every element
+// of the set. It stops at the first element for which pred
+// returns true.
+func (set *ThreadSafeSet) None(pred func(elem interface{}) bool) bool {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.None(pred)
+}
+
 // Cardinality Returns the number of elements in the set.
 func (set *ThreadSafeSet) Cardinality() int {
+	if set.reader != nil {
+		return set.reader.view().len()
+	}
 	set.RLock()
 	defer set.RUnlock()
-	return len(set.unsafeSet.dat)
+	return set.unsafeSet.Cardinality()
 }
 
 // Size Returns the number of elements in the set.
 func (set *ThreadSafeSet) Size() int {
-	set.RLock()
-	defer set.RUnlock()
-	return set.unsafeSet.Size()
+	return set.Cardinality()
 }
 
 // Clear removes all elements from the set, leaving
 // the empty set.
 func (set *ThreadSafeSet) Clear() {
 	set.Lock()
-	set.unsafeSet = newThreadUnsafeSet()
+	set.clear()
+	set.Unlock()
+}
+
+// clear removes all elements from the set, the caller must hold the
+// write lock.
+func (set *ThreadSafeSet) clear() {
+	if _, ok := set.unsafeSet.store.(*cowStore); ok {
+		// The store may be shared with clones, leave it to them.
+		set.unsafeSet = set.unsafeSet.emptyLike(0)
+	} else {
+		set.unsafeSet.Clear()
+	}
+}
+
+// Grow makes room for at least n more elements, so that
+// they can be added without growing the set repeatedly.
+func (set *ThreadSafeSet) Grow(n int) {
+	set.Lock()
+	set.unsafeSet.Grow(n)
 	set.Unlock()
 }
 
-// Clone returns a deep-clone of the set using the same
-// implementation, duplicating all keys.
+// Clone returns a clone of the set using the same
+// implementation.
+//
+// Clone is O(1): the clone shares the elements of the set
+// until either of them is modified, and the first change
+// to either set copies the elements.
 func (set *ThreadSafeSet) Clone() Set {
-	set.RLock()
-	unsafeClone := set.unsafeSet.Clone().(*ThreadUnsafeSet)
-	ret := &ThreadSafeSet{unsafeSet: *unsafeClone}
-	set.RUnlock()
-	return ret
+	set.Lock()
+	defer set.Unlock()
+	clone := set.cowClone()
+	return clone.ToThreadSafe()
+}
+
+// Combinations returns an Iterator over all subsets of this
+// set with k elements, which are built lazily as they are
+// received.
+func (set *ThreadSafeSet) Combinations(k int) *Iterator {
+	return combinationsIterator(set.ToSlice(), k, set.newSubset)
 }
 
 // Contains returns whether the given items
 // are all in the set.
 func (set *ThreadSafeSet) Contains(val ...interface{}) bool {
+	if set.reader != nil {
+		view := set.reader.view()
+		for _, v := range val {
+			if _, ok := view.get(v); !ok {
+				return false
+			}
+		}
+		return true
+	}
 	set.RLock()
 	ret := set.unsafeSet.Contains(val...)
 	set.RUnlock()
 	return ret
 }
 
+// Get returns the element of the set equal to val, which
+// may differ from val in the data its Hash and Equal ignore, and
+// whether there is one.
+func (set *ThreadSafeSet) Get(val interface{}) (interface{}, bool) {
+	if set.reader != nil {
+		return set.reader.view().get(val)
+	}
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.Get(val)
+}
+
 // Difference returns the difference between this set
 // and other. The returned set will contain
 // all elements of this set that are not also
 // elements of other.
-//
-// Note that the argument to Difference
-// must be of the same type as the receiver
-// of the method. Otherwise, Difference will
-// panic.
 func (set *ThreadSafeSet) Difference(other Set) Set {
-	o := other.(*ThreadSafeSet)
+	o := set.threadSafeOf(other)
 
-	set.RLock()
-	o.RLock()
+	unlock := set.rlockWith(o)
 
 	unsafeDifference := set.unsafeSet.Difference(&o.unsafeSet).(*ThreadUnsafeSet)
 	ret := &ThreadSafeSet{unsafeSet: *unsafeDifference}
-	set.RUnlock()
-	o.RUnlock()
+	unlock()
 	return ret
 }
 
+// DifferenceContext is like Difference, but aborts with an
+// *OperationError once ctx is done.
+func (set *ThreadSafeSet) DifferenceContext(ctx context.Context, other Set) (Set, error) {
+	o := set.threadSafeOf(other)
+
+	defer set.rlockWith(o)()
+
+	unsafeDifference, err := set.unsafeSet.DifferenceContext(ctx, &o.unsafeSet)
+	if err != nil {
+		return nil, err
+	}
+	return &ThreadSafeSet{unsafeSet: *unsafeDifference.(*ThreadUnsafeSet)}, nil
+}
+
+// DifferenceCardinality returns the number of elements of
+// the difference of this set and other, without building
+// that set.
+func (set *ThreadSafeSet) DifferenceCardinality(other Set) int {
+	o := set.threadSafeOf(other)
+
+	unlock := set.rlockWith(o)
+	ret := set.unsafeSet.DifferenceCardinality(&o.unsafeSet)
+	unlock()
+	return ret
+}
+
+// DifferenceWith removes all elements of other from this set.
+func (set *ThreadSafeSet) DifferenceWith(other Set) {
+	o := set.threadSafeOf(other)
+	defer set.lockWith(o)()
+	set.unsafeSet.DifferenceWith(&o.unsafeSet)
+}
+
+// DifferenceWithContext is like DifferenceWith, but aborts with an
+// *OperationError once ctx is done, leaving the set untouched.
+func (set *ThreadSafeSet) DifferenceWithContext(ctx context.Context, other Set) error {
+	o := set.threadSafeOf(other)
+	defer set.lockWith(o)()
+	return set.unsafeSet.DifferenceWithContext(ctx, &o.unsafeSet)
+}
+
+// Filter returns a new set with the elements of this set
+// for which pred returns true.
+func (set *ThreadSafeSet) Filter(pred func(elem interface{}) bool) Set {
+	set.RLock()
+	unsafeFiltered := set.unsafeSet.Filter(pred).(*ThreadUnsafeSet)
+	set.RUnlock()
+	return &ThreadSafeSet{unsafeSet: *unsafeFiltered}
+}
+
+// GroupBy partitions the set by the key keyFn returns for each
+// element, returning a new set for every key.
+func (set *ThreadSafeSet) GroupBy(keyFn func(elem interface{}) string) map[string]Set {
+	set.RLock()
+	groups := set.unsafeSet.GroupBy(keyFn)
+	set.RUnlock()
+	for key, group := range groups {
+		groups[key] = group.(*ThreadUnsafeSet).ToThreadSafe()
+	}
+	return groups
+}
+
 // Equal determines if two sets are equal to each
 // other. If they have the same cardinality
 // and contain the same elements, they are
 // considered equal. The order in which
 // the elements were added is irrelevant.
-//
-// Note that the argument to Equal must be
-// of the same type as the receiver of the
-// method. Otherwise, Equal will panic.
 func (set *ThreadSafeSet) Equal(other Set) bool {
-	o := other.(*ThreadSafeSet)
+	o := set.threadSafeOf(other)
 
-	set.RLock()
-	o.RLock()
+	unlock := set.rlockWith(o)
 
 	ret := set.unsafeSet.Equal(&o.unsafeSet)
-	set.RUnlock()
-	o.RUnlock()
+	unlock()
 	return ret
 }
 
 // Intersect returns a new set containing only the elements
 // that exist only in both sets.
-//
-// Note that the argument to Intersect
-// must be of the same type as the receiver
-// of the method. Otherwise, Intersect will
-// panic.
 func (set *ThreadSafeSet) Intersect(other Set) Set {
-	o := other.(*ThreadSafeSet)
+	o := set.threadSafeOf(other)
 
-	set.RLock()
-	o.RLock()
+	unlock := set.rlockWith(o)
 
 	unsafeIntersection := set.unsafeSet.Intersect(&o.unsafeSet).(*ThreadUnsafeSet)
 	ret := &ThreadSafeSet{unsafeSet: *unsafeIntersection}
-	set.RUnlock()
-	o.RUnlock()
+	unlock()
+	return ret
+}
+
+// IntersectContext is like Intersect, but aborts with an
+// *OperationError once ctx is done.
+func (set *ThreadSafeSet) IntersectContext(ctx context.Context, other Set) (Set, error) {
+	o := set.threadSafeOf(other)
+
+	defer set.rlockWith(o)()
+
+	unsafeIntersection, err := set.unsafeSet.IntersectContext(ctx, &o.unsafeSet)
+	if err != nil {
+		return nil, err
+	}
+	return &ThreadSafeSet{unsafeSet: *unsafeIntersection.(*ThreadUnsafeSet)}, nil
+}
+
+// IntersectCardinality returns the number of elements of
+// the intersection of this set and other, without building
+// that set.
+func (set *ThreadSafeSet) IntersectCardinality(other Set) int {
+	o := set.threadSafeOf(other)
+
+	unlock := set.rlockWith(o)
+	ret := set.unsafeSet.IntersectCardinality(&o.unsafeSet)
+	unlock()
 	return ret
 }
 
+// IntersectWith removes all elements that are not in other
+// from this set.
+func (set *ThreadSafeSet) IntersectWith(other Set) {
+	o := set.threadSafeOf(other)
+	defer set.lockWith(o)()
+	set.unsafeSet.IntersectWith(&o.unsafeSet)
+}
+
+// IsDisjoint determines if this set and the other set have
+// no elements in common.
+func (set *ThreadSafeSet) IsDisjoint(other Set) bool {
+	o := set.threadSafeOf(other)
+
+	unlock := set.rlockWith(o)
+	ret := set.unsafeSet.IsDisjoint(&o.unsafeSet)
+	unlock()
+	return ret
+}
+
+// Overlaps determines if this set and the other set have
+// at least one element in common.
+func (set *ThreadSafeSet) Overlaps(other Set) bool {
+	return !set.IsDisjoint(other)
+}
+
 // IsProperSubset determines if every element in this set is in
 // the other set but the two sets are not equal.
-//
-// Note that the argument to IsProperSubset
-// must be of the same type as the receiver
-// of the method. Otherwise, IsProperSubset
-// will panic.
 func (set *ThreadSafeSet) IsProperSubset(other Set) bool {
-	o := other.(*ThreadSafeSet)
+	o := set.threadSafeOf(other)
 
-	set.RLock()
-	defer set.RUnlock()
-	o.RLock()
-	defer o.RUnlock()
+	defer set.rlockWith(o)()
 
 	return set.unsafeSet.IsProperSubset(&o.unsafeSet)
 }
@@ -158,104 +492,206 @@ func (set *ThreadSafeSet) IsProperSubset(other Set) bool {
 // IsProperSuperset determines if every element in the other set
 // is in this set but the two sets are not
 // equal.
-//
-// Note that the argument to IsSuperset
-// must be of the same type as the receiver
-// of the method. Otherwise, IsSuperset will
-// panic.
 func (set *ThreadSafeSet) IsProperSuperset(other Set) bool {
 	return other.IsProperSubset(set)
 }
 
 // IsSubset determines if every element in this set is in
 // the other set.
-//
-// Note that the argument to IsSubset
-// must be of the same type as the receiver
-// of the method. Otherwise, IsSubset will
-// panic.
 func (set *ThreadSafeSet) IsSubset(other Set) bool {
-	o := other.(*ThreadSafeSet)
+	o := set.threadSafeOf(other)
 
-	set.RLock()
-	o.RLock()
+	unlock := set.rlockWith(o)
 	ret := set.unsafeSet.IsSubset(&o.unsafeSet)
-	set.RUnlock()
-	o.RUnlock()
+	unlock()
 	return ret
 }
 
 // IsSuperset determines if every element in the other set
 // is in this set.
-//
-// Note that the argument to IsSuperset
-// must be of the same type as the receiver
-// of the method. Otherwise, IsSuperset will
-// panic.
 func (set *ThreadSafeSet) IsSuperset(other Set) bool {
 	return other.IsSubset(set)
 }
 
+// IsSubsetWithin determines if at most k elements of this set
+// are missing from the other set.
+func (set *ThreadSafeSet) IsSubsetWithin(other Set, k int) bool {
+	o := set.threadSafeOf(other)
+
+	unlock := set.rlockWith(o)
+	ret := set.unsafeSet.IsSubsetWithin(&o.unsafeSet, k)
+	unlock()
+	return ret
+}
+
+// IsSupersetWithin determines if at most k elements of the
+// other set are missing from this set.
+func (set *ThreadSafeSet) IsSupersetWithin(other Set, k int) bool {
+	return other.IsSubsetWithin(set, k)
+}
+
 // Each iterates over elements and executes the passed func against each element.
 // If passed func returns true, stop iteration at the time.
 func (set *ThreadSafeSet) Each(cb func(elem interface{}) bool) {
-	set.RLock()
-	for _, obj := range set.unsafeSet.dat {
-		if cb(obj) {
-			break
-		}
+	if set.reader != nil {
+		set.reader.view().each(cb)
+		return
 	}
+	set.RLock()
+	set.unsafeSet.Each(cb)
 	set.RUnlock()
 }
 
+// EachErr iterates over elements and executes the passed func against each element.
+// If passed func returns an error, stop iteration and return that error.
+func (set *ThreadSafeSet) EachErr(cb func(elem interface{}) error) error {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.EachErr(cb)
+}
+
 // Iter returns a channel of elements that you can
 // range over.
+//
+// The elements are snapshotted before Iter returns, so the set is
+// not locked while the channel is consumed.
 func (set *ThreadSafeSet) Iter() <-chan interface{} {
-	ch := make(chan interface{})
-	go func() {
-		set.RLock()
-
-		for _, obj := range set.unsafeSet.dat {
-			ch <- obj
-		}
-		close(ch)
-		set.RUnlock()
-	}()
+	return set.IterBuffered(0)
+}
 
-	return ch
+// IterBuffered is like Iter, but the returned channel is buffered
+// for n elements, letting the producer run ahead of a slow consumer.
+func (set *ThreadSafeSet) IterBuffered(n int) <-chan interface{} {
+	return iterSlice(set.ToSlice(), n)
 }
 
 // Iterator returns an Iterator object that you can
 // use to range over the set.
+//
+// The elements are snapshotted before Iterator returns, so the set
+// is not locked while the iterator is consumed.
 func (set *ThreadSafeSet) Iterator() *Iterator {
-	iterator, ch, stopCh := newIterator()
+	return sliceIterator(set.ToSlice())
+}
 
-	go func() {
-		set.RLock()
-	L:
-		for _, obj := range set.unsafeSet.dat {
-			select {
-			case <-stopCh:
-				break L
-			case ch <- obj:
-			}
-		}
-		close(ch)
-		set.RUnlock()
-	}()
+// Cursor returns a pull-style Cursor over a snapshot of the set.
+// The set is not locked while the Cursor is advanced.
+func (set *ThreadSafeSet) Cursor() *Cursor {
+	return &Cursor{objs: set.ToSlice()}
+}
+
+// MinBy returns the smallest element of the set according
+// to less, or false if the set is empty.
+func (set *ThreadSafeSet) MinBy(less func(a, b interface{}) bool) (interface{}, bool) {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.MinBy(less)
+}
+
+// MaxBy returns the largest element of the set according
+// to less, or false if the set is empty.
+func (set *ThreadSafeSet) MaxBy(less func(a, b interface{}) bool) (interface{}, bool) {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.MaxBy(less)
+}
+
+// Min returns the smallest element of the set, or false if
+// the set is empty. Strings are compared byte by byte.
+func (set *ThreadSafeSet) Min() (interface{}, bool) {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.Min()
+}
+
+// Max returns the largest element of the set, or false if
+// the set is empty. Strings are compared byte by byte.
+func (set *ThreadSafeSet) Max() (interface{}, bool) {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.Max()
+}
+
+// Freeze returns an immutable copy of the set.
+func (set *ThreadSafeSet) Freeze() FrozenSet {
+	set.Lock()
+	defer set.Unlock()
+	return freeze(set.cowClone())
+}
+
+// Hash returns a hash of the elements of the set, which makes
+// sets Hashable, so that they can be elements of other sets.
+func (set *ThreadSafeSet) Hash() string {
+	return setHash(set.ToSlice())
+}
+
+// PowerSet returns a new set with all subsets of this set.
+// It panics if the set has more than PowerSetLimit elements.
+func (set *ThreadSafeSet) PowerSet() Set {
+	ret := newThreadSafeSet()
+	powerSet(&ret, set.ToSlice(), set.newSubset)
+	return &ret
+}
+
+// PowerSetIterator returns an Iterator over all subsets of
+// this set, which are built lazily as they are received.
+func (set *ThreadSafeSet) PowerSetIterator() *Iterator {
+	return powerSetIterator(set.ToSlice(), set.newSubset)
+}
+
+// newSubset returns a new, empty set of the same kind for a subset.
+func (set *ThreadSafeSet) newSubset() Set {
+	return &ThreadSafeSet{unsafeSet: set.unsafeSet.emptyLike(0)}
+}
 
-	return iterator
+// RandomSample returns n distinct elements of the set, selected
+// uniformly at random by r, or all elements in random order if
+// the set has no more than n elements.
+func (set *ThreadSafeSet) RandomSample(n int, r *rand.Rand) []interface{} {
+	return sample(set.ToSlice(), n, r)
+}
+
+// RandomElement returns an element of the set selected uniformly
+// at random by r, or false if the set is empty.
+func (set *ThreadSafeSet) RandomElement(r *rand.Rand) (interface{}, bool) {
+	return randomElement(set.ToSlice(), r)
 }
 
 // Remove remove a single element from the set.
 func (set *ThreadSafeSet) Remove(i interface{}) {
-	hash, err := calcHash(i)
-	if err != nil {
-		panic(err)
-	}
 	set.Lock()
-	delete(set.unsafeSet.dat, hash)
-	set.Unlock()
+	defer set.Unlock()
+	set.unsafeSet.Remove(i)
+}
+
+// RemoveAll removes all given elements from the set under a
+// single lock acquisition.
+func (set *ThreadSafeSet) RemoveAll(vals ...interface{}) {
+	set.Lock()
+	defer set.Unlock()
+	set.unsafeSet.RemoveAll(vals...)
+}
+
+// RemoveIf removes all elements for which pred returns true
+// and returns the number of elements removed.
+//
+// The set is locked while pred runs, so pred must not
+// access the set.
+func (set *ThreadSafeSet) RemoveIf(pred func(elem interface{}) bool) int {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.RemoveIf(pred)
+}
+
+// RetainIf removes all elements for which pred returns false
+// and returns the number of elements removed.
+//
+// The set is locked while pred runs, so pred must not
+// access the set.
+func (set *ThreadSafeSet) RetainIf(pred func(elem interface{}) bool) int {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.RetainIf(pred)
 }
 
 // String provides a convenient string representation
@@ -269,40 +705,76 @@ func (set *ThreadSafeSet) String() string {
 
 // SymmetricDifference returns a new set with all elements which are
 // in either this set or the other set but not in both.
-//
-// Note that the argument to SymmetricDifference
-// must be of the same type as the receiver
-// of the method. Otherwise, SymmetricDifference
-// will panic.
 func (set *ThreadSafeSet) SymmetricDifference(other Set) Set {
-	o := other.(*ThreadSafeSet)
+	o := set.threadSafeOf(other)
 
-	set.RLock()
-	o.RLock()
+	unlock := set.rlockWith(o)
 
-	unsafeDifference := set.unsafeSet.Difference(&o.unsafeSet).(*ThreadUnsafeSet)
+	unsafeDifference := set.unsafeSet.SymmetricDifference(&o.unsafeSet).(*ThreadUnsafeSet)
 	ret := &ThreadSafeSet{unsafeSet: *unsafeDifference}
-	set.RUnlock()
-	o.RUnlock()
+	unlock()
 	return ret
 }
 
+// SymmetricDifferenceWith removes the elements of other that
+// are in this set and adds those that are not.
+func (set *ThreadSafeSet) SymmetricDifferenceWith(other Set) {
+	o := set.threadSafeOf(other)
+	defer set.lockWith(o)()
+	set.unsafeSet.SymmetricDifferenceWith(&o.unsafeSet)
+}
+
 // Union returns a new set with all elements in both sets.
-//
-// Note that the argument to Union must be of the
-// same type as the receiver of the method.
-// Otherwise, IsSuperset will panic.
 func (set *ThreadSafeSet) Union(other Set) Set {
-	o := other.(*ThreadSafeSet)
-	set.RLock()
-	o.RLock()
+	o := set.threadSafeOf(other)
+	unlock := set.rlockWith(o)
 	unsafeUnion := set.unsafeSet.Union(&o.unsafeSet).(*ThreadUnsafeSet)
 	ret := &ThreadSafeSet{unsafeSet: *unsafeUnion}
-	set.RUnlock()
-	o.RUnlock()
+	unlock()
+	return ret
+}
+
+// UnionContext is like Union, but aborts with an
+// *OperationError once ctx is done.
+func (set *ThreadSafeSet) UnionContext(ctx context.Context, other Set) (Set, error) {
+	o := set.threadSafeOf(other)
+
+	defer set.rlockWith(o)()
+
+	unsafeUnion, err := set.unsafeSet.UnionContext(ctx, &o.unsafeSet)
+	if err != nil {
+		return nil, err
+	}
+	return &ThreadSafeSet{unsafeSet: *unsafeUnion.(*ThreadUnsafeSet)}, nil
+}
+
+// UnionCardinality returns the number of elements of the
+// union of this set and other, without building that set.
+func (set *ThreadSafeSet) UnionCardinality(other Set) int {
+	o := set.threadSafeOf(other)
+
+	unlock := set.rlockWith(o)
+	ret := set.unsafeSet.UnionCardinality(&o.unsafeSet)
+	unlock()
 	return ret
 }
 
+// UnionWith adds all elements of other to this set.
+func (set *ThreadSafeSet) UnionWith(other Set) {
+	o := set.threadSafeOf(other)
+	defer set.lockWith(o)()
+	set.unsafeSet.UnionWith(&o.unsafeSet)
+}
+
+// UnionWithContext is like UnionWith, but aborts with an
+// *OperationError once ctx is done, keeping the elements
+// added so far.
+func (set *ThreadSafeSet) UnionWithContext(ctx context.Context, other Set) error {
+	o := set.threadSafeOf(other)
+	defer set.lockWith(o)()
+	return set.unsafeSet.UnionWithContext(ctx, &o.unsafeSet)
+}
+
 // Pop removes and returns an arbitrary item from the set.
 func (set *ThreadSafeSet) Pop() (interface{}, bool) {
 	set.Lock()
@@ -310,15 +782,116 @@ func (set *ThreadSafeSet) Pop() (interface{}, bool) {
 	return set.unsafeSet.Pop()
 }
 
+// PopN removes and returns up to n arbitrary items from the
+// set, holding the lock only once.
+func (set *ThreadSafeSet) PopN(n int) []interface{} {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.PopN(n)
+}
+
+// PopIf removes and returns the first item found that
+// satisfies pred, or false if there is no such item.
+//
+// The set is locked while pred runs, so pred must not
+// access the set.
+func (set *ThreadSafeSet) PopIf(pred func(elem interface{}) bool) (interface{}, bool) {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.PopIf(pred)
+}
+
+// TryAdd is like Add, but returns an error instead of
+// panicking if val can't be hashed or is of a different
+// type than the elements of the set.
+func (set *ThreadSafeSet) TryAdd(val interface{}) (bool, error) {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.TryAdd(val)
+}
+
+// TryRemove is like Remove, but returns an error instead of
+// panicking if val can't be hashed.
+func (set *ThreadSafeSet) TryRemove(val interface{}) error {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.TryRemove(val)
+}
+
+// try runs op, one of the Try operations of ThreadUnsafeSet, on the
+// sets backing set and other, or returns an error if other is not a
+// *ThreadSafeSet.
+func (set *ThreadSafeSet) try(other Set, op func(set *ThreadUnsafeSet, other Set) (Set, error)) (Set, error) {
+	o, err := set.tryThreadSafeOf(other)
+	if err != nil {
+		return nil, err
+	}
+
+	defer set.rlockWith(o)()
+
+	ret, err := op(&set.unsafeSet, &o.unsafeSet)
+	if err != nil {
+		return nil, err
+	}
+	return &ThreadSafeSet{unsafeSet: *ret.(*ThreadUnsafeSet)}, nil
+}
+
+// TryUnion is like Union, but returns an error instead of
+// panicking if the set can't hold the elements of other.
+func (set *ThreadSafeSet) TryUnion(other Set) (Set, error) {
+	return set.try(other, (*ThreadUnsafeSet).TryUnion)
+}
+
+// TryIntersect is like Intersect, but returns an error
+// instead of panicking if the set can't hold the elements
+// of other.
+func (set *ThreadSafeSet) TryIntersect(other Set) (Set, error) {
+	return set.try(other, (*ThreadUnsafeSet).TryIntersect)
+}
+
+// TryDifference is like Difference, but returns an error
+// instead of panicking if the set can't hold the elements
+// of other.
+func (set *ThreadSafeSet) TryDifference(other Set) (Set, error) {
+	return set.try(other, (*ThreadUnsafeSet).TryDifference)
+}
+
+// TrySymmetricDifference is like SymmetricDifference, but
+// returns an error instead of panicking if the set can't
+// hold the elements of other.
+func (set *ThreadSafeSet) TrySymmetricDifference(other Set) (Set, error) {
+	return set.try(other, (*ThreadUnsafeSet).TrySymmetricDifference)
+}
+
 // ToSlice returns the members of the set as a slice.
 func (set *ThreadSafeSet) ToSlice() []interface{} {
-	objs := make([]interface{}, 0, set.Size())
-	set.RLock()
-	for _, obj := range set.unsafeSet.dat {
-		objs = append(objs, obj)
+	if set.reader != nil {
+		view := set.reader.view()
+		objs := make([]interface{}, 0, view.len())
+		view.each(func(obj interface{}) bool {
+			objs = append(objs, obj)
+			return false
+		})
+		return objs
 	}
-	set.RUnlock()
-	return objs
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.ToSlice()
+}
+
+// ToSortedSlice returns the members of the set as a slice sorted
+// by less, or in the order of a nil less of Set.ToSortedSlice. The
+// set is not locked while sorting.
+func (set *ThreadSafeSet) ToSortedSlice(less func(a, b interface{}) bool) []interface{} {
+	return sortSlice(set.ToSlice(), less)
+}
+
+// ToMap returns a copy of the members of the set as the keys of
+// a map.
+func (set *ThreadSafeSet) ToMap() map[interface{}]struct{} {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.ToMap()
 }
 
 // MarshalJSON will marshal the set into a JSON-based representation.
@@ -330,12 +903,37 @@ func (set *ThreadSafeSet) MarshalJSON() ([]byte, error) {
 	return b, err
 }
 
-// UnmarshalJSON will unmarshal a JSON-based byte slice into a full Set datastructure.
-// For this to work, set subtypes must implemented the Marshal/Unmarshal interface.
+// MarshalJSONSorted is like MarshalJSON, but emits the
+// elements in the order of ToSortedSlice(nil), so that equal
+// sets always have the same JSON representation.
+func (set *ThreadSafeSet) MarshalJSONSorted() ([]byte, error) {
+	return marshalSorted(set.ToSlice())
+}
+
+// UnmarshalJSON replaces the elements of the set by those of a JSON
+// array. The array is decoded without holding the lock, into a new set
+// that is swapped in under the write lock, so readers see either the old
+// or the new elements. The set is left unchanged if b can't be decoded.
 func (set *ThreadSafeSet) UnmarshalJSON(b []byte) error {
 	set.RLock()
-	err := set.unsafeSet.UnmarshalJSON(b)
+	decoded := newThreadUnsafeSet()
+	if set.unsafeSet.store != nil {
+		decoded = set.unsafeSet.emptyLike(0)
+	}
 	set.RUnlock()
 
-	return err
+	if err := decoded.decodeJSON(b); err != nil {
+		return err
+	}
+	set.Lock()
+	defer set.Unlock()
+	if set.unsafeSet.store == nil {
+		set.unsafeSet = newThreadUnsafeSet()
+	}
+	// The type of the set is only checked now, as it may have changed
+	// while the array was decoded.
+	if typ := set.unsafeSet.typ; typ != nil && decoded.typ != nil && decoded.typ != typ && !decoded.conf.mixed {
+		return &TypeMismatchError{Want: typ, Got: decoded.typ}
+	}
+	return set.unsafeSet.replace(&decoded)
 }
@@ -47,6 +47,78 @@ func Test_AddConcurrent(t *testing.T) {
 	}
 }
 
+func Test_AppendConcurrent(t *testing.T) {
+	runtime.GOMAXPROCS(2)
+
+	s := NewSet()
+	ints := rand.Perm(N)
+	vals := make([]interface{}, 0, len(ints))
+	for _, v := range ints {
+		vals = append(vals, v)
+	}
+
+	var added int64
+	var wg sync.WaitGroup
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			atomic.AddInt64(&added, int64(s.Append(vals...)))
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	if added != N || s.Cardinality() != N {
+		t.Errorf("Expected %v elements to be added once, got %v (cardinality %v)", N, added, s.Cardinality())
+	}
+}
+
+func Test_AddIfConcurrent(t *testing.T) {
+	runtime.GOMAXPROCS(2)
+
+	s := NewSet()
+	var added int64
+
+	var wg sync.WaitGroup
+	wg.Add(N)
+	for i := 0; i < N; i++ {
+		go func(i int) {
+			if s.AddIf(i, func(current Set) bool { return current.Size() == 0 }) {
+				atomic.AddInt64(&added, 1)
+			}
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+
+	if added != 1 || s.Size() != 1 {
+		t.Errorf("Expected exactly one element to be added, got %v (size %v)", added, s.Size())
+	}
+}
+
+func Test_CompareAndAddConcurrent(t *testing.T) {
+	runtime.GOMAXPROCS(2)
+
+	s := NewSet()
+	var added int64
+
+	var wg sync.WaitGroup
+	wg.Add(N)
+	for i := 0; i < N; i++ {
+		go func(i int) {
+			if s.CompareAndAdd(i%2, 1-i%2) {
+				atomic.AddInt64(&added, 1)
+			}
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+
+	if added != 1 || s.Size() != 1 {
+		t.Errorf("Expected exactly one element to be added, got %v (size %v)", added, s.Size())
+	}
+}
+
 func Test_CardinalityConcurrent(t *testing.T) {
 	runtime.GOMAXPROCS(2)
 
@@ -71,6 +143,47 @@ func Test_CardinalityConcurrent(t *testing.T) {
 	wg.Wait()
 }
 
+func Test_GrowConcurrent(t *testing.T) {
+	runtime.GOMAXPROCS(2)
+
+	s := NewSetWithCapacity(10)
+	var wg sync.WaitGroup
+	for i := 0; i < N; i++ {
+		wg.Add(2)
+		go func(i int) {
+			s.Add(i)
+			wg.Done()
+		}(i)
+		go func() {
+			s.Grow(1)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	if s.Size() != N {
+		t.Errorf("Expected %v elements, got %v", N, s.Size())
+	}
+}
+
+func Test_ToThreadUnsafe(t *testing.T) {
+	s := NewSet(1).(*ThreadSafeSet)
+	u := s.ToThreadUnsafe()
+
+	u.Add(2)
+	if !s.Contains(1, 2) {
+		t.Errorf("Expected changes through ToThreadUnsafe to be seen, got %v", s)
+	}
+	s.Remove(1)
+	if u.Contains(1) || u.Size() != 1 {
+		t.Errorf("Expected changes through the set to be seen, got %v", u)
+	}
+	s.Clear()
+	if u.Size() != 0 {
+		t.Errorf("Expected Clear to empty the unsafe set, got %v", u)
+	}
+}
+
 func Test_ClearConcurrent(t *testing.T) {
 	runtime.GOMAXPROCS(2)
 
@@ -114,6 +227,43 @@ func Test_CloneConcurrent(t *testing.T) {
 	wg.Wait()
 }
 
+func Test_CloneCopyOnWrite(t *testing.T) {
+	runtime.GOMAXPROCS(2)
+
+	s := NewSet()
+	for i := 0; i < N; i++ {
+		s.Add(i)
+	}
+	clones := []Set{s, s.Clone(), s.Clone()}
+	clones = append(clones, clones[1].Clone())
+
+	var wg sync.WaitGroup
+	wg.Add(len(clones))
+	for i, c := range clones {
+		go func(i int, c Set) {
+			for v := 0; v < N; v++ {
+				if v%len(clones) == i {
+					c.Remove(v)
+				}
+				c.Contains(v)
+			}
+			wg.Done()
+		}(i, c)
+	}
+	wg.Wait()
+
+	for i, c := range clones {
+		if c.Size() != N-N/len(clones) {
+			t.Fatalf("Expected clone %v to have %v elements, got %v", i, N-N/len(clones), c.Size())
+		}
+		for v := i; v < N; v += len(clones) {
+			if c.Contains(v) {
+				t.Fatalf("Expected clone %v to have lost %v", i, v)
+			}
+		}
+	}
+}
+
 func Test_ContainsConcurrent(t *testing.T) {
 	runtime.GOMAXPROCS(2)
 
@@ -157,6 +307,40 @@ func Test_DifferenceConcurrent(t *testing.T) {
 	wg.Wait()
 }
 
+func Test_OppositeDifferencesConcurrent(t *testing.T) {
+	runtime.GOMAXPROCS(4)
+
+	s, ss := NewSet(), NewSet()
+	for i := 0; i < N; i++ {
+		s.Add(i)
+		ss.Add(i)
+	}
+
+	// Writers wedged between the read locks of the two sets
+	// deadlock this unless the locks are taken in a fixed order.
+	var wg sync.WaitGroup
+	for i := 0; i < N; i++ {
+		wg.Add(4)
+		go func() {
+			s.Difference(ss)
+			wg.Done()
+		}()
+		go func() {
+			ss.Difference(s)
+			wg.Done()
+		}()
+		go func(i int) {
+			s.Add(i + N)
+			wg.Done()
+		}(i)
+		go func() {
+			ss.UnionWith(s)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
+
 func Test_EqualConcurrent(t *testing.T) {
 	runtime.GOMAXPROCS(2)
 
@@ -282,6 +466,39 @@ func Test_IterConcurrent(t *testing.T) {
 	}
 }
 
+func Test_IterBufferedWithWriter(t *testing.T) {
+	s := NewSet()
+	for i := 0; i < N; i++ {
+		s.Add(i)
+	}
+
+	ch := s.IterBuffered(10)
+	<-ch
+
+	// The set must not stay locked while the channel is consumed.
+	s.Add(N)
+
+	n := 1
+	for range ch {
+		n++
+	}
+	if n != N {
+		t.Errorf("Expected %v elements, got %v", N, n)
+	}
+}
+
+func Test_IterBufferedNegative(t *testing.T) {
+	for _, s := range []Set{NewSet(1, 2, 3), NewThreadUnsafeSet(1, 2, 3), NewShardedSet(2, 1, 2, 3)} {
+		n := 0
+		for range s.IterBuffered(-1) {
+			n++
+		}
+		if n != 3 {
+			t.Errorf("Expected 3 elements from %T, got %v", s, n)
+		}
+	}
+}
+
 func Test_RemoveConcurrent(t *testing.T) {
 	runtime.GOMAXPROCS(2)
 
@@ -306,6 +523,31 @@ func Test_RemoveConcurrent(t *testing.T) {
 	}
 }
 
+func Test_RemoveAllConcurrent(t *testing.T) {
+	runtime.GOMAXPROCS(2)
+
+	s := NewSet()
+	vals := make([]interface{}, 0, N)
+	for _, v := range rand.Perm(N) {
+		s.Add(v)
+		vals = append(vals, v)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		go func(i int) {
+			s.RemoveAll(vals[i*N/10 : (i+1)*N/10]...)
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+
+	if s.Cardinality() != 0 {
+		t.Errorf("Expected cardinality 0; got %v", s.Cardinality())
+	}
+}
+
 func Test_StringConcurrent(t *testing.T) {
 	runtime.GOMAXPROCS(2)
 
@@ -418,6 +660,41 @@ func Test_UnmarshalJSON(t *testing.T) {
 	}
 }
 
+func Test_UnmarshalJSONReplaces(t *testing.T) {
+	s := NewSet("a", "b")
+	if err := s.UnmarshalJSON([]byte(`["c"]`)); err != nil || !s.Equal(NewSet("c")) {
+		t.Errorf("Expected the elements to be replaced by c, got %v, %v", s, err)
+	}
+	if err := s.UnmarshalJSON([]byte(`["d", 1]`)); err == nil || !s.Equal(NewSet("c")) {
+		t.Errorf("Expected a failed decoding to leave c, got %v, %v", s, err)
+	}
+	if err := s.UnmarshalJSON([]byte(`[1]`)); err == nil || !s.Equal(NewSet("c")) {
+		t.Errorf("Expected numbers not to replace strings, got %v, %v", s, err)
+	}
+	var zero ThreadSafeSet
+	if err := zero.UnmarshalJSON([]byte(`["z"]`)); err != nil || !zero.Contains("z") {
+		t.Errorf("Expected the zero value to take z, got %v, %v", &zero, err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < N; i++ {
+			s.UnmarshalJSON([]byte(`["x", "y"]`))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < N; i++ {
+			if n := s.Size(); n != 1 && n != 2 {
+				t.Errorf("Expected c or x and y, got %v", s)
+			}
+		}
+	}()
+	wg.Wait()
+}
+
 func Test_MarshalJSON(t *testing.T) {
 	expected := NewSet(
 		[]interface{}{
@@ -448,3 +725,66 @@ func Test_MarshalJSON(t *testing.T) {
 		t.Errorf("Expected no difference, got: %v", expected.Difference(actual))
 	}
 }
+
+func Test_UpdateConcurrent(t *testing.T) {
+	for _, s := range []Set{NewSet(0), NewShardedSet(4, 0)} {
+		// The set always holds exactly one element, moved by the writers.
+		var wg sync.WaitGroup
+		var stop int32
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < N; i++ {
+					s.Update(func(tx SetTx) {
+						var cur interface{}
+						tx.Each(func(elem interface{}) bool {
+							cur = elem
+							return true
+						})
+						tx.Remove(cur)
+						tx.Add(cur.(int) + 1)
+					})
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			atomic.StoreInt32(&stop, 1)
+		}()
+		for atomic.LoadInt32(&stop) == 0 {
+			if n := len(s.ToSlice()); n != 1 {
+				t.Fatalf("Expected 1 element, saw %v", n)
+			}
+		}
+		if !s.Contains(4*N) || s.Size() != 1 {
+			t.Errorf("Expected only %v, got %v", 4*N, s)
+		}
+	}
+}
+
+func Test_ApplyEachOtherConcurrent(t *testing.T) {
+	a, b := NewSet(1, 2), NewSet(3, 4)
+	a.Apply(NewChangeSet(a, nil))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < N; j++ {
+				a.Apply(NewChangeSet(b, nil))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < N; j++ {
+				b.Apply(NewChangeSet(a, nil))
+			}
+		}()
+	}
+	wg.Wait()
+	if !a.Equal(NewSet(1, 2, 3, 4)) || !b.Equal(a) {
+		t.Errorf("Expected both sets to hold 1 to 4, got %v and %v", a, b)
+	}
+}
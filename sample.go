@@ -0,0 +1,119 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// sample returns n elements of elems selected uniformly at random by r,
+// or all of them in random order if there are no more than n. elems are
+// put in a canonical order first, so that the same r always selects the
+// same elements from the same set. A nil r uses the default source of
+// package math/rand.
+func sample(elems []interface{}, n int, r *rand.Rand) []interface{} {
+	if n <= 0 || len(elems) == 0 {
+		return []interface{}{}
+	}
+	intn := intnOf(r)
+	sort.Stable(newByKey(elems))
+
+	if n > len(elems) {
+		n = len(elems)
+	}
+	// Partial Fisher-Yates shuffle of the first n positions.
+	for i := 0; i < n; i++ {
+		j := i + intn(len(elems)-i)
+		elems[i], elems[j] = elems[j], elems[i]
+	}
+	return elems[:n]
+}
+
+// randomElement returns an element of elems selected uniformly at random
+// by r, like sample(elems, 1, r) but without sorting elems: only the
+// element at the drawn position of the canonical order is looked up.
+func randomElement(elems []interface{}, r *rand.Rand) (interface{}, bool) {
+	if len(elems) == 0 {
+		return nil, false
+	}
+	k := intnOf(r)(len(elems))
+	newByKey(elems).selectAt(k)
+	return elems[k], true
+}
+
+// intnOf returns the Intn method of r, or rand.Intn if r is nil.
+func intnOf(r *rand.Rand) func(n int) int {
+	if r == nil {
+		return rand.Intn
+	}
+	return r.Intn
+}
+
+// byKey sorts elements by their precomputed sort keys. The keys include
+// the type of the elements, so that elements of different types with the
+// same hash get different keys.
+type byKey struct {
+	keys  []string
+	elems []interface{}
+}
+
+func newByKey(elems []interface{}) byKey {
+	keys := make([]string, len(elems))
+	for i, elem := range elems {
+		keys[i] = elemKey(elem)
+	}
+	return byKey{keys: keys, elems: elems}
+}
+
+// selectAt moves the element that sorting would put at position k there,
+// in linear time on average, by partitioning around the middle key until
+// only elements with the key of position k are left.
+func (b byKey) selectAt(k int) {
+	lo, hi := 0, b.Len()
+	for hi-lo > 1 {
+		pivot := b.keys[lo+(hi-lo)/2]
+		// Partition into keys less than, equal to and greater than the
+		// pivot, in [lo, lt), [lt, gt) and [gt, hi).
+		lt, i, gt := lo, lo, hi
+		for i < gt {
+			switch {
+			case b.keys[i] < pivot:
+				b.Swap(lt, i)
+				lt++
+				i++
+			case b.keys[i] > pivot:
+				gt--
+				b.Swap(i, gt)
+			default:
+				i++
+			}
+		}
+		switch {
+		case k < lt:
+			hi = lt
+		case k >= gt:
+			lo = gt
+		default:
+			return
+		}
+	}
+}
+
+func (b byKey) Len() int           { return len(b.keys) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.elems[i], b.elems[j] = b.elems[j], b.elems[i]
+}
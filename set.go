@@ -13,11 +13,81 @@
 // limitations under the License.
 package goset
 
+import (
+	"context"
+	"io"
+	"math/rand"
+
+	"github.com/b1tkeeper/goset/sketch"
+)
+
+// Set is a set of elements of a single type.
+//
+// The methods that take another set accept sets of any type. They are
+// fastest for two sets of the same type, a set of another type is
+// copied into a set that identifies its elements like the receiver does
+// first. If the receiver can't hold the elements of the other set, the
+// methods panic, and the Try variants return an error.
 type Set interface {
 	// Add adds an element to the set. Returns whether
 	// the item was added.
 	Add(val interface{}) bool
 
+	// Append adds all given elements to the set. Returns the
+	// number of items that were added.
+	Append(vals ...interface{}) int
+
+	// AddIf adds an element to the set only if pred, called with
+	// the current contents of the set, returns true. The check and
+	// the insertion happen atomically. Returns whether the item
+	// was added.
+	//
+	// pred must not modify or retain current.
+	AddIf(val interface{}, pred func(current Set) bool) bool
+
+	// CompareAndAdd adds an element to the set only if none of
+	// the expectedAbsent elements is in the set. The check and
+	// the insertion happen atomically. Returns whether the item
+	// was added.
+	CompareAndAdd(val interface{}, expectedAbsent ...interface{}) bool
+
+	// Replace removes old from the set and adds new in its place,
+	// atomically, and returns whether old was in the set. new may
+	// hash differently than old, and may already be in the set,
+	// which then holds it once. The set is left untouched if old
+	// isn't in it or new can't be added to it.
+	Replace(old, new interface{}) bool
+
+	// UpdateElem replaces the element of the set equal to val, if
+	// any, by the result of f called with it, see Replace. f must
+	// not access the set.
+	UpdateElem(val interface{}, f func(cur interface{}) interface{})
+
+	// Update calls f with a view of the set and applies all the
+	// adds and removes made through it at once when f returns, so
+	// that other goroutines never see part of them. Nothing is
+	// applied if f panics, and tx.Rollback discards the changes
+	// made before it.
+	//
+	// f must not access the set itself, nor retain tx.
+	Update(f func(tx SetTx))
+
+	// Any reports whether pred returns true for at least
+	// one element of the set. It stops at the first such
+	// element.
+	Any(pred func(elem interface{}) bool) bool
+
+	// All reports whether pred returns true for every
+	// element of the set. It stops at the first element for
+	// which pred returns false. All is true for the empty
+	// set.
+	All(pred func(elem interface{}) bool) bool
+
+	// None reports whether pred returns false for every
+	// element of the set. It stops at the first element for
+	// which pred returns true.
+	None(pred func(elem interface{}) bool) bool
+
 	// Cardinality Returns the number of elements in the set.
 	Cardinality() int
 
@@ -28,147 +98,413 @@ type Set interface {
 	// the empty set.
 	Clear()
 
-	// Clone returns a deep-clone of the set using the same
-	// implementation, duplicating all keys.
+	// Grow makes room for at least n more elements, so that
+	// they can be added without growing the set repeatedly.
+	Grow(n int)
+
+	// Clone returns a clone of the set using the same
+	// implementation. Changes to the clone don't affect
+	// the set, and vice versa.
 	Clone() Set
 
+	// Combinations returns an Iterator over all subsets of
+	// this set with k elements. The subsets are built lazily
+	// as they are received, so they are never all held in
+	// memory at once.
+	Combinations(k int) *Iterator
+
 	// Contains returns whether the given items
 	// are all in the set.
 	Contains(val ...interface{}) bool
 
+	// Get returns the element of the set equal to val, which
+	// may differ from val in the data its Hash and Equal ignore, and
+	// whether there is one.
+	Get(val interface{}) (interface{}, bool)
+
 	// Difference returns the difference between this set
 	// and other. The returned set will contain
 	// all elements of this set that are not also
 	// elements of other.
-	//
-	// Note that the argument to Difference
-	// must be of the same type as the receiver
-	// of the method. Otherwise, Difference will
-	// panic.
 	Difference(other Set) Set
 
+	// Diff returns what must be added to and removed from this
+	// set to turn it into other: the elements of other that are
+	// not in this set, and the elements of this set that are not
+	// in other.
+	Diff(other Set) (added Set, removed Set)
+
+	// Apply removes the removes of cs from this set and adds its
+	// adds, atomically: if an element can't be added or removed,
+	// the set is left unchanged and Apply panics.
+	Apply(cs ChangeSet)
+
+	// DifferenceContext is like Difference, but checks ctx
+	// while it runs and aborts with an *OperationError once
+	// ctx is done, leaving both sets untouched. Progress
+	// is reported to the func registered with WithProgress.
+	DifferenceContext(ctx context.Context, other Set) (Set, error)
+
+	// DifferenceCardinality returns the number of elements
+	// of Difference(other), without
+	// building that set.
+	DifferenceCardinality(other Set) int
+
+	// DifferenceWith removes all elements of other from this
+	// set. It is the in-place variant of Difference.
+	DifferenceWith(other Set)
+
+	// Filter returns a new set with the elements of this
+	// set for which pred returns true.
+	Filter(pred func(elem interface{}) bool) Set
+
+	// GroupBy partitions the set by the key keyFn returns
+	// for each element, returning a new set for every key.
+	GroupBy(keyFn func(elem interface{}) string) map[string]Set
+
 	// Equal determines if two sets are equal to each
 	// other. If they have the same cardinality
 	// and contain the same elements, they are
 	// considered equal. The order in which
 	// the elements were added is irrelevant.
-	//
-	// Note that the argument to Equal must be
-	// of the same type as the receiver of the
-	// method. Otherwise, Equal will panic.
 	Equal(other Set) bool
 
 	// Intersect returns a new set containing only the elements
 	// that exist only in both sets.
-	//
-	// Note that the argument to Intersect
-	// must be of the same type as the receiver
-	// of the method. Otherwise, Intersect will
-	// panic.
 	Intersect(other Set) Set
 
+	// IntersectContext is like Intersect, but checks ctx
+	// while it runs and aborts with an *OperationError once
+	// ctx is done, leaving both sets untouched. Progress
+	// is reported to the func registered with WithProgress.
+	IntersectContext(ctx context.Context, other Set) (Set, error)
+
+	// IntersectCardinality returns the number of elements
+	// of Intersect(other), without
+	// building that set.
+	IntersectCardinality(other Set) int
+
+	// Jaccard returns the Jaccard index of this set and other,
+	// the size of their intersection divided by the size of their
+	// union, computed without building either set. It is 1 for
+	// two empty sets.
+	Jaccard(other Set) float64
+
+	// Dice returns the Sørensen–Dice coefficient of this set and
+	// other, twice the size of their intersection divided by the
+	// sum of their sizes. It is 1 for two empty sets.
+	Dice(other Set) float64
+
+	// Overlap returns the overlap coefficient of this set and
+	// other, the size of their intersection divided by the size
+	// of the smaller set. It is 1 for two empty sets and 0 if
+	// only one of them is empty.
+	Overlap(other Set) float64
+
+	// IntersectWith removes all elements that are not in other
+	// from this set. It is the in-place variant of Intersect.
+	IntersectWith(other Set)
+
+	// IsDisjoint determines if this set and the other set
+	// have no elements in common. It stops at the first
+	// common element, without building the intersection.
+	IsDisjoint(other Set) bool
+
+	// Overlaps determines if this set and the other set
+	// have at least one element in common. It is the
+	// inverse of IsDisjoint.
+	Overlaps(other Set) bool
+
 	// IsProperSubset determines if every element in this set is in
 	// the other set but the two sets are not equal.
-	//
-	// Note that the argument to IsProperSubset
-	// must be of the same type as the receiver
-	// of the method. Otherwise, IsProperSubset
-	// will panic.
 	IsProperSubset(other Set) bool
 
 	// IsProperSuperset determines if every element in the other set
 	// is in this set but the two sets are not
 	// equal.
-	//
-	// Note that the argument to IsSuperset
-	// must be of the same type as the receiver
-	// of the method. Otherwise, IsSuperset will
-	// panic.
 	IsProperSuperset(other Set) bool
 
 	// IsSubset determines if every element in this set is in
 	// the other set.
-	//
-	// Note that the argument to IsSubset
-	// must be of the same type as the receiver
-	// of the method. Otherwise, IsSubset will
-	// panic.
 	IsSubset(other Set) bool
 
 	// IsSuperset determines if every element in the other set
 	// is in this set.
-	//
-	// Note that the argument to IsSuperset
-	// must be of the same type as the receiver
-	// of the method. Otherwise, IsSuperset will
-	// panic.
 	IsSuperset(other Set) bool
 
+	// IsSubsetWithin determines if at most k elements of
+	// this set are missing from the other set, i.e. if this
+	// set is a subset of the other set give or take k
+	// elements.
+	IsSubsetWithin(other Set, k int) bool
+
+	// IsSupersetWithin determines if at most k elements of
+	// the other set are missing from this set.
+	IsSupersetWithin(other Set, k int) bool
+
 	// Each iterates over elements and executes the passed func against each element.
 	// If passed func returns true, stop iteration at the time.
 	Each(func(elem interface{}) bool)
 
+	// EachErr iterates over elements and executes the passed func against each element.
+	// If passed func returns an error, stop iteration and return that error.
+	EachErr(func(elem interface{}) error) error
+
 	// Iter returns a channel of elements that you can
 	// range over.
+	//
+	// The elements are snapshotted before Iter returns, so
+	// the set may be modified, and is not locked, while the
+	// channel is consumed.
 	Iter() <-chan interface{}
 
+	// IterBuffered is like Iter, but the returned channel is
+	// buffered for n elements, letting the producer run ahead
+	// of a slow consumer. A negative n is treated as 0.
+	IterBuffered(n int) <-chan interface{}
+
 	// Iterator returns an Iterator object that you can
 	// use to range over the set.
+	//
+	// Like Iter, the elements are snapshotted before
+	// Iterator returns.
 	Iterator() *Iterator
 
+	// Cursor returns a pull-style Cursor over a snapshot of
+	// the set. Unlike Iter and Iterator it needs neither a
+	// channel nor a goroutine, so an abandoned Cursor leaks
+	// nothing.
+	Cursor() *Cursor
+
+	// MinBy returns the smallest element of the set
+	// according to less, or false if the set is empty.
+	MinBy(less func(a, b interface{}) bool) (interface{}, bool)
+
+	// MaxBy returns the largest element of the set
+	// according to less, or false if the set is empty.
+	MaxBy(less func(a, b interface{}) bool) (interface{}, bool)
+
+	// Min returns the smallest element of the set, or false
+	// if the set is empty. Numbers are compared by value,
+	// strings byte by byte, other elements by their hash.
+	Min() (interface{}, bool)
+
+	// Max returns the largest element of the set, or false
+	// if the set is empty. Numbers are compared by value,
+	// strings byte by byte, other elements by their hash.
+	Max() (interface{}, bool)
+
+	// Freeze returns an immutable copy of the set.
+	Freeze() FrozenSet
+
+	// Hash returns a hash of the elements of the set, which
+	// does not depend on their order. It makes sets Hashable,
+	// so that they can be elements of other sets.
+	//
+	// Note that a set must not be modified while it is an
+	// element of another set.
+	Hash() string
+
+	// PowerSet returns a new set with all subsets of this
+	// set, including the empty set and the set itself. It
+	// panics if the set has more than PowerSetLimit elements.
+	PowerSet() Set
+
+	// PowerSetIterator returns an Iterator over all subsets
+	// of this set, which are built lazily as they are
+	// received.
+	PowerSetIterator() *Iterator
+
+	// RandomSample returns n distinct elements of the set,
+	// selected uniformly at random by r, or all elements in
+	// random order if the set has no more than n elements.
+	// Given equally seeded sources, equal sets yield the
+	// same sample. A nil r uses the default source of
+	// package math/rand.
+	RandomSample(n int, r *rand.Rand) []interface{}
+
+	// RandomElement returns an element of the set selected
+	// uniformly at random by r, or false if the set is empty.
+	// Like RandomSample, it is reproducible for a seeded r.
+	RandomElement(r *rand.Rand) (interface{}, bool)
+
 	// Remove remove a single element from the set.
 	Remove(i interface{})
 
+	// RemoveAll removes all given elements from the set.
+	RemoveAll(vals ...interface{})
+
+	// RemoveIf removes all elements for which pred returns
+	// true and returns the number of elements removed.
+	RemoveIf(pred func(elem interface{}) bool) int
+
+	// RetainIf removes all elements for which pred returns
+	// false and returns the number of elements removed.
+	RetainIf(pred func(elem interface{}) bool) int
+
 	// String provides a convenient string representation
 	// of the current state of the set.
 	String() string
 
 	// SymmetricDifference returns a new set with all elements which are
 	// in either this set or the other set but not in both.
-	//
-	// Note that the argument to SymmetricDifference
-	// must be of the same type as the receiver
-	// of the method. Otherwise, SymmetricDifference
-	// will panic.
 	SymmetricDifference(other Set) Set
 
+	// SymmetricDifferenceWith removes the elements of other
+	// that are in this set and adds those that are not. It is
+	// the in-place variant of SymmetricDifference.
+	SymmetricDifferenceWith(other Set)
+
 	// Union returns a new set with all elements in both sets.
-	//
-	// Note that the argument to Union must be of the
-	// same type as the receiver of the method.
-	// Otherwise, IsSuperset will panic.
 	Union(other Set) Set
 
+	// UnionContext is like Union, but checks ctx while it
+	// runs and aborts with an *OperationError once ctx is
+	// done, leaving both sets untouched. Progress is
+	// reported to the func registered with WithProgress.
+	UnionContext(ctx context.Context, other Set) (Set, error)
+
+	// UnionCardinality returns the number of elements
+	// of Union(other), without
+	// building that set.
+	UnionCardinality(other Set) int
+
+	// UnionWith adds all elements of other to this set. It is
+	// the in-place variant of Union.
+	UnionWith(other Set)
+
 	// Pop removes and returns an arbitrary item from the set.
 	Pop() (interface{}, bool)
 
+	// PopN removes and returns up to n arbitrary items from the
+	// set, or fewer if the set has less than n items.
+	PopN(n int) []interface{}
+
+	// PopIf removes and returns the first item found that
+	// satisfies pred, or false if there is no such item.
+	PopIf(pred func(elem interface{}) bool) (interface{}, bool)
+
+	// TryAdd is like Add, but returns an error instead of
+	// panicking if val can't be hashed or is of a different
+	// type than the elements of the set.
+	TryAdd(val interface{}) (bool, error)
+
+	// TryRemove is like Remove, but returns an error instead of
+	// panicking if val can't be hashed.
+	TryRemove(val interface{}) error
+
+	// TryUnion is like Union, but returns an error instead of
+	// panicking if the set can't hold the elements of other.
+	TryUnion(other Set) (Set, error)
+
+	// TryIntersect is like Intersect, but returns an error
+	// instead of panicking if the set can't hold the elements
+	// of other.
+	TryIntersect(other Set) (Set, error)
+
+	// TryDifference is like Difference, but returns an error
+	// instead of panicking if the set can't hold the elements
+	// of other.
+	TryDifference(other Set) (Set, error)
+
+	// TrySymmetricDifference is like SymmetricDifference, but
+	// returns an error instead of panicking if the set can't
+	// hold the elements of other.
+	TrySymmetricDifference(other Set) (Set, error)
+
 	// ToSlice returns the members of the set as a slice.
 	ToSlice() []interface{}
 
+	// ToSortedSlice returns the members of the set as a slice
+	// sorted by less. A nil less sorts numbers by value, strings
+	// byte by byte, so "file10" sorts before "file2", and other
+	// elements in an arbitrary but deterministic order. Pass
+	// NaturalOrder to sort the numbers within strings by value.
+	ToSortedSlice(less func(a, b interface{}) bool) []interface{}
+
+	// ToMap returns a copy of the members of the set as the
+	// keys of a map. It panics if an element is a Hashable
+	// of a type that can't be used as a map key.
+	ToMap() map[interface{}]struct{}
+
 	// MarshalJSON will marshal the set into a JSON-based representation.
 	MarshalJSON() ([]byte, error)
 
-	// UnmarshalJSON will unmarshal a JSON-based byte slice into a full Set datastructure.
-	// For this to work, set subtypes must implemented the Marshal/Unmarshal interface.
+	// MarshalJSONSorted is like MarshalJSON, but emits the
+	// elements in the order of ToSortedSlice(nil), so that equal
+	// sets always have the same JSON representation.
+	MarshalJSONSorted() ([]byte, error)
+
+	// UnmarshalJSON replaces the elements of the set by those of a
+	// JSON array. The set is left unchanged if the array can't be
+	// decoded, or holds elements of a different type than the set.
 	UnmarshalJSON(b []byte) error
+
+	// Save writes the set to w in a compact binary format, which
+	// Load reads.
+	Save(w io.Writer) error
+
+	// AddToSketch adds the elements of the set to h, like a
+	// HyperLogLog or a MinHash of package sketch.
+	AddToSketch(h sketch.Sketch)
+
+	// ToBloomFilter returns a compact Bloom filter of the elements of
+	// the set with the given false positive rate.
+	ToBloomFilter(fpRate float64) *BloomFilter
 }
 
 // NewSet creates and returns a new set with the given elements.
+// Any Options among vals configure the set instead.
 // Operations on the resulting set are thread-safe.
 func NewSet(vals ...interface{}) Set {
-	s := newThreadSafeSet()
-	for _, item := range vals {
-		s.Add(item)
-	}
-	return &s
+	s := newSetFrom(vals)
+	return s.ToThreadSafe()
 }
 
 // NewThreadUnsafeSet creates and returns a new set with the given elements.
+// Any Options among vals configure the set instead.
 // Operations on the resulting set are not thread-safe.
 func NewThreadUnsafeSet(vals ...interface{}) Set {
+	s := newSetFrom(vals)
+	return &s
+}
+
+// NewSetWithCapacity creates and returns a new, empty set with room
+// for n elements.
+// Operations on the resulting set are thread-safe.
+func NewSetWithCapacity(n int) Set {
+	return &ThreadSafeSet{unsafeSet: ThreadUnsafeSet{store: newHashStore(n)}}
+}
+
+// NewThreadUnsafeSetWithCapacity creates and returns a new, empty set
+// with room for n elements.
+// Operations on the resulting set are not thread-safe.
+func NewThreadUnsafeSetWithCapacity(n int) Set {
+	return &ThreadUnsafeSet{store: newHashStore(n)}
+}
+
+// NewSetFromChannel creates and returns a new set with the elements
+// received from ch until it is closed.
+// Operations on the resulting set are thread-safe.
+func NewSetFromChannel(ch <-chan interface{}) Set {
+	s, _ := NewSetFromChannelContext(context.Background(), ch)
+	return s
+}
+
+// NewSetFromChannelContext is like NewSetFromChannel, but stops receiving
+// once ctx is done. In that case the set of the elements received so far
+// is returned together with an *OperationError.
+func NewSetFromChannelContext(ctx context.Context, ch <-chan interface{}) (Set, error) {
 	s := newThreadUnsafeSet()
-	for _, item := range vals {
-		s.Add(item)
+	for {
+		select {
+		case <-ctx.Done():
+			return s.ToThreadSafe(), &OperationError{Op: "receive", Err: ctx.Err()}
+		case item, ok := <-ch:
+			if !ok {
+				return s.ToThreadSafe(), nil
+			}
+			s.Add(item)
+		}
 	}
-	return &s
 }
@@ -0,0 +1,1174 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/b1tkeeper/goset/sketch"
+)
+
+// ShardedSet is a thread-safe set that spreads its elements over a number
+// of shards by their hash, each a ThreadSafeSet with its own lock. Writers
+// of elements in different shards don't contend with each other, which
+// makes it a drop-in replacement for a ThreadSafeSet of many elements
+// changed by many goroutines at once.
+//
+// The methods that take a single element, or a few, only lock the shards
+// of those elements. Pop, PopN, PopIf, RemoveIf and RetainIf go through
+// the shards one at a time. All other methods lock every shard and work
+// on a copy of the whole set, so they are slower than those of a
+// ThreadSafeSet.
+type ShardedSet struct {
+	shards []*ThreadSafeSet
+	typ    atomic.Value // shardType, the type of the elements of all shards
+	pinMu  sync.Mutex   // Held while pin sets typ
+	hasher Hasher       // Hasher of the shards, nil for the built-in hash
+}
+
+// shardType is the element type of a ShardedSet, nil while the set has
+// not been given its first element.
+type shardType struct {
+	typ reflect.Type
+}
+
+// NewShardedSet creates and returns a new set with the given elements,
+// spread over the given number of shards. A number of shards below one
+// means four times GOMAXPROCS. Any Options among vals configure each
+// shard, and the Hasher of WithHasher also picks the shard of an
+// element. Operations on the resulting set are thread-safe.
+func NewShardedSet(shards int, vals ...interface{}) *ShardedSet {
+	if shards < 1 {
+		shards = 4 * runtime.GOMAXPROCS(0)
+	}
+	var opts []interface{}
+	for _, v := range vals {
+		if opt, ok := v.(Option); ok {
+			opts = append(opts, opt)
+		}
+	}
+	set := &ShardedSet{shards: make([]*ThreadSafeSet, shards)}
+	for i := range set.shards {
+		s := newSetFrom(opts)
+		set.shards[i] = s.ToThreadSafe()
+	}
+	// Elements the shards identify by a Hasher are routed by it too, so
+	// that the same element always lands in the same shard.
+	if hs, ok := unwrapStore(set.shards[0].unsafeSet.store, false).(*hasherStore); ok {
+		set.hasher = hs.h
+	}
+	set.typ.Store(shardType{})
+	for _, v := range vals {
+		if _, ok := v.(Option); !ok {
+			set.Add(v)
+		}
+	}
+	return set
+}
+
+// Shards returns the number of shards of the set.
+func (set *ShardedSet) Shards() int {
+	return len(set.shards)
+}
+
+// index returns the shard of val, or an error if val can't be hashed.
+func (set *ShardedSet) index(val interface{}) (int, error) {
+	if set.hasher != nil {
+		return int(hashKey(set.hasher.Hash(val)) % uint64(len(set.shards))), nil
+	}
+	hash, err := calcHash(val)
+	if err != nil {
+		return 0, err
+	}
+	return int(hashKey(hash) % uint64(len(set.shards))), nil
+}
+
+// pin makes typ the element type of the set unless it has one already,
+// and returns an error if it has another one. The caller must hold the
+// lock of a shard, so that the set isn't cleared meanwhile.
+func (set *ShardedSet) pin(typ reflect.Type) error {
+	if typ == nil || set.shards[0].unsafeSet.conf.mixed {
+		return nil
+	}
+	cur := set.typ.Load().(shardType)
+	if cur.typ == nil {
+		// Shards locked by different goroutines may pin at once.
+		set.pinMu.Lock()
+		defer set.pinMu.Unlock()
+		if cur = set.typ.Load().(shardType); cur.typ == nil {
+			set.typ.Store(shardType{typ})
+			return nil
+		}
+	}
+	if cur.typ != typ {
+		return &TypeMismatchError{Want: cur.typ, Got: typ}
+	}
+	return nil
+}
+
+// lockAll write-locks all shards and returns a func releasing them.
+func (set *ShardedSet) lockAll() func() {
+	for _, s := range set.shards {
+		s.Lock()
+	}
+	return func() {
+		for _, s := range set.shards {
+			s.Unlock()
+		}
+	}
+}
+
+// rlockAll read-locks all shards and returns a func releasing them.
+func (set *ShardedSet) rlockAll() func() {
+	for _, s := range set.shards {
+		s.RLock()
+	}
+	return func() {
+		for _, s := range set.shards {
+			s.RUnlock()
+		}
+	}
+}
+
+// lockFor locks the shards of vals, for writing if write is set, and
+// returns the shard of each val and a func releasing the locks. The shard
+// of a val that can't be hashed is -1.
+func (set *ShardedSet) lockFor(vals []interface{}, write bool) ([]int, func()) {
+	idx := make([]int, len(vals))
+	locked := make([]bool, len(set.shards))
+	for i, v := range vals {
+		j, err := set.index(v)
+		if err != nil {
+			j = -1
+		} else {
+			locked[j] = true
+		}
+		idx[i] = j
+	}
+	// Shards are always locked in order, so that two calls can't
+	// deadlock.
+	for i, s := range set.shards {
+		switch {
+		case !locked[i]:
+		case write:
+			s.Lock()
+		default:
+			s.RLock()
+		}
+	}
+	return idx, func() {
+		for i, s := range set.shards {
+			switch {
+			case !locked[i]:
+			case write:
+				s.Unlock()
+			default:
+				s.RUnlock()
+			}
+		}
+	}
+}
+
+// like returns a new, empty set backed by the same kind of store as the
+// shards.
+func (set *ShardedSet) like() ThreadUnsafeSet {
+	s := set.shards[0]
+	s.RLock()
+	defer s.RUnlock()
+	return s.unsafeSet.emptyLike(0)
+}
+
+// snapshot returns a copy of all elements of the set.
+func (set *ShardedSet) snapshot() *ThreadUnsafeSet {
+	defer set.rlockAll()()
+	return set.snapshotLocked()
+}
+
+// snapshotLocked is snapshot for a caller holding the locks of all
+// shards.
+func (set *ShardedSet) snapshotLocked() *ThreadUnsafeSet {
+	n := 0
+	for _, s := range set.shards {
+		n += s.unsafeSet.Size()
+	}
+	ret := set.shards[0].unsafeSet.emptyLike(n)
+	for _, s := range set.shards {
+		s.unsafeSet.store.each(func(elem interface{}) bool {
+			ret.Add(elem)
+			return false
+		})
+	}
+	return &ret
+}
+
+// operand returns a copy of other in a set like the shards, or an error
+// if it can't hold the elements of other.
+func (set *ShardedSet) operand(other Set) (*ThreadUnsafeSet, error) {
+	if o, ok := other.(*ShardedSet); ok {
+		return o.snapshot(), nil
+	}
+	if other == nil {
+		return nil, &IncompatibleSetError{Set: set, Other: other}
+	}
+	like := set.like()
+	o := &like
+	var err error
+	other.Each(func(elem interface{}) bool {
+		_, err = o.TryAdd(elem)
+		return err != nil
+	})
+	return o, err
+}
+
+// split returns the elements of u by shard.
+func (set *ShardedSet) split(u *ThreadUnsafeSet) []*ThreadUnsafeSet {
+	parts := make([]*ThreadUnsafeSet, len(set.shards))
+	for i := range parts {
+		p := u.emptyLike(0)
+		parts[i] = &p
+	}
+	u.store.each(func(elem interface{}) bool {
+		i, _ := set.index(elem)
+		parts[i].Add(elem)
+		return false
+	})
+	return parts
+}
+
+// emptyLike returns a new, empty set with as many shards as set, backed
+// by the same kind of store.
+func (set *ShardedSet) emptyLike() *ShardedSet {
+	ret := &ShardedSet{shards: make([]*ThreadSafeSet, len(set.shards)), hasher: set.hasher}
+	like := set.like()
+	for i := range ret.shards {
+		ret.shards[i] = &ThreadSafeSet{unsafeSet: like.emptyLike(0)}
+	}
+	ret.typ.Store(shardType{})
+	return ret
+}
+
+// adopt returns a new set with as many shards as set, holding the
+// elements of u.
+func (set *ShardedSet) adopt(u Set) *ShardedSet {
+	ret := set.emptyLike()
+	ret.unionWith(u.(*ThreadUnsafeSet))
+	return ret
+}
+
+// unionWith adds the elements of u to the set. The caller must hold the
+// locks of all shards, unless the set is not shared yet.
+func (set *ShardedSet) unionWith(u *ThreadUnsafeSet) {
+	if u.Size() == 0 {
+		return
+	}
+	if err := set.pin(u.typ); err != nil {
+		panic(err)
+	}
+	for i, p := range set.split(u) {
+		set.shards[i].unsafeSet.UnionWith(p)
+	}
+}
+
+// Add adds an element to the set. Returns whether
+// the item was added.
+func (set *ShardedSet) Add(val interface{}) bool {
+	added, err := set.TryAdd(val)
+	if err != nil {
+		panic(err)
+	}
+	return added
+}
+
+// TryAdd is like Add, but returns an error instead of
+// panicking if val can't be hashed or is of a different
+// type than the elements of the set.
+func (set *ShardedSet) TryAdd(val interface{}) (bool, error) {
+	if val == nil {
+		return set.shards[0].TryAdd(val)
+	}
+	i, err := set.index(val)
+	if err != nil {
+		return false, err
+	}
+	s := set.shards[i]
+	s.Lock()
+	defer s.Unlock()
+	if err := set.pin(reflect.TypeOf(val)); err != nil {
+		return false, err
+	}
+	return s.unsafeSet.TryAdd(val)
+}
+
+// Append adds all given elements to the set. Returns the
+// number of items that were added.
+func (set *ShardedSet) Append(vals ...interface{}) int {
+	n := 0
+	for _, v := range vals {
+		if set.Add(v) {
+			n++
+		}
+	}
+	return n
+}
+
+// AddIf adds an element to the set only if pred, called with
+// a copy of the current contents of the set, returns true. The
+// check and the insertion happen atomically. Returns whether
+// the item was added.
+//
+// pred runs with all shards locked, it must not access the set.
+func (set *ShardedSet) AddIf(val interface{}, pred func(current Set) bool) bool {
+	i, err := set.index(val)
+	if err != nil {
+		panic(err)
+	}
+	defer set.lockAll()()
+	if !pred(set.snapshotLocked()) {
+		return false
+	}
+	if err := set.pin(reflect.TypeOf(val)); err != nil {
+		panic(err)
+	}
+	return set.shards[i].unsafeSet.Add(val)
+}
+
+// CompareAndAdd adds an element to the set only if none of
+// the expectedAbsent elements is in the set. The check and
+// the insertion happen atomically, with the shards of all
+// given elements locked. Returns whether the item was added.
+func (set *ShardedSet) CompareAndAdd(val interface{}, expectedAbsent ...interface{}) bool {
+	vals := append([]interface{}{val}, expectedAbsent...)
+	idx, unlock := set.lockFor(vals, true)
+	defer unlock()
+	if idx[0] < 0 {
+		_, err := set.index(val)
+		panic(err)
+	}
+	for k, v := range expectedAbsent {
+		if i := idx[k+1]; i >= 0 && set.shards[i].unsafeSet.Contains(v) {
+			return false
+		}
+	}
+	if err := set.pin(reflect.TypeOf(val)); err != nil {
+		panic(err)
+	}
+	return set.shards[idx[0]].unsafeSet.Add(val)
+}
+
+// Replace removes old from the set and adds new in its place,
+// atomically, with the shards of both locked, and returns whether
+// old was in the set. new may hash differently than old.
+func (set *ShardedSet) Replace(old, new interface{}) bool {
+	idx, unlock := set.lockFor([]interface{}{old, new}, true)
+	defer unlock()
+	return set.replaceLocked(old, new, idx[0], idx[1])
+}
+
+// replaceLocked replaces old, in shard i, by new, in shard j. The
+// caller must hold the write locks of both shards.
+func (set *ShardedSet) replaceLocked(old, new interface{}, i, j int) bool {
+	if i < 0 {
+		return false
+	}
+	stored, ok := set.shards[i].unsafeSet.Get(old)
+	if !ok {
+		return false
+	}
+	if j < 0 {
+		_, err := set.index(new)
+		panic(err)
+	}
+	if err := set.pin(reflect.TypeOf(new)); err != nil {
+		panic(err)
+	}
+	set.shards[i].unsafeSet.Remove(stored)
+	set.shards[j].unsafeSet.Add(new)
+	return true
+}
+
+// UpdateElem replaces the element of the set equal to val, if
+// any, by the result of f called with it, see Replace.
+//
+// As the shard of the result isn't known in advance, f runs with
+// all shards locked, it must not access the set.
+func (set *ShardedSet) UpdateElem(val interface{}, f func(cur interface{}) interface{}) {
+	i, err := set.index(val)
+	if err != nil {
+		return
+	}
+	defer set.lockAll()()
+	cur, ok := set.shards[i].unsafeSet.Get(val)
+	if !ok {
+		return
+	}
+	next := f(cur)
+	j, err := set.index(next)
+	if err != nil {
+		panic(err)
+	}
+	set.replaceLocked(cur, next, i, j)
+}
+
+// Update calls f with a view of the set and applies the changes
+// made through it at once when f returns, see SetTx. Nothing is
+// applied if f panics.
+//
+// f runs with all shards locked, it must not access the set.
+func (set *ShardedSet) Update(f func(tx SetTx)) {
+	defer set.lockAll()()
+	base := lockedShards{set}
+	tx := newSetTx(base, &set.shards[0].unsafeSet, set.typ.Load().(shardType).typ)
+	tx.run(f, func(val interface{}) {
+		set.shards[base.shard(val)].unsafeSet.Remove(val)
+	}, func(val interface{}) {
+		if err := set.pin(reflect.TypeOf(val)); err != nil {
+			panic(err)
+		}
+		set.shards[base.shard(val)].unsafeSet.Add(val)
+	})
+}
+
+// lockedShards reads a ShardedSet whose shards are locked by the
+// caller.
+type lockedShards struct {
+	*ShardedSet
+}
+
+// shard returns the shard of val, which the caller knows is hashable.
+func (set lockedShards) shard(val interface{}) int {
+	i, _ := set.index(val)
+	return i
+}
+
+func (set lockedShards) Contains(vals ...interface{}) bool {
+	for _, v := range vals {
+		i, err := set.index(v)
+		if err != nil || !set.shards[i].unsafeSet.Contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (set lockedShards) Each(f func(elem interface{}) bool) {
+	for _, s := range set.shards {
+		if s.unsafeSet.Any(f) {
+			return
+		}
+	}
+}
+
+func (set lockedShards) Size() int {
+	n := 0
+	for _, s := range set.shards {
+		n += s.unsafeSet.Size()
+	}
+	return n
+}
+
+// Any reports whether pred returns true for at least
+// one element of the set. It stops at the first such
+// element.
+func (set *ShardedSet) Any(pred func(elem interface{}) bool) bool {
+	defer set.rlockAll()()
+	for _, s := range set.shards {
+		if s.unsafeSet.Any(pred) {
+			return true
+		}
+	}
+	return false
+}
+
+// All reports whether pred returns true for every
+// element of the set.
+func (set *ShardedSet) All(pred func(elem interface{}) bool) bool {
+	return !set.Any(func(elem interface{}) bool {
+		return !pred(elem)
+	})
+}
+
+// None reports whether pred returns false for every
+// element of the set.
+func (set *ShardedSet) None(pred func(elem interface{}) bool) bool {
+	return !set.Any(pred)
+}
+
+// Cardinality Returns the number of elements in the set.
+// The shards are counted one at a time, so the count may
+// be off by elements changed meanwhile.
+func (set *ShardedSet) Cardinality() int {
+	n := 0
+	for _, s := range set.shards {
+		n += s.Size()
+	}
+	return n
+}
+
+// Size Returns the number of elements in the set, see
+// Cardinality.
+func (set *ShardedSet) Size() int {
+	return set.Cardinality()
+}
+
+// Clear removes all elements from the set, leaving
+// the empty set.
+func (set *ShardedSet) Clear() {
+	defer set.lockAll()()
+	for _, s := range set.shards {
+		s.clear()
+	}
+	set.typ.Store(shardType{})
+}
+
+// Grow makes room for at least n more elements, spread
+// evenly over the shards.
+func (set *ShardedSet) Grow(n int) {
+	if n <= 0 {
+		return
+	}
+	per := n/len(set.shards) + 1
+	for _, s := range set.shards {
+		s.Grow(per)
+	}
+}
+
+// Clone returns a clone of the set with as many shards.
+// Like the Clone of a ThreadSafeSet, it shares the elements
+// of each shard until either set changes the shard.
+func (set *ShardedSet) Clone() Set {
+	defer set.lockAll()()
+	clone := &ShardedSet{shards: make([]*ThreadSafeSet, len(set.shards)), hasher: set.hasher}
+	for i, s := range set.shards {
+		c := s.cowClone()
+		clone.shards[i] = c.ToThreadSafe()
+	}
+	clone.typ.Store(set.typ.Load())
+	return clone
+}
+
+// Combinations returns an Iterator over all subsets of this
+// set with k elements, which are built lazily as they are
+// received.
+func (set *ShardedSet) Combinations(k int) *Iterator {
+	return combinationsIterator(set.ToSlice(), k, set.newSubset)
+}
+
+// Contains returns whether the given items
+// are all in the set.
+func (set *ShardedSet) Contains(val ...interface{}) bool {
+	idx, unlock := set.lockFor(val, false)
+	defer unlock()
+	for k, v := range val {
+		if i := idx[k]; i < 0 || !set.shards[i].unsafeSet.Contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the element of the set equal to val, which
+// may differ from val in the data its Hash and Equal ignore, and
+// whether there is one.
+func (set *ShardedSet) Get(val interface{}) (interface{}, bool) {
+	i, err := set.index(val)
+	if err != nil {
+		return nil, false
+	}
+	return set.shards[i].Get(val)
+}
+
+// Difference returns the difference between this set
+// and other. The returned set will contain
+// all elements of this set that are not also
+// elements of other.
+func (set *ShardedSet) Difference(other Set) Set {
+	return set.adopt(set.snapshot().Difference(other))
+}
+
+// Diff returns the elements that must be added to and removed
+// from the set to turn it into other, see ThreadUnsafeSet.Diff.
+func (set *ShardedSet) Diff(other Set) (added Set, removed Set) {
+	a, r := set.snapshot().Diff(other)
+	return set.adopt(a), set.adopt(r)
+}
+
+// Apply applies cs to the set with all shards locked, so that no
+// one sees the set partially changed, see ThreadUnsafeSet.Apply.
+func (set *ShardedSet) Apply(cs ChangeSet) {
+	var adds, removes *ThreadUnsafeSet
+	for _, c := range []struct {
+		s   Set
+		dst **ThreadUnsafeSet
+	}{{cs.Adds, &adds}, {cs.Removes, &removes}} {
+		if c.s == nil {
+			like := set.like()
+			*c.dst = &like
+			continue
+		}
+		o, err := set.operand(c.s)
+		if err != nil {
+			panic(err)
+		}
+		*c.dst = o
+	}
+	defer set.lockAll()()
+	if adds.Size() > 0 {
+		if err := set.pin(adds.typ); err != nil {
+			panic(err)
+		}
+	}
+	// The changes are held by sets like the shards, so the shards
+	// can't fail to apply them.
+	addParts, removeParts := set.split(adds), set.split(removes)
+	for i, s := range set.shards {
+		if err := s.unsafeSet.apply(ChangeSet{Adds: addParts[i], Removes: removeParts[i]}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// DifferenceContext is like Difference, but aborts with an
+// *OperationError once ctx is done.
+func (set *ShardedSet) DifferenceContext(ctx context.Context, other Set) (Set, error) {
+	ret, err := set.snapshot().DifferenceContext(ctx, other)
+	if err != nil {
+		return nil, err
+	}
+	return set.adopt(ret), nil
+}
+
+// DifferenceCardinality returns the number of elements of
+// the difference of this set and other, without building
+// that set.
+func (set *ShardedSet) DifferenceCardinality(other Set) int {
+	return set.snapshot().DifferenceCardinality(other)
+}
+
+// DifferenceWith removes all elements of other from this set.
+func (set *ShardedSet) DifferenceWith(other Set) {
+	o, err := set.operand(other)
+	if err != nil {
+		panic(err)
+	}
+	defer set.lockAll()()
+	for _, s := range set.shards {
+		s.unsafeSet.DifferenceWith(o)
+	}
+}
+
+// Filter returns a new set with the elements of this set
+// for which pred returns true.
+func (set *ShardedSet) Filter(pred func(elem interface{}) bool) Set {
+	return set.adopt(set.snapshot().Filter(pred))
+}
+
+// GroupBy partitions the set by the key keyFn returns for each
+// element, returning a new set for every key.
+func (set *ShardedSet) GroupBy(keyFn func(elem interface{}) string) map[string]Set {
+	groups := set.snapshot().GroupBy(keyFn)
+	for key, group := range groups {
+		groups[key] = set.adopt(group)
+	}
+	return groups
+}
+
+// Equal determines if two sets are equal to each
+// other.
+func (set *ShardedSet) Equal(other Set) bool {
+	return set.snapshot().Equal(other)
+}
+
+// Intersect returns a new set containing only the elements
+// that exist only in both sets.
+func (set *ShardedSet) Intersect(other Set) Set {
+	return set.adopt(set.snapshot().Intersect(other))
+}
+
+// IntersectContext is like Intersect, but aborts with an
+// *OperationError once ctx is done.
+func (set *ShardedSet) IntersectContext(ctx context.Context, other Set) (Set, error) {
+	ret, err := set.snapshot().IntersectContext(ctx, other)
+	if err != nil {
+		return nil, err
+	}
+	return set.adopt(ret), nil
+}
+
+// IntersectCardinality returns the number of elements of
+// the intersection of this set and other, without building
+// that set.
+func (set *ShardedSet) IntersectCardinality(other Set) int {
+	return set.snapshot().IntersectCardinality(other)
+}
+
+// Jaccard returns the Jaccard index of this set and other.
+func (set *ShardedSet) Jaccard(other Set) float64 {
+	return set.snapshot().Jaccard(other)
+}
+
+// Dice returns the Sørensen–Dice coefficient of this set
+// and other.
+func (set *ShardedSet) Dice(other Set) float64 {
+	return set.snapshot().Dice(other)
+}
+
+// Overlap returns the overlap coefficient of this set and
+// other.
+func (set *ShardedSet) Overlap(other Set) float64 {
+	return set.snapshot().Overlap(other)
+}
+
+// IntersectWith removes all elements that are not in other
+// from this set.
+func (set *ShardedSet) IntersectWith(other Set) {
+	o, err := set.operand(other)
+	if err != nil {
+		panic(err)
+	}
+	defer set.lockAll()()
+	for _, s := range set.shards {
+		s.unsafeSet.IntersectWith(o)
+	}
+}
+
+// IsDisjoint determines if this set and the other set
+// have no elements in common.
+func (set *ShardedSet) IsDisjoint(other Set) bool {
+	return set.snapshot().IsDisjoint(other)
+}
+
+// Overlaps determines if this set and the other set
+// have at least one element in common.
+func (set *ShardedSet) Overlaps(other Set) bool {
+	return !set.IsDisjoint(other)
+}
+
+// IsProperSubset determines if every element in this set is in
+// the other set but the two sets are not equal.
+func (set *ShardedSet) IsProperSubset(other Set) bool {
+	return set.snapshot().IsProperSubset(other)
+}
+
+// IsProperSuperset determines if every element in the other set
+// is in this set but the two sets are not equal.
+func (set *ShardedSet) IsProperSuperset(other Set) bool {
+	return set.snapshot().IsProperSuperset(other)
+}
+
+// IsSubset determines if every element in this set is in
+// the other set.
+func (set *ShardedSet) IsSubset(other Set) bool {
+	return set.snapshot().IsSubset(other)
+}
+
+// IsSuperset determines if every element in the other set
+// is in this set.
+func (set *ShardedSet) IsSuperset(other Set) bool {
+	return set.snapshot().IsSuperset(other)
+}
+
+// IsSubsetWithin determines if at most k elements of this
+// set are missing from the other set.
+func (set *ShardedSet) IsSubsetWithin(other Set, k int) bool {
+	return set.snapshot().IsSubsetWithin(other, k)
+}
+
+// IsSupersetWithin determines if at most k elements of the
+// other set are missing from this set.
+func (set *ShardedSet) IsSupersetWithin(other Set, k int) bool {
+	return set.snapshot().IsSupersetWithin(other, k)
+}
+
+// Each iterates over elements and executes the passed func against each element.
+// If passed func returns true, stop iteration at the time.
+//
+// All shards are read-locked while the func runs, so it must not
+// modify the set.
+func (set *ShardedSet) Each(cb func(elem interface{}) bool) {
+	set.Any(cb)
+}
+
+// EachErr iterates over elements and executes the passed func against each element.
+// If passed func returns an error, stop iteration and return that error.
+func (set *ShardedSet) EachErr(cb func(elem interface{}) error) error {
+	defer set.rlockAll()()
+	for _, s := range set.shards {
+		if err := s.unsafeSet.EachErr(cb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Iter returns a channel of elements that you can
+// range over.
+//
+// The elements are snapshotted before Iter returns, so the set is
+// not locked while the channel is consumed.
+func (set *ShardedSet) Iter() <-chan interface{} {
+	return set.IterBuffered(0)
+}
+
+// IterBuffered is like Iter, but the returned channel is buffered
+// for n elements, letting the producer run ahead of a slow consumer.
+func (set *ShardedSet) IterBuffered(n int) <-chan interface{} {
+	return iterSlice(set.ToSlice(), n)
+}
+
+// Iterator returns an Iterator object that you can
+// use to range over a snapshot of the set.
+func (set *ShardedSet) Iterator() *Iterator {
+	return sliceIterator(set.ToSlice())
+}
+
+// Cursor returns a pull-style Cursor over a snapshot of the set.
+func (set *ShardedSet) Cursor() *Cursor {
+	return &Cursor{objs: set.ToSlice()}
+}
+
+// MinBy returns the smallest element of the set according
+// to less, or false if the set is empty.
+func (set *ShardedSet) MinBy(less func(a, b interface{}) bool) (interface{}, bool) {
+	return set.snapshot().MinBy(less)
+}
+
+// MaxBy returns the largest element of the set according
+// to less, or false if the set is empty.
+func (set *ShardedSet) MaxBy(less func(a, b interface{}) bool) (interface{}, bool) {
+	return set.snapshot().MaxBy(less)
+}
+
+// Min returns the smallest element of the set, or false if
+// the set is empty. Strings are compared byte by byte.
+func (set *ShardedSet) Min() (interface{}, bool) {
+	return set.snapshot().Min()
+}
+
+// Max returns the largest element of the set, or false if
+// the set is empty. Strings are compared byte by byte.
+func (set *ShardedSet) Max() (interface{}, bool) {
+	return set.snapshot().Max()
+}
+
+// Freeze returns an immutable copy of the set.
+func (set *ShardedSet) Freeze() FrozenSet {
+	return freeze(*set.snapshot())
+}
+
+// Hash returns a hash of the elements of the set, which makes
+// sets Hashable, so that they can be elements of other sets.
+func (set *ShardedSet) Hash() string {
+	return setHash(set.ToSlice())
+}
+
+// PowerSet returns a new set with all subsets of this set.
+// It panics if the set has more than PowerSetLimit elements.
+func (set *ShardedSet) PowerSet() Set {
+	ret := newThreadSafeSet()
+	powerSet(&ret, set.ToSlice(), set.newSubset)
+	return &ret
+}
+
+// PowerSetIterator returns an Iterator over all subsets of
+// this set, which are built lazily as they are received.
+func (set *ShardedSet) PowerSetIterator() *Iterator {
+	return powerSetIterator(set.ToSlice(), set.newSubset)
+}
+
+// newSubset returns a new, empty set of the same kind for a subset.
+func (set *ShardedSet) newSubset() Set {
+	return set.emptyLike()
+}
+
+// RandomSample returns n distinct elements of the set, selected
+// uniformly at random by r, or all elements in random order if
+// the set has no more than n elements.
+func (set *ShardedSet) RandomSample(n int, r *rand.Rand) []interface{} {
+	return set.snapshot().RandomSample(n, r)
+}
+
+// RandomElement returns an element of the set selected uniformly
+// at random by r, or false if the set is empty.
+func (set *ShardedSet) RandomElement(r *rand.Rand) (interface{}, bool) {
+	return set.snapshot().RandomElement(r)
+}
+
+// Remove remove a single element from the set.
+func (set *ShardedSet) Remove(i interface{}) {
+	if err := set.TryRemove(i); err != nil {
+		panic(err)
+	}
+}
+
+// TryRemove is like Remove, but returns an error instead of
+// panicking if val can't be hashed.
+func (set *ShardedSet) TryRemove(val interface{}) error {
+	i, err := set.index(val)
+	if err != nil {
+		return err
+	}
+	return set.shards[i].TryRemove(val)
+}
+
+// RemoveAll removes all given elements from the set, with the
+// shards of all of them locked.
+func (set *ShardedSet) RemoveAll(vals ...interface{}) {
+	idx, unlock := set.lockFor(vals, true)
+	defer unlock()
+	for k, v := range vals {
+		if idx[k] < 0 {
+			_, err := set.index(v)
+			panic(err)
+		}
+		set.shards[idx[k]].unsafeSet.Remove(v)
+	}
+}
+
+// RemoveIf removes all elements for which pred returns true
+// and returns the number of elements removed.
+//
+// The shards are locked one at a time while pred runs, so
+// pred must not access the set.
+func (set *ShardedSet) RemoveIf(pred func(elem interface{}) bool) int {
+	n := 0
+	for _, s := range set.shards {
+		n += s.RemoveIf(pred)
+	}
+	return n
+}
+
+// RetainIf removes all elements for which pred returns false
+// and returns the number of elements removed, see RemoveIf.
+func (set *ShardedSet) RetainIf(pred func(elem interface{}) bool) int {
+	n := 0
+	for _, s := range set.shards {
+		n += s.RetainIf(pred)
+	}
+	return n
+}
+
+// String provides a convenient string representation
+// of the current state of the set.
+func (set *ShardedSet) String() string {
+	return setString(set.snapshot(), "goset.ShardedSet")
+}
+
+// SymmetricDifference returns a new set with all elements which are
+// in either this set or the other set but not in both.
+func (set *ShardedSet) SymmetricDifference(other Set) Set {
+	return set.adopt(set.snapshot().SymmetricDifference(other))
+}
+
+// SymmetricDifferenceWith removes the elements of other that
+// are in this set and adds those that are not.
+func (set *ShardedSet) SymmetricDifferenceWith(other Set) {
+	o, err := set.operand(other)
+	if err != nil {
+		panic(err)
+	}
+	defer set.lockAll()()
+	if o.Size() > 0 {
+		if err := set.pin(o.typ); err != nil {
+			panic(err)
+		}
+	}
+	for i, p := range set.split(o) {
+		set.shards[i].unsafeSet.SymmetricDifferenceWith(p)
+	}
+}
+
+// Union returns a new set with all elements in both sets.
+func (set *ShardedSet) Union(other Set) Set {
+	return set.adopt(set.snapshot().Union(other))
+}
+
+// UnionContext is like Union, but aborts with an
+// *OperationError once ctx is done.
+func (set *ShardedSet) UnionContext(ctx context.Context, other Set) (Set, error) {
+	ret, err := set.snapshot().UnionContext(ctx, other)
+	if err != nil {
+		return nil, err
+	}
+	return set.adopt(ret), nil
+}
+
+// UnionCardinality returns the number of elements of the
+// union of this set and other, without building that set.
+func (set *ShardedSet) UnionCardinality(other Set) int {
+	return set.snapshot().UnionCardinality(other)
+}
+
+// UnionWith adds all elements of other to this set.
+func (set *ShardedSet) UnionWith(other Set) {
+	o, err := set.operand(other)
+	if err != nil {
+		panic(err)
+	}
+	defer set.lockAll()()
+	set.unionWith(o)
+}
+
+// Pop removes and returns an arbitrary item from the set.
+func (set *ShardedSet) Pop() (interface{}, bool) {
+	for _, s := range set.shards {
+		if obj, ok := s.Pop(); ok {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// PopN removes and returns up to n arbitrary items from the set.
+func (set *ShardedSet) PopN(n int) []interface{} {
+	var ret []interface{}
+	for _, s := range set.shards {
+		if len(ret) >= n {
+			break
+		}
+		ret = append(ret, s.PopN(n-len(ret))...)
+	}
+	return ret
+}
+
+// PopIf removes and returns the first item found that
+// satisfies pred, or false if there is no such item.
+func (set *ShardedSet) PopIf(pred func(elem interface{}) bool) (interface{}, bool) {
+	for _, s := range set.shards {
+		if obj, ok := s.PopIf(pred); ok {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// try runs op on a snapshot of the set and returns its result
+// spread over as many shards as set.
+func (set *ShardedSet) try(other Set, op func(set *ThreadUnsafeSet, other Set) (Set, error)) (Set, error) {
+	ret, err := op(set.snapshot(), other)
+	if err != nil {
+		return nil, err
+	}
+	return set.adopt(ret), nil
+}
+
+// TryUnion is like Union, but returns an error instead of
+// panicking if the set can't hold the elements of other.
+func (set *ShardedSet) TryUnion(other Set) (Set, error) {
+	return set.try(other, (*ThreadUnsafeSet).TryUnion)
+}
+
+// TryIntersect is like Intersect, but returns an error instead
+// of panicking if the set can't hold the elements of other.
+func (set *ShardedSet) TryIntersect(other Set) (Set, error) {
+	return set.try(other, (*ThreadUnsafeSet).TryIntersect)
+}
+
+// TryDifference is like Difference, but returns an error instead
+// of panicking if the set can't hold the elements of other.
+func (set *ShardedSet) TryDifference(other Set) (Set, error) {
+	return set.try(other, (*ThreadUnsafeSet).TryDifference)
+}
+
+// TrySymmetricDifference is like SymmetricDifference, but returns
+// an error instead of panicking if the set can't hold the elements
+// of other.
+func (set *ShardedSet) TrySymmetricDifference(other Set) (Set, error) {
+	return set.try(other, (*ThreadUnsafeSet).TrySymmetricDifference)
+}
+
+// ToSlice returns the members of the set as a slice.
+func (set *ShardedSet) ToSlice() []interface{} {
+	defer set.rlockAll()()
+	n := 0
+	for _, s := range set.shards {
+		n += s.unsafeSet.Size()
+	}
+	keys := make([]interface{}, 0, n)
+	for _, s := range set.shards {
+		s.unsafeSet.store.each(func(elem interface{}) bool {
+			keys = append(keys, elem)
+			return false
+		})
+	}
+	return keys
+}
+
+// ToSortedSlice returns the members of the set as a slice
+// sorted by less, see Set.ToSortedSlice.
+func (set *ShardedSet) ToSortedSlice(less func(a, b interface{}) bool) []interface{} {
+	return set.snapshot().ToSortedSlice(less)
+}
+
+// ToMap returns a copy of the members of the set as the
+// keys of a map.
+func (set *ShardedSet) ToMap() map[interface{}]struct{} {
+	return set.snapshot().ToMap()
+}
+
+// MarshalJSON will marshal the set into a JSON-based representation.
+func (set *ShardedSet) MarshalJSON() ([]byte, error) {
+	return set.snapshot().MarshalJSON()
+}
+
+// MarshalJSONSorted is like MarshalJSON, but emits the
+// elements in the order of ToSortedSlice(nil).
+func (set *ShardedSet) MarshalJSONSorted() ([]byte, error) {
+	return marshalSorted(set.ToSlice())
+}
+
+// UnmarshalJSON replaces the elements of the set by those of a JSON
+// array, see ThreadSafeSet.UnmarshalJSON.
+func (set *ShardedSet) UnmarshalJSON(b []byte) error {
+	decoded := set.like()
+	decoded.typ = set.typ.Load().(shardType).typ
+	if err := decoded.decodeJSON(b); err != nil {
+		return err
+	}
+	defer set.lockAll()()
+	for _, s := range set.shards {
+		s.clear()
+	}
+	set.typ.Store(shardType{})
+	set.unionWith(&decoded)
+	return nil
+}
+
+// Save writes the set to w in a compact binary format, which
+// Load reads.
+func (set *ShardedSet) Save(w io.Writer) error {
+	return set.snapshot().Save(w)
+}
+
+// SaveContext is like Save, but aborts with an *OperationError
+// once ctx is done.
+func (set *ShardedSet) SaveContext(ctx context.Context, w io.Writer) error {
+	return set.snapshot().SaveContext(ctx, w)
+}
+
+// AddToSketch adds the elements of the set to h.
+func (set *ShardedSet) AddToSketch(h sketch.Sketch) {
+	set.snapshot().AddToSketch(h)
+}
+
+// ToBloomFilter returns a compact Bloom filter of the elements of
+// the set with the given false positive rate.
+func (set *ShardedSet) ToBloomFilter(fpRate float64) *BloomFilter {
+	return set.snapshot().ToBloomFilter(fpRate)
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_ShardedSet(t *testing.T) {
+	var s Set = NewShardedSet(4, 1, 2, 3)
+	if s.(*ShardedSet).Shards() != 4 || s.Size() != 3 || !s.Contains(1, 2, 3) {
+		t.Errorf("Expected 1, 2 and 3 in 4 shards, got %v", s)
+	}
+	if !s.Equal(NewSet(1, 2, 3)) || !NewSet(1, 2, 3).Equal(s) {
+		t.Errorf("Expected the sharded set to equal a plain one")
+	}
+	if _, err := s.TryAdd("a"); err == nil {
+		t.Errorf("Expected an error for an element of the wrong type")
+	}
+
+	u := s.Union(NewSet(4))
+	if _, ok := u.(*ShardedSet); !ok || !u.Equal(NewSet(1, 2, 3, 4)) {
+		t.Errorf("Expected a sharded union of 1 to 4, got %v", u)
+	}
+	s.UnionWith(NewSet(4, 5))
+	s.IntersectWith(NewSet(2, 3, 4, 5, 6))
+	s.DifferenceWith(NewSet(5))
+	s.SymmetricDifferenceWith(NewSet(4, 7))
+	if !s.Equal(NewSet(2, 3, 7)) {
+		t.Errorf("Expected 2, 3 and 7, got %v", s)
+	}
+	s.Apply(NewChangeSet(NewSet(8), NewSet(2)))
+	if !s.Equal(NewSet(3, 7, 8)) {
+		t.Errorf("Expected 3, 7 and 8 after Apply, got %v", s)
+	}
+
+	c := s.Clone()
+	c.Remove(3)
+	if !s.Contains(3) || c.Contains(3) {
+		t.Errorf("Expected the clone to be independent of the set")
+	}
+	if s.CompareAndAdd(9, 8) || !s.CompareAndAdd(9, 10) {
+		t.Errorf("Expected CompareAndAdd to add 9 only if 10 is absent")
+	}
+	if n := s.RemoveIf(func(elem interface{}) bool { return elem.(int) > 7 }); n != 2 || s.Size() != 2 {
+		t.Errorf("Expected 8 and 9 to be removed, got %v", s)
+	}
+	s.Clear()
+	if s.Size() != 0 || !s.Add("a") {
+		t.Errorf("Expected Clear to reset the element type")
+	}
+}
+
+func Test_ShardedSetConcurrent(t *testing.T) {
+	s := NewShardedSet(0)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < N; i++ {
+				s.Add(g*N + i)
+				s.Contains(i)
+				if i%10 == 0 {
+					s.Remove(g*N + i)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if s.Size() != 8*N*9/10 {
+		t.Errorf("Expected %d elements, got %d", 8*N*9/10, s.Size())
+	}
+}
+
+func Test_ShardedSetWithHasher(t *testing.T) {
+	fold := HasherFunc(func(elem interface{}) string {
+		return strings.ToLower(elem.(string))
+	})
+	s := NewShardedSet(16, WithHasher(fold), "a", "A", "b", "B", "c", "C", "d", "D")
+	if s.Size() != 4 || !s.Contains("a", "B") {
+		t.Errorf("Expected the shards to be picked by the hasher, got %v", s)
+	}
+	if c := s.Clone(); c.Add("c") || c.Size() != 4 {
+		t.Errorf("Expected the clone to be routed by the hasher, got %v", c)
+	}
+
+	for _, opt := range []Option{WithDeepHashing(), WithTaggedHashing()} {
+		deep := NewShardedSet(4, opt)
+		if added, err := deep.TryAdd([]int{1}); !added || err != nil {
+			t.Errorf("Expected a hasher to make unhashable elements storable, got %v, %v", added, err)
+		}
+		if !deep.Contains([]int{1}) {
+			t.Errorf("Expected the element to be found, got %v", deep)
+		}
+	}
+}
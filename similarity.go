@@ -0,0 +1,110 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+// similarity returns sim of the sizes of the set, of other and of
+// their intersection, which is counted without building it. Two empty
+// sets are equal, so their similarity is 1.
+func (set *ThreadUnsafeSet) similarity(other Set, sim func(a, b, common int) float64) float64 {
+	o := set.unsafeOf(other)
+	a, b := set.Size(), o.Size()
+	if a == 0 && b == 0 {
+		return 1
+	}
+	return sim(a, b, set.IntersectCardinality(o))
+}
+
+func jaccard(a, b, common int) float64 {
+	return float64(common) / float64(a+b-common)
+}
+
+func dice(a, b, common int) float64 {
+	return 2 * float64(common) / float64(a+b)
+}
+
+func overlap(a, b, common int) float64 {
+	if a > b {
+		a = b
+	}
+	if a == 0 {
+		return 0
+	}
+	return float64(common) / float64(a)
+}
+
+// Jaccard returns the Jaccard index of the set and other, the size of
+// their intersection divided by the size of their union.
+func (set *ThreadUnsafeSet) Jaccard(other Set) float64 {
+	return set.similarity(other, jaccard)
+}
+
+// Dice returns the Sørensen–Dice coefficient of the set and other, twice
+// the size of their intersection divided by the sum of their sizes.
+func (set *ThreadUnsafeSet) Dice(other Set) float64 {
+	return set.similarity(other, dice)
+}
+
+// Overlap returns the overlap coefficient of the set and other, the size
+// of their intersection divided by the size of the smaller set. It is 1
+// if one set is a subset of the other, but 0 if only one is empty.
+func (set *ThreadUnsafeSet) Overlap(other Set) float64 {
+	return set.similarity(other, overlap)
+}
+
+// similarity returns sim of the sizes of the set, of other and of their
+// intersection, see ThreadUnsafeSet.similarity.
+func (set *ThreadSafeSet) similarity(other Set, sim func(a, b, common int) float64) float64 {
+	o := set.threadSafeOf(other)
+
+	unlock := set.rlockWith(o)
+	ret := set.unsafeSet.similarity(&o.unsafeSet, sim)
+	unlock()
+	return ret
+}
+
+// Jaccard returns the Jaccard index of the set and other, see
+// ThreadUnsafeSet.Jaccard.
+func (set *ThreadSafeSet) Jaccard(other Set) float64 {
+	return set.similarity(other, jaccard)
+}
+
+// Dice returns the Sørensen–Dice coefficient of the set and other, see
+// ThreadUnsafeSet.Dice.
+func (set *ThreadSafeSet) Dice(other Set) float64 {
+	return set.similarity(other, dice)
+}
+
+// Overlap returns the overlap coefficient of the set and other, see
+// ThreadUnsafeSet.Overlap.
+func (set *ThreadSafeSet) Overlap(other Set) float64 {
+	return set.similarity(other, overlap)
+}
+
+// Jaccard returns the Jaccard index of the set and other, see
+// ThreadUnsafeSet.Jaccard.
+func (set FrozenSet) Jaccard(other FrozenSet) float64 {
+	return set.elems().Jaccard(other.elems())
+}
+
+// Dice returns the Sørensen–Dice coefficient of the set and other, see
+// ThreadUnsafeSet.Dice.
+func (set FrozenSet) Dice(other FrozenSet) float64 {
+	return set.elems().Dice(other.elems())
+}
+
+// Overlap returns the overlap coefficient of the set and other, see
+// ThreadUnsafeSet.Overlap.
+func (set FrozenSet) Overlap(other FrozenSet) float64 {
+	return set.elems().Overlap(other.elems())
+}
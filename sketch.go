@@ -0,0 +1,51 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "github.com/b1tkeeper/goset/sketch"
+
+// elemKey returns the bytes that identify elem in a sketch: its hash
+// string, or its hash by the Hasher of a set WithHasher.
+func (set *ThreadUnsafeSet) elemKey(elem interface{}) []byte {
+	if s, ok := unwrapStore(set.store, false).(*hasherStore); ok {
+		return []byte(s.h.Hash(elem))
+	}
+	hash, _ := calcHash(elem)
+	return []byte(hash)
+}
+
+// AddToSketch adds the elements of the set to h by their hash strings,
+// so that they are summarized like the strings themselves, which can be
+// added to h as well. Elements of a set WithMixedTypes with equal hash
+// strings, like 1 and "1", are summarized as one.
+func (set *ThreadUnsafeSet) AddToSketch(h sketch.Sketch) {
+	set.store.each(func(elem interface{}) bool {
+		h.Add(set.elemKey(elem))
+		return false
+	})
+}
+
+// AddToSketch adds the elements of the set to h, see
+// ThreadUnsafeSet.AddToSketch.
+func (set *ThreadSafeSet) AddToSketch(h sketch.Sketch) {
+	set.RLock()
+	defer set.RUnlock()
+	set.unsafeSet.AddToSketch(h)
+}
+
+// AddToSketch adds the elements of the set to h, see
+// ThreadUnsafeSet.AddToSketch.
+func (set FrozenSet) AddToSketch(h sketch.Sketch) {
+	set.elems().AddToSketch(h)
+}
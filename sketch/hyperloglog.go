@@ -0,0 +1,200 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sketch provides probabilistic summaries of sets, which answer
+// questions about a set approximately in a fraction of its memory.
+//
+// A HyperLogLog estimates the number of distinct elements it was fed,
+// standalone or from a goset.Set:
+//
+//	h := sketch.NewHyperLogLog(14)
+//	set.AddToSketch(h)
+//	h.AddString("another")
+//	n := h.Estimate()
+//
+// A MinHash computes a signature of a set, whose similarity to another
+// signature estimates the Jaccard similarity of the sets.
+package sketch
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Sketch is a summary of the elements added to it.
+type Sketch interface {
+	Add(elem []byte)
+}
+
+// Bounds of the precision of a HyperLogLog.
+const (
+	MinPrecision = 4
+	MaxPrecision = 18
+)
+
+// HyperLogLog estimates the number of distinct elements added to it in
+// 2^precision bytes, with a standard error of about 1.04/√2^precision,
+// e.g. 0.8% for a precision of 14 in 16 KiB. It is not safe for
+// concurrent use.
+type HyperLogLog struct {
+	p         uint8
+	registers []uint8
+}
+
+// NewHyperLogLog returns an empty HyperLogLog of the given precision,
+// which is clamped to [MinPrecision, MaxPrecision].
+func NewHyperLogLog(precision uint8) *HyperLogLog {
+	if precision < MinPrecision {
+		precision = MinPrecision
+	}
+	if precision > MaxPrecision {
+		precision = MaxPrecision
+	}
+	return &HyperLogLog{p: precision, registers: make([]uint8, 1<<precision)}
+}
+
+// Precision returns the precision of h.
+func (h *HyperLogLog) Precision() uint8 {
+	return h.p
+}
+
+// hash64 returns a 64-bit hash of elem: FNV-1a, with the finalizer of
+// MurmurHash3 to spread its bits, which HyperLogLog relies on.
+func hash64(elem []byte) uint64 {
+	hash := uint64(14695981039346656037)
+	for _, b := range elem {
+		hash ^= uint64(b)
+		hash *= 1099511628211
+	}
+	return mix64(hash)
+}
+
+// mix64 is the finalizer of MurmurHash3, which spreads the bits of h.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// Add adds elem to h.
+func (h *HyperLogLog) Add(elem []byte) {
+	hash := hash64(elem)
+	i := hash >> (64 - h.p)
+	rho := uint8(bits.LeadingZeros64(hash<<h.p|1<<(h.p-1)) + 1)
+	if rho > h.registers[i] {
+		h.registers[i] = rho
+	}
+}
+
+// AddString adds elem to h.
+func (h *HyperLogLog) AddString(elem string) {
+	h.Add([]byte(elem))
+}
+
+// Estimate returns the estimated number of distinct elements added to h.
+func (h *HyperLogLog) Estimate() uint64 {
+	m := float64(len(h.registers))
+	sum, zeros := 0.0, 0
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	var alpha float64
+	switch len(h.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Linear counting is more accurate for small cardinalities.
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// Merge adds the elements added to other to h, as if they had been
+// added to h. Both must have the same precision.
+func (h *HyperLogLog) Merge(other *HyperLogLog) error {
+	if h.p != other.p {
+		return fmt.Errorf("sketch: can't merge a HyperLogLog of precision %d into one of precision %d", other.p, h.p)
+	}
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+	return nil
+}
+
+// Clone returns a copy of h.
+func (h *HyperLogLog) Clone() *HyperLogLog {
+	registers := make([]uint8, len(h.registers))
+	copy(registers, h.registers)
+	return &HyperLogLog{p: h.p, registers: registers}
+}
+
+// Reset removes all elements from h.
+func (h *HyperLogLog) Reset() {
+	for i := range h.registers {
+		h.registers[i] = 0
+	}
+}
+
+// EstimateUnionCardinality returns the estimated number of distinct
+// elements added to a or b, which must have the same precision.
+func EstimateUnionCardinality(a, b *HyperLogLog) (uint64, error) {
+	union := a.Clone()
+	if err := union.Merge(b); err != nil {
+		return 0, err
+	}
+	return union.Estimate(), nil
+}
+
+// hllVersion is the version of the binary form of a HyperLogLog.
+const hllVersion = 1
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (h *HyperLogLog) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 2, 2+len(h.registers))
+	b[0], b[1] = hllVersion, h.p
+	return append(b, h.registers...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing h
+// with the HyperLogLog encoded by MarshalBinary.
+func (h *HyperLogLog) UnmarshalBinary(b []byte) error {
+	if len(b) < 2 || b[0] != hllVersion {
+		return errors.New("sketch: not an encoded HyperLogLog")
+	}
+	p := b[1]
+	if p < MinPrecision || p > MaxPrecision || len(b)-2 != 1<<p {
+		return errors.New("sketch: corrupt HyperLogLog")
+	}
+	h.p = p
+	h.registers = append([]uint8(nil), b[2:]...)
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sketch
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func Test_HyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 10, 1000, 100000} {
+		h := NewHyperLogLog(14)
+		for i := 0; i < n; i++ {
+			h.AddString(strconv.Itoa(i))
+			h.AddString(strconv.Itoa(i))
+		}
+		if got := float64(h.Estimate()); math.Abs(got-float64(n)) > 0.03*float64(n)+1 {
+			t.Errorf("Expected about %d distinct elements, got %v", n, got)
+		}
+	}
+
+	a, b := NewHyperLogLog(12), NewHyperLogLog(12)
+	for i := 0; i < 20000; i++ {
+		a.AddString(strconv.Itoa(i))
+		b.AddString(strconv.Itoa(i + 10000))
+	}
+	if n, err := EstimateUnionCardinality(a, b); err != nil || math.Abs(float64(n)-30000) > 1500 {
+		t.Errorf("Expected a union of about 30000 elements, got %d, %v", n, err)
+	}
+	if a.Estimate() > 21000 {
+		t.Errorf("Expected EstimateUnionCardinality to leave its arguments alone")
+	}
+	if _, err := EstimateUnionCardinality(a, NewHyperLogLog(10)); err == nil {
+		t.Errorf("Expected an error for different precisions")
+	}
+
+	encoded, _ := a.MarshalBinary()
+	var decoded HyperLogLog
+	if err := decoded.UnmarshalBinary(encoded); err != nil || decoded.Estimate() != a.Estimate() {
+		t.Errorf("Expected the HyperLogLog to survive encoding, got %v", err)
+	}
+	if err := decoded.UnmarshalBinary(encoded[:100]); err == nil {
+		t.Errorf("Expected an error for a truncated HyperLogLog")
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sketch
+
+import (
+	"fmt"
+	"math"
+)
+
+// MinHash computes a signature of the elements added to it: for each of
+// its hash functions, the minimum hash of the elements. The fraction of
+// equal values in the signatures of two sets estimates their Jaccard
+// similarity, with a standard error of about 1/√k for k hash functions.
+// It is not safe for concurrent use.
+type MinHash struct {
+	mins []uint64
+}
+
+// Signature is the signature of a set computed by a MinHash.
+type Signature []uint64
+
+// NewMinHash returns a MinHash with k hash functions, at least one.
+func NewMinHash(k int) *MinHash {
+	if k < 1 {
+		k = 1
+	}
+	m := &MinHash{mins: make([]uint64, k)}
+	m.Reset()
+	return m
+}
+
+// Add adds elem to m.
+func (m *MinHash) Add(elem []byte) {
+	hash := hash64(elem)
+	for i := range m.mins {
+		// Derive hash function i by mixing hash with a seed of its own.
+		if h := mix64(hash + uint64(i+1)*0x9e3779b97f4a7c15); h < m.mins[i] {
+			m.mins[i] = h
+		}
+	}
+}
+
+// AddString adds elem to m.
+func (m *MinHash) AddString(elem string) {
+	m.Add([]byte(elem))
+}
+
+// Signature returns the signature of the elements added to m.
+func (m *MinHash) Signature() Signature {
+	return append(Signature(nil), m.mins...)
+}
+
+// Merge adds the elements added to other to m, as if they had been
+// added to m, so that m computes the signature of the union. Both must
+// have the same number of hash functions.
+func (m *MinHash) Merge(other *MinHash) error {
+	if len(m.mins) != len(other.mins) {
+		return fmt.Errorf("sketch: can't merge a MinHash of %d hash functions into one of %d", len(other.mins), len(m.mins))
+	}
+	for i, h := range other.mins {
+		if h < m.mins[i] {
+			m.mins[i] = h
+		}
+	}
+	return nil
+}
+
+// Reset removes all elements from m.
+func (m *MinHash) Reset() {
+	for i := range m.mins {
+		m.mins[i] = math.MaxUint64
+	}
+}
+
+// EstimateJaccard returns the estimated Jaccard similarity of the sets
+// of the signatures a and b, the size of their intersection divided by
+// the size of their union, from 0 for disjoint to 1 for equal sets. The
+// signatures must be computed with the same number of hash functions.
+func EstimateJaccard(a, b Signature) (float64, error) {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0, fmt.Errorf("sketch: can't compare signatures of %d and %d hash functions", len(a), len(b))
+	}
+	equal := 0
+	for i := range a {
+		if a[i] == b[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(a)), nil
+}
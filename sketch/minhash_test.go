@@ -0,0 +1,50 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sketch
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func Test_MinHash(t *testing.T) {
+	a, b := NewMinHash(256), NewMinHash(256)
+	// {0..999} and {500..1499} share 500 of 1500 elements.
+	for i := 0; i < 1000; i++ {
+		a.AddString(strconv.Itoa(i))
+		b.AddString(strconv.Itoa(i + 500))
+	}
+	j, err := EstimateJaccard(a.Signature(), b.Signature())
+	if err != nil || math.Abs(j-1.0/3) > 0.1 {
+		t.Errorf("Expected a similarity of about 1/3, got %v, %v", j, err)
+	}
+	if j, _ := EstimateJaccard(a.Signature(), a.Signature()); j != 1 {
+		t.Errorf("Expected equal sets to be similar, got %v", j)
+	}
+	if _, err := EstimateJaccard(a.Signature(), NewMinHash(8).Signature()); err == nil {
+		t.Errorf("Expected an error for signatures of different lengths")
+	}
+
+	union := NewMinHash(256)
+	for i := 0; i < 1500; i++ {
+		union.AddString(strconv.Itoa(i))
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if j, _ := EstimateJaccard(a.Signature(), union.Signature()); j != 1 {
+		t.Errorf("Expected the merged signature to be the signature of the union, got %v", j)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/b1tkeeper/goset/sketch"
+)
+
+func Test_AddToSketch(t *testing.T) {
+	s := NewSet()
+	for i := 0; i < 1000; i++ {
+		s.Add(i)
+	}
+	h := sketch.NewHyperLogLog(14)
+	s.AddToSketch(h)
+	for i := 500; i < 1500; i++ {
+		h.AddString(strconv.Itoa(i))
+	}
+	if n := h.Estimate(); n < 1450 || n > 1550 {
+		t.Errorf("Expected about 1500 distinct elements, got %d", n)
+	}
+}
+
+func Test_MinHashOfSets(t *testing.T) {
+	a, b := sketch.NewMinHash(128), sketch.NewMinHash(128)
+	NewSet("a", "b", "c", "d").AddToSketch(a)
+	NewFrozenSet("a", "b", "c", "e").AddToSketch(b)
+	if j, err := sketch.EstimateJaccard(a.Signature(), b.Signature()); err != nil || j < 0.4 || j > 0.8 {
+		t.Errorf("Expected a similarity of about 0.6, got %v, %v", j, err)
+	}
+}
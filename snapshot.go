@@ -0,0 +1,479 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+)
+
+// snapshotMagic starts the snapshots written by Save, followed by the
+// version of the format.
+const (
+	snapshotMagic   = "GSET"
+	snapshotVersion = 1
+)
+
+// Snapshot flags.
+const snapshotMixed = 1 << iota
+
+// Element tags of the snapshot format. Elements of other types are
+// tagged tagGob and gob-encoded.
+const (
+	tagInt byte = iota + 1
+	tagInt8
+	tagInt16
+	tagInt32
+	tagInt64
+	tagUint
+	tagUint8
+	tagUint16
+	tagUint32
+	tagUint64
+	tagUintptr
+	tagFloat32
+	tagFloat64
+	tagComplex64
+	tagComplex128
+	tagString
+	tagBool
+	tagTime
+	tagDuration
+	tagIP
+	tagUUID
+	tagBytes
+	tagNumber
+	tagGob = 0xff
+)
+
+var errSnapshotTruncated = errors.New("goset: unexpected end of snapshot")
+
+// snapshotWriter writes the elements of a snapshot.
+type snapshotWriter struct {
+	w interface {
+		io.Writer
+		io.ByteWriter
+		WriteString(s string) (int, error)
+	}
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (w *snapshotWriter) uvarint(v uint64) {
+	w.w.Write(w.buf[:binary.PutUvarint(w.buf[:], v)])
+}
+
+func (w *snapshotWriter) varint(v int64) {
+	w.w.Write(w.buf[:binary.PutVarint(w.buf[:], v)])
+}
+
+func (w *snapshotWriter) bytes(b []byte) {
+	w.uvarint(uint64(len(b)))
+	w.w.Write(b)
+}
+
+func (w *snapshotWriter) elem(elem interface{}) error {
+	tag := func(t byte) { w.w.WriteByte(t) }
+	switch e := elem.(type) {
+	case int:
+		tag(tagInt)
+		w.varint(int64(e))
+	case int8:
+		tag(tagInt8)
+		w.varint(int64(e))
+	case int16:
+		tag(tagInt16)
+		w.varint(int64(e))
+	case int32:
+		tag(tagInt32)
+		w.varint(int64(e))
+	case int64:
+		tag(tagInt64)
+		w.varint(e)
+	case uint:
+		tag(tagUint)
+		w.uvarint(uint64(e))
+	case uint8:
+		tag(tagUint8)
+		w.uvarint(uint64(e))
+	case uint16:
+		tag(tagUint16)
+		w.uvarint(uint64(e))
+	case uint32:
+		tag(tagUint32)
+		w.uvarint(uint64(e))
+	case uint64:
+		tag(tagUint64)
+		w.uvarint(e)
+	case uintptr:
+		tag(tagUintptr)
+		w.uvarint(uint64(e))
+	case float32:
+		tag(tagFloat32)
+		binary.Write(w.w, binary.LittleEndian, math.Float32bits(e))
+	case float64:
+		tag(tagFloat64)
+		binary.Write(w.w, binary.LittleEndian, math.Float64bits(e))
+	case complex64:
+		tag(tagComplex64)
+		binary.Write(w.w, binary.LittleEndian, [2]uint32{math.Float32bits(real(e)), math.Float32bits(imag(e))})
+	case complex128:
+		tag(tagComplex128)
+		binary.Write(w.w, binary.LittleEndian, [2]uint64{math.Float64bits(real(e)), math.Float64bits(imag(e))})
+	case string:
+		tag(tagString)
+		w.bytes([]byte(e))
+	case bool:
+		tag(tagBool)
+		if e {
+			w.w.WriteByte(1)
+		} else {
+			w.w.WriteByte(0)
+		}
+	case time.Time:
+		b, err := e.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		tag(tagTime)
+		w.bytes(b)
+	case time.Duration:
+		tag(tagDuration)
+		w.varint(int64(e))
+	case net.IP:
+		tag(tagIP)
+		w.bytes(e)
+	case [16]byte:
+		tag(tagUUID)
+		w.w.Write(e[:])
+	case []byte:
+		tag(tagBytes)
+		w.bytes(e)
+	case json.Number:
+		tag(tagNumber)
+		w.bytes([]byte(e))
+	default:
+		b, err := encodeGobElem(elem)
+		if err != nil {
+			return err
+		}
+		tag(tagGob)
+		w.bytes(b)
+	}
+	return nil
+}
+
+// Save writes the elements of the set to w in a compact, versioned
+// binary format, which Load reads. Elements of the natively supported
+// types are written as they are, others are gob-encoded, so their types
+// must be registered with gob.Register.
+func (set *ThreadUnsafeSet) Save(w io.Writer) error {
+	return set.SaveContext(context.Background(), w)
+}
+
+// SaveContext is like Save, but checks ctx between the elements it
+// writes and aborts with an *OperationError once ctx is done. w may then
+// hold the start of the snapshot, which Load rejects as truncated.
+// Progress is reported to the func registered with WithProgress.
+func (set *ThreadUnsafeSet) SaveContext(ctx context.Context, w io.Writer) error {
+	t := newOpTracker(ctx, "save", set.Size())
+	if err := t.check(); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	sw := &snapshotWriter{w: bw}
+	sw.w.WriteString(snapshotMagic)
+	sw.w.WriteByte(snapshotVersion)
+	var flags byte
+	if set.conf.mixed {
+		flags |= snapshotMixed
+	}
+	sw.w.WriteByte(flags)
+	sw.uvarint(uint64(set.Size()))
+	var err error
+	set.store.each(func(elem interface{}) bool {
+		if err = t.step(); err != nil {
+			return true
+		}
+		err = sw.elem(elem)
+		return err != nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	t.finish()
+	return nil
+}
+
+// Save writes the elements of the set to w, see ThreadUnsafeSet.Save.
+func (set *ThreadSafeSet) Save(w io.Writer) error {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.Save(w)
+}
+
+// SaveContext is like Save, see ThreadUnsafeSet.SaveContext.
+func (set *ThreadSafeSet) SaveContext(ctx context.Context, w io.Writer) error {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.SaveContext(ctx, w)
+}
+
+// Save writes the elements of the set to w, see ThreadUnsafeSet.Save.
+func (set FrozenSet) Save(w io.Writer) error {
+	return set.elems().Save(w)
+}
+
+// SaveContext is like Save, see ThreadUnsafeSet.SaveContext.
+func (set FrozenSet) SaveContext(ctx context.Context, w io.Writer) error {
+	return set.elems().SaveContext(ctx, w)
+}
+
+// snapshotReader reads the elements of a snapshot.
+type snapshotReader struct {
+	r interface {
+		io.Reader
+		io.ByteReader
+	}
+}
+
+func (r *snapshotReader) uvarint() (uint64, error) {
+	v, err := binary.ReadUvarint(r.r)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = errSnapshotTruncated
+	}
+	return v, err
+}
+
+func (r *snapshotReader) varint() (int64, error) {
+	v, err := binary.ReadVarint(r.r)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = errSnapshotTruncated
+	}
+	return v, err
+}
+
+func (r *snapshotReader) full(b []byte) error {
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return errSnapshotTruncated
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *snapshotReader) bytes() ([]byte, error) {
+	n, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	// Read in chunks, so that a corrupt length can't allocate more
+	// than the snapshot holds.
+	var b []byte
+	for n > 0 {
+		chunk := n
+		if chunk > 1<<16 {
+			chunk = 1 << 16
+		}
+		b = append(b, make([]byte, chunk)...)
+		if err := r.full(b[len(b)-int(chunk):]); err != nil {
+			return nil, err
+		}
+		n -= chunk
+	}
+	return b, nil
+}
+
+func (r *snapshotReader) uint64() (uint64, error) {
+	var b [8]byte
+	err := r.full(b[:])
+	return binary.LittleEndian.Uint64(b[:]), err
+}
+
+func (r *snapshotReader) uint32() (uint32, error) {
+	var b [4]byte
+	err := r.full(b[:])
+	return binary.LittleEndian.Uint32(b[:]), err
+}
+
+func (r *snapshotReader) elem() (interface{}, error) {
+	tag, err := r.r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = errSnapshotTruncated
+		}
+		return nil, err
+	}
+	switch tag {
+	case tagInt, tagInt8, tagInt16, tagInt32, tagInt64, tagDuration:
+		v, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case tagInt:
+			return int(v), nil
+		case tagInt8:
+			return int8(v), nil
+		case tagInt16:
+			return int16(v), nil
+		case tagInt32:
+			return int32(v), nil
+		case tagInt64:
+			return v, nil
+		}
+		return time.Duration(v), nil
+	case tagUint, tagUint8, tagUint16, tagUint32, tagUint64, tagUintptr:
+		v, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case tagUint:
+			return uint(v), nil
+		case tagUint8:
+			return uint8(v), nil
+		case tagUint16:
+			return uint16(v), nil
+		case tagUint32:
+			return uint32(v), nil
+		case tagUint64:
+			return v, nil
+		}
+		return uintptr(v), nil
+	case tagFloat32:
+		v, err := r.uint32()
+		return math.Float32frombits(v), err
+	case tagFloat64:
+		v, err := r.uint64()
+		return math.Float64frombits(v), err
+	case tagComplex64:
+		re, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+		im, err := r.uint32()
+		return complex(math.Float32frombits(re), math.Float32frombits(im)), err
+	case tagComplex128:
+		re, err := r.uint64()
+		if err != nil {
+			return nil, err
+		}
+		im, err := r.uint64()
+		return complex(math.Float64frombits(re), math.Float64frombits(im)), err
+	case tagBool:
+		b, err := r.r.ReadByte()
+		if err == io.EOF {
+			err = errSnapshotTruncated
+		}
+		return b != 0, err
+	case tagUUID:
+		var u [16]byte
+		err := r.full(u[:])
+		return u, err
+	}
+
+	b, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case tagString:
+		return string(b), nil
+	case tagTime:
+		var t time.Time
+		err := t.UnmarshalBinary(b)
+		return t, err
+	case tagIP:
+		return net.IP(b), nil
+	case tagBytes:
+		return b, nil
+	case tagNumber:
+		return json.Number(b), nil
+	case tagGob:
+		return decodeGobElem(b)
+	}
+	return nil, fmt.Errorf("goset: unknown element tag 0x%02x in snapshot", tag)
+}
+
+// Load reads a set saved by Save from r and returns it as a thread-safe
+// set. If r is not an io.ByteReader, Load may read past the end of the
+// snapshot.
+func Load(r io.Reader) (Set, error) {
+	return LoadContext(context.Background(), r)
+}
+
+// LoadContext is like Load, but checks ctx while it reads the elements
+// and aborts with an *OperationError once ctx is done. Progress is
+// reported to the func registered with WithProgress.
+func LoadContext(ctx context.Context, r io.Reader) (Set, error) {
+	sr := &snapshotReader{}
+	if br, ok := r.(interface {
+		io.Reader
+		io.ByteReader
+	}); ok {
+		sr.r = br
+	} else {
+		sr.r = bufio.NewReader(r)
+	}
+	var header [len(snapshotMagic) + 2]byte
+	if err := sr.full(header[:]); err != nil {
+		return nil, err
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, errors.New("goset: not a snapshot of a set")
+	}
+	if v := header[len(snapshotMagic)]; v != snapshotVersion {
+		return nil, fmt.Errorf("goset: unsupported snapshot version %d", v)
+	}
+	set := newThreadUnsafeSet()
+	set.conf.mixed = header[len(snapshotMagic)+1]&snapshotMixed != 0
+	n, err := sr.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	// A corrupt count must not allocate more than the snapshot holds.
+	if n < 1<<16 {
+		set.Grow(int(n))
+	}
+	t := newOpTracker(ctx, "load", int(n))
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	for ; n > 0; n-- {
+		if err := t.step(); err != nil {
+			return nil, err
+		}
+		elem, err := sr.elem()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := set.TryAdd(elem); err != nil {
+			return nil, err
+		}
+	}
+	t.finish()
+	return set.ToThreadSafe(), nil
+}
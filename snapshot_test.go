@@ -0,0 +1,141 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+type savedPoint struct{ X, Y int }
+
+func (p savedPoint) Hash() string {
+	return fmt.Sprint(p.X, p.Y)
+}
+
+func Test_SaveLoad(t *testing.T) {
+	gob.Register(savedPoint{})
+	sets := []Set{
+		NewSet(-1, 0, 1<<40),
+		NewSet(uint8(1), uint8(255)),
+		NewSet(1.5, math.Inf(-1)),
+		NewSet(complex64(1 + 2i)),
+		NewSet("a", "", "日本"),
+		NewSet(true, false),
+		NewSet(time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC)),
+		NewSet(time.Second),
+		NewSet(net.ParseIP("10.0.0.1")),
+		NewSet([16]byte{1, 2}),
+		NewSet([]byte("bytes")),
+		NewSet(savedPoint{1, 2}, savedPoint{3, 4}),
+		NewSet(WithMixedTypes(), 1, "1", int64(1)),
+		NewSet(),
+	}
+	for _, s := range sets {
+		var buf bytes.Buffer
+		if err := s.Save(&buf); err != nil {
+			t.Fatalf("Saving %v failed: %v", s, err)
+		}
+		loaded, err := Load(&buf)
+		if err != nil || !loaded.Equal(s) {
+			t.Errorf("Expected %v to be loaded, got %v, %v", s, loaded, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	NewFrozenSet("x", "y").Save(&buf)
+	b := buf.Bytes()
+	if _, err := Load(bytes.NewReader(b[:len(b)-1])); err == nil {
+		t.Errorf("Expected an error for a truncated snapshot")
+	}
+	b[4] = 99
+	if _, err := Load(bytes.NewReader(b)); err == nil {
+		t.Errorf("Expected an error for an unknown version")
+	}
+	if _, err := Load(bytes.NewReader([]byte(`["x"]`))); err == nil {
+		t.Errorf("Expected an error for data that isn't a snapshot")
+	}
+}
+
+func Test_LoadContextProgress(t *testing.T) {
+	s := NewSet()
+	const N = 1000
+	for i := 0; i < N; i++ {
+		s.Add(i)
+	}
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatalf("Saving failed: %v", err)
+	}
+
+	var reports []Progress
+	ctx := WithProgress(context.Background(), 100, func(p Progress) {
+		reports = append(reports, p)
+	})
+	loaded, err := LoadContext(ctx, bytes.NewReader(buf.Bytes()))
+	if err != nil || !loaded.Equal(s) {
+		t.Fatalf("Expected %v elements to be loaded, got %v, %v", N, loaded, err)
+	}
+	if len(reports) != N/100+1 {
+		t.Fatalf("Expected %v progress reports, got %v", N/100+1, len(reports))
+	}
+	last := reports[len(reports)-1]
+	if !last.Done || last.Processed != N || last.Total != N || last.Op != "load" {
+		t.Errorf("Unexpected final progress report: %+v", last)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := LoadContext(ctx, bytes.NewReader(buf.Bytes())); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a canceled load, got %v", err)
+	}
+}
+
+func Test_SaveContext(t *testing.T) {
+	s := NewSet()
+	const N = 4000
+	for i := 0; i < N; i++ {
+		s.Add(i)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	err := s.(*ThreadSafeSet).SaveContext(ctx, &buf)
+	if opErr, ok := err.(*OperationError); !ok || opErr.Op != "save" || opErr.Err != context.Canceled {
+		t.Errorf("Expected a canceled save *OperationError, got %v", err)
+	}
+
+	var reports []Progress
+	ctx = WithProgress(context.Background(), 1000, func(p Progress) {
+		reports = append(reports, p)
+	})
+	buf.Reset()
+	if err := s.(*ThreadSafeSet).SaveContext(ctx, &buf); err != nil {
+		t.Fatalf("Error should be nil: %v", err)
+	}
+	if loaded, err := Load(&buf); err != nil || !loaded.Equal(s) {
+		t.Errorf("Expected %v elements to be loaded, got %v, %v", N, loaded, err)
+	}
+	if len(reports) != N/1000+1 || !reports[len(reports)-1].Done {
+		t.Errorf("Unexpected progress reports: %+v", reports)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// NaturalLess reports whether a sorts before b in natural order, that is
+// runs of decimal digits are compared by their numeric value instead of
+// byte by byte, so "file2" sorts before "file10".
+//
+// Strings that only differ in the leading zeros of a number are ordered
+// by plain byte comparison, which keeps the ordering total.
+func NaturalLess(a, b string) bool {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		ca, cb := a[i], b[j]
+		if !isDigit(ca) || !isDigit(cb) {
+			if ca != cb {
+				return ca < cb
+			}
+			i++
+			j++
+			continue
+		}
+
+		// Both sides start a number, compare the whole digit runs.
+		si, sj := i, j
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+		for j < len(b) && isDigit(b[j]) {
+			j++
+		}
+		na, nb := trimZeros(a[si:i]), trimZeros(b[sj:j])
+		if len(na) != len(nb) {
+			return len(na) < len(nb)
+		}
+		if na != nb {
+			return na < nb
+		}
+	}
+	if len(a)-i != len(b)-j {
+		return len(a)-i < len(b)-j
+	}
+	return a < b
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func trimZeros(s string) string {
+	for len(s) > 1 && s[0] == '0' {
+		s = s[1:]
+	}
+	return s
+}
+
+// defaultLess orders numbers by value, with NaN first, times
+// chronologically and strings byte by byte, not by NaturalLess.
+// Elements of other types, or of different kinds, are ordered by type name
+// and then by their hash (or formatted value), so the result is always
+// deterministic.
+func defaultLess(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	ka, kb := kindClass(va), kindClass(vb)
+	if ka == kb {
+		// Equal values of different types, like int8(1) and 1, fall
+		// through to be ordered by type name.
+		switch ka {
+		case reflect.String:
+			if x, y := va.String(), vb.String(); x != y {
+				return x < y
+			}
+		case reflect.Int:
+			if x, y := va.Int(), vb.Int(); x != y {
+				return x < y
+			}
+		case reflect.Uint:
+			if x, y := va.Uint(), vb.Uint(); x != y {
+				return x < y
+			}
+		case reflect.Float64:
+			// NaN compares unequal to everything, so it is
+			// explicitly ordered before every other float.
+			x, y := va.Float(), vb.Float()
+			if nx, ny := math.IsNaN(x), math.IsNaN(y); nx || ny {
+				if nx != ny {
+					return nx
+				}
+			} else if x != y {
+				return x < y
+			}
+		}
+	}
+	if x, ok := a.(time.Time); ok {
+		if y, ok := b.(time.Time); ok && !x.Equal(y) {
+			return x.Before(y)
+		}
+	}
+	ta, tb := fmt.Sprintf("%T", a), fmt.Sprintf("%T", b)
+	if ta != tb {
+		return ta < tb
+	}
+	return sortKey(a) < sortKey(b)
+}
+
+// NaturalOrder is a less function for NewSortedSet, ToSortedSlice, MinBy
+// and MaxBy that orders two strings by NaturalLess, so "file2" sorts
+// before "file10". Any other pair of elements is ordered as with a nil
+// less function.
+func NaturalOrder(a, b interface{}) bool {
+	if x, ok := a.(string); ok {
+		if y, ok := b.(string); ok {
+			return NaturalLess(x, y)
+		}
+	}
+	return defaultLess(a, b)
+}
+
+// kindClass folds the sized numeric kinds into a single kind per class.
+func kindClass(v reflect.Value) reflect.Kind {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.Int
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return reflect.Uint
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	case reflect.String:
+		return reflect.String
+	default:
+		return reflect.Invalid
+	}
+}
+
+// sortSlice sorts objs by less, or by defaultLess if less is nil.
+func sortSlice(objs []interface{}, less func(a, b interface{}) bool) []interface{} {
+	if less == nil {
+		less = defaultLess
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		return less(objs[i], objs[j])
+	})
+	return objs
+}
+
+func sortKey(obj interface{}) string {
+	if hash, err := calcHash(obj); err == nil {
+		return hash
+	}
+	return fmt.Sprintf("%v", obj)
+}
+
+// marshalSorted marshals objs into a JSON array sorted by defaultLess.
+// Elements that defaultLess doesn't order, like distinct elements with
+// the same hash, are ordered by their JSON encoding, so the result
+// doesn't depend on the order of objs.
+func marshalSorted(objs []interface{}) ([]byte, error) {
+	items := make([][]byte, len(objs))
+	for i, obj := range objs {
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = b
+	}
+	order := make([]int, len(objs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if defaultLess(objs[a], objs[b]) {
+			return true
+		}
+		return !defaultLess(objs[b], objs[a]) && bytes.Compare(items[a], items[b]) < 0
+	})
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, a := range order {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(items[a])
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func Test_NaturalLess(t *testing.T) {
+	names := []string{"file10", "file2", "file1", "file02", "a", "file", "file2b", "file2a"}
+	sort.Slice(names, func(i, j int) bool {
+		return NaturalLess(names[i], names[j])
+	})
+
+	expected := []string{"a", "file", "file1", "file02", "file2", "file2a", "file2b", "file10"}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, names)
+		}
+	}
+}
+
+func Test_NaturalOrder(t *testing.T) {
+	sorted := NewSortedSet(NaturalOrder, "file10", "file2", "file1")
+	expected := []interface{}{"file1", "file2", "file10"}
+	objs := sorted.ToSlice()
+	for i := range expected {
+		if objs[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, objs)
+		}
+	}
+
+	objs = NewThreadUnsafeSet("b10", "b9", "a").ToSortedSlice(NaturalOrder)
+	expected = []interface{}{"a", "b9", "b10"}
+	for i := range expected {
+		if objs[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, objs)
+		}
+	}
+
+	objs = NewSet(10, 9, 1).ToSortedSlice(NaturalOrder)
+	expected = []interface{}{1, 9, 10}
+	for i := range expected {
+		if objs[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, objs)
+		}
+	}
+}
+
+func Test_ToSortedSlice(t *testing.T) {
+	objs := NewSet(10, 2, 1, 100).ToSortedSlice(nil)
+	expected := []interface{}{1, 2, 10, 100}
+	for i := range expected {
+		if objs[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, objs)
+		}
+	}
+
+	objs = NewThreadUnsafeSet(1, 3, 2).ToSortedSlice(func(a, b interface{}) bool {
+		return a.(int) > b.(int)
+	})
+	if objs[0] != 3 || objs[2] != 1 {
+		t.Errorf("Expected descending order, got %v", objs)
+	}
+}
+
+func Test_ToSortedSliceNaN(t *testing.T) {
+	objs := NewSet(2.5, math.Inf(-1), math.NaN(), 1.0).ToSortedSlice(nil)
+	if len(objs) != 4 || !math.IsNaN(objs[0].(float64)) {
+		t.Fatalf("Expected NaN first, got %v", objs)
+	}
+	if objs[1] != math.Inf(-1) || objs[2] != 1.0 || objs[3] != 2.5 {
+		t.Errorf("Expected the other floats in order, got %v", objs)
+	}
+	if defaultLess(math.NaN(), math.NaN()) {
+		t.Errorf("Expected NaN not to sort before NaN")
+	}
+}
+
+func Test_MarshalJSONSorted(t *testing.T) {
+	for _, s := range []Set{NewSet(10, 9, 1, 100), NewThreadUnsafeSet(100, 1, 10, 9)} {
+		b, err := s.MarshalJSONSorted()
+		if err != nil || string(b) != "[1,9,10,100]" {
+			t.Errorf("Expected [1,9,10,100], got %s, %v", b, err)
+		}
+	}
+	colliding := NewThreadUnsafeSet(parityInt(3), parityInt(1), parityInt(5))
+	first, _ := colliding.MarshalJSONSorted()
+	for i := 0; i < 10; i++ {
+		if b, _ := colliding.Clone().MarshalJSONSorted(); string(b) != string(first) {
+			t.Errorf("Expected a stable order, got %s and %s", first, b)
+		}
+	}
+	if b, _ := NewSet().Freeze().MarshalJSONSorted(); string(b) != "[]" {
+		t.Errorf("Expected [], got %s", b)
+	}
+}
+
+func Test_SelectAt(t *testing.T) {
+	sorted := newByKey(NewSet(3, 1, 4, 15, 9, 2, 6, 5, 35, 8).ToSlice())
+	sort.Stable(sorted)
+	for k := range sorted.elems {
+		b := newByKey(NewSet(3, 1, 4, 15, 9, 2, 6, 5, 35, 8).ToSlice())
+		b.selectAt(k)
+		if b.elems[k] != sorted.elems[k] {
+			t.Errorf("Expected %v at position %v, got %v", sorted.elems[k], k, b.elems[k])
+		}
+	}
+}
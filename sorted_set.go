@@ -0,0 +1,170 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+// SortedSet is a thread-safe set that keeps its elements sorted by a less
+// function in a balanced tree. Each, Iter, Iterator, ToSlice, String and
+// MarshalJSON visit elements in ascending order, and the sets returned by
+// its operations, such as Union or Filter, are sorted the same way.
+//
+// Elements are identified by less instead of their hash: two elements
+// are the same if neither is less than the other. less must be a strict
+// weak ordering over all elements added to, or looked up in, the set.
+type SortedSet struct {
+	*ThreadSafeSet
+}
+
+// NewSortedSet creates and returns a new set with the given elements,
+// sorted by less. A nil less sorts numbers by value, strings byte by
+// byte, and other elements by type name and hash. Pass NaturalOrder to
+// sort "file2" before "file10".
+func NewSortedSet(less func(a, b interface{}) bool, vals ...interface{}) *SortedSet {
+	if less == nil {
+		less = defaultLess
+	}
+	set := &SortedSet{&ThreadSafeSet{unsafeSet: ThreadUnsafeSet{store: &treeStore{less: less}}}}
+	for _, item := range vals {
+		set.Add(item)
+	}
+	return set
+}
+
+// tree returns the store of the set, which may be shared with clones of
+// the set, the caller must hold the lock.
+func (set *SortedSet) tree() *treeStore {
+	return unwrapStore(set.unsafeSet.store, false).(*treeStore)
+}
+
+// Clone returns a clone of the set, sorted by the same less function.
+func (set *SortedSet) Clone() Set {
+	return &SortedSet{set.ThreadSafeSet.Clone().(*ThreadSafeSet)}
+}
+
+// nodeVal returns the element of n, or false if n is nil.
+func nodeVal(n *treeNode) (interface{}, bool) {
+	if n == nil {
+		return nil, false
+	}
+	return n.val, true
+}
+
+// First returns the smallest element of the set, or false if the set
+// is empty.
+func (set *SortedSet) First() (interface{}, bool) {
+	set.RLock()
+	defer set.RUnlock()
+	return nodeVal(set.tree().root.min())
+}
+
+// Last returns the largest element of the set, or false if the set is
+// empty.
+func (set *SortedSet) Last() (interface{}, bool) {
+	set.RLock()
+	defer set.RUnlock()
+	return nodeVal(set.tree().root.max())
+}
+
+// Floor returns the greatest element of the set less than or equal to
+// v, or false if there is no such element.
+func (set *SortedSet) Floor(v interface{}) (interface{}, bool) {
+	set.RLock()
+	defer set.RUnlock()
+	t := set.tree()
+	return nodeVal(t.root.below(v, true, t.less))
+}
+
+// Ceiling returns the smallest element of the set greater than or equal
+// to v, or false if there is no such element.
+func (set *SortedSet) Ceiling(v interface{}) (interface{}, bool) {
+	set.RLock()
+	defer set.RUnlock()
+	t := set.tree()
+	return nodeVal(t.root.above(v, true, t.less))
+}
+
+// Lower returns the greatest element of the set strictly less than v,
+// or false if there is no such element.
+func (set *SortedSet) Lower(v interface{}) (interface{}, bool) {
+	set.RLock()
+	defer set.RUnlock()
+	t := set.tree()
+	return nodeVal(t.root.below(v, false, t.less))
+}
+
+// Higher returns the smallest element of the set strictly greater than
+// v, or false if there is no such element.
+func (set *SortedSet) Higher(v interface{}) (interface{}, bool) {
+	set.RLock()
+	defer set.RUnlock()
+	t := set.tree()
+	return nodeVal(t.root.above(v, false, t.less))
+}
+
+// Range returns an Iterator over the elements from from, inclusive, to
+// to, exclusive, in ascending order. Like Iterator, the elements are
+// snapshotted before Range returns.
+func (set *SortedSet) Range(from, to interface{}) *Iterator {
+	var objs []interface{}
+	set.RLock()
+	t := set.tree()
+	t.root.eachRange(from, to, t.less, func(obj interface{}) bool {
+		objs = append(objs, obj)
+		return false
+	})
+	set.RUnlock()
+	return sliceIterator(objs)
+}
+
+// Rank returns the number of elements of the set less than v, which is
+// the index of v in ToSlice if v is in the set. It runs in O(log n).
+func (set *SortedSet) Rank(v interface{}) int {
+	set.RLock()
+	defer set.RUnlock()
+	t := set.tree()
+	return t.root.rank(v, t.less)
+}
+
+// Select returns the i-th smallest element of the set, counting from 0,
+// or nil if i is out of range. It runs in O(log n).
+func (set *SortedSet) Select(i int) interface{} {
+	set.RLock()
+	defer set.RUnlock()
+	obj, _ := nodeVal(set.tree().root.nth(i))
+	return obj
+}
+
+// PopMin removes and returns the smallest element of the set, or false
+// if the set is empty. Together with Add, it lets a SortedSet serve as
+// a priority queue without duplicates.
+func (set *SortedSet) PopMin() (interface{}, bool) {
+	set.Lock()
+	defer set.Unlock()
+	obj, ok := nodeVal(set.tree().root.min())
+	if ok {
+		set.unsafeSet.store.remove(obj)
+	}
+	return obj, ok
+}
+
+// PopMax removes and returns the largest element of the set, or false
+// if the set is empty.
+func (set *SortedSet) PopMax() (interface{}, bool) {
+	set.Lock()
+	defer set.Unlock()
+	obj, ok := nodeVal(set.tree().root.max())
+	if ok {
+		set.unsafeSet.store.remove(obj)
+	}
+	return obj, ok
+}
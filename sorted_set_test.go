@@ -0,0 +1,149 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func Test_SortedSet(t *testing.T) {
+	s := NewSortedSet(nil)
+	r := rand.New(rand.NewSource(1))
+	expected := map[int]bool{}
+	for i := 0; i < N; i++ {
+		v := r.Intn(N / 2)
+		if s.Add(v) == expected[v] {
+			t.Fatalf("Unexpected result adding %v", v)
+		}
+		expected[v] = true
+		if i%3 == 0 {
+			v = r.Intn(N / 2)
+			s.Remove(v)
+			delete(expected, v)
+		}
+	}
+
+	objs := s.ToSlice()
+	if len(objs) != len(expected) || s.Size() != len(expected) {
+		t.Fatalf("Expected %v elements, got %v", len(expected), len(objs))
+	}
+	if !sort.SliceIsSorted(objs, func(i, j int) bool { return objs[i].(int) < objs[j].(int) }) {
+		t.Fatalf("Expected elements in ascending order, got %v", objs)
+	}
+	for v := range expected {
+		if !s.Contains(v) {
+			t.Fatalf("Expected %v to be in the set", v)
+		}
+	}
+
+	union := s.Union(NewSet(-1))
+	if first := union.ToSlice()[0]; first != -1 {
+		t.Errorf("Expected a sorted union, got %v first", first)
+	}
+	if !s.Clone().(*SortedSet).Equal(s) {
+		t.Errorf("Expected the clone to equal the set")
+	}
+}
+
+func Test_SortedSetComparator(t *testing.T) {
+	s := NewSortedSet(func(a, b interface{}) bool {
+		return len(a.(string)) < len(b.(string))
+	}, "ccc", "a", "bb", "x")
+	if s.Size() != 3 || !s.Contains("y") {
+		t.Errorf("Expected elements to be identified by the comparator, got %v", s)
+	}
+}
+
+func Test_SortedSetNavigation(t *testing.T) {
+	s := NewSortedSet(nil, 10, 20, 30, 40)
+	cases := []struct {
+		name     string
+		f        func(v interface{}) (interface{}, bool)
+		v        int
+		expected interface{}
+	}{
+		{"Floor", s.Floor, 20, 20},
+		{"Floor", s.Floor, 25, 20},
+		{"Floor", s.Floor, 5, nil},
+		{"Ceiling", s.Ceiling, 25, 30},
+		{"Ceiling", s.Ceiling, 45, nil},
+		{"Lower", s.Lower, 20, 10},
+		{"Lower", s.Lower, 10, nil},
+		{"Higher", s.Higher, 20, 30},
+		{"Higher", s.Higher, 40, nil},
+	}
+	for _, c := range cases {
+		if got, ok := c.f(c.v); got != c.expected || ok != (c.expected != nil) {
+			t.Errorf("Expected %v(%v) to be %v, got %v", c.name, c.v, c.expected, got)
+		}
+	}
+
+	if first, _ := s.First(); first != 10 {
+		t.Errorf("Expected First to be 10, got %v", first)
+	}
+	if last, _ := s.Last(); last != 40 {
+		t.Errorf("Expected Last to be 40, got %v", last)
+	}
+	if _, ok := NewSortedSet(nil).First(); ok {
+		t.Errorf("Expected no first element of the empty set")
+	}
+
+	var objs []interface{}
+	for obj := range s.Range(15, 40).C {
+		objs = append(objs, obj)
+	}
+	if len(objs) != 2 || objs[0] != 20 || objs[1] != 30 {
+		t.Errorf("Expected [20 30], got %v", objs)
+	}
+}
+
+func Test_SortedSetRankSelect(t *testing.T) {
+	s := NewSortedSet(nil)
+	for i := N - 1; i >= 0; i-- {
+		s.Add(i * 2)
+	}
+	for i := 0; i < N; i++ {
+		if rank := s.Rank(i * 2); rank != i {
+			t.Fatalf("Expected rank of %v to be %v, got %v", i*2, i, rank)
+		}
+		if obj := s.Select(i); obj != i*2 {
+			t.Fatalf("Expected element %v to be %v, got %v", i, i*2, obj)
+		}
+	}
+	if rank := s.Rank(3); rank != 2 {
+		t.Errorf("Expected rank of an absent element to count smaller elements, got %v", rank)
+	}
+	if obj := s.Select(N); obj != nil {
+		t.Errorf("Expected nil out of range, got %v", obj)
+	}
+}
+
+func Test_SortedSetPopMinMax(t *testing.T) {
+	s := NewSortedSet(nil, 3, 1, 4, 5, 9, 2, 6)
+	for _, expected := range []int{1, 2, 3} {
+		if obj, ok := s.PopMin(); !ok || obj != expected {
+			t.Fatalf("Expected PopMin to return %v, got %v", expected, obj)
+		}
+	}
+	for _, expected := range []int{9, 6, 5, 4} {
+		if obj, ok := s.PopMax(); !ok || obj != expected {
+			t.Fatalf("Expected PopMax to return %v, got %v", expected, obj)
+		}
+	}
+	if _, ok := s.PopMin(); ok || s.Size() != 0 {
+		t.Errorf("Expected the set to be empty, got %v", s)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Value implements driver.Valuer, so that a set can be written to a
+// database column: the set is stored as a JSON array sorted like
+// MarshalJSONSorted.
+func (set *ThreadUnsafeSet) Value() (driver.Value, error) {
+	return set.MarshalJSONSorted()
+}
+
+// Scan implements sql.Scanner, replacing the elements of the set with
+// those of a column holding a JSON array, like the ones Value writes, or
+// a Postgres array like {a,b,"c d"}. A NULL column makes the set empty.
+// Strings and numbers are parsed as the element type of the set, if it
+// has one and they are not of that type, like UnmarshalText does. A
+// zero set becomes a set with the default storage.
+func (set *ThreadUnsafeSet) Scan(src interface{}) error {
+	var text []byte
+	switch s := src.(type) {
+	case nil:
+	case []byte:
+		text = s
+	case string:
+		text = []byte(s)
+	default:
+		return fmt.Errorf("can't scan a %T into a set", src)
+	}
+	text = bytes.TrimSpace(text)
+
+	var elems []interface{}
+	switch {
+	case len(text) == 0:
+	case text[0] == '[':
+		d := json.NewDecoder(bytes.NewReader(text))
+		d.UseNumber()
+		if err := d.Decode(&elems); err != nil {
+			return err
+		}
+	case text[0] == '{':
+		items, err := parsePostgresArray(string(text))
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			elems = append(elems, item)
+		}
+	default:
+		return fmt.Errorf("can't scan %q into a set, expected a JSON or Postgres array", text)
+	}
+
+	if set.store == nil {
+		*set = newThreadUnsafeSet()
+	}
+	typ := set.typ
+	scanned := set.emptyLike(len(elems))
+	for _, elem := range elems {
+		switch e := elem.(type) {
+		case string, json.Number:
+			if typ != nil && typ != reflect.TypeOf(elem) {
+				var err error
+				if elem, err = parseText(fmt.Sprint(e), typ); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := scanned.TryAdd(elem); err != nil {
+			return err
+		}
+	}
+	return set.replace(&scanned)
+}
+
+// parsePostgresArray returns the elements of a one-dimensional Postgres
+// array literal.
+func parsePostgresArray(s string) ([]string, error) {
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, fmt.Errorf("%q is not a Postgres array", s)
+	}
+	s = s[1 : len(s)-1]
+	var items []string
+	for {
+		// Postgres allows whitespace around the elements.
+		if s = strings.TrimLeftFunc(s, unicode.IsSpace); len(s) == 0 {
+			break
+		}
+		var item strings.Builder
+		if s[0] == '"' {
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+				if i < len(s) {
+					item.WriteByte(s[i])
+				}
+			}
+			if i >= len(s) {
+				return nil, errors.New("unterminated quoted element in Postgres array")
+			}
+			s = strings.TrimLeftFunc(s[i+1:], unicode.IsSpace)
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			raw := strings.TrimSpace(s[:end])
+			switch {
+			case strings.EqualFold(raw, "NULL"):
+				return nil, errors.New("NULL can't be an element of a set")
+			case strings.ContainsAny(raw, "{}"):
+				return nil, errors.New("nested Postgres arrays can't be scanned into a set")
+			}
+			item.WriteString(raw)
+			s = s[end:]
+		}
+		items = append(items, item.String())
+		if len(s) > 0 {
+			if s[0] != ',' {
+				return nil, fmt.Errorf("unexpected %q in Postgres array", s[0])
+			}
+			s = s[1:]
+		}
+	}
+	return items, nil
+}
+
+// Value implements driver.Valuer, see ThreadUnsafeSet.Value.
+func (set *ThreadSafeSet) Value() (driver.Value, error) {
+	return set.MarshalJSONSorted()
+}
+
+// Scan implements sql.Scanner, see ThreadUnsafeSet.Scan.
+func (set *ThreadSafeSet) Scan(src interface{}) error {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.Scan(src)
+}
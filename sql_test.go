@@ -0,0 +1,59 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+)
+
+var (
+	_ sql.Scanner   = &ThreadSafeSet{}
+	_ driver.Valuer = &ThreadUnsafeSet{}
+)
+
+func Test_SQL(t *testing.T) {
+	v, err := NewSet(3, 1, 2).(*ThreadSafeSet).Value()
+	if b, ok := v.([]byte); err != nil || !ok || string(b) != "[1,2,3]" {
+		t.Errorf("Expected [1,2,3], got %v, %v", v, err)
+	}
+
+	ids := NewSet(0).(*ThreadSafeSet)
+	if err := ids.Scan(v); err != nil || !ids.Equal(NewSet(1, 2, 3)) {
+		t.Errorf("Expected {1, 2, 3}, got %v, %v", ids, err)
+	}
+	if err := ids.Scan("{4, 5}"); err != nil || !ids.Equal(NewSet(4, 5)) {
+		t.Errorf("Expected {4, 5}, got %v, %v", ids, err)
+	}
+	if err := ids.Scan(nil); err != nil || ids.Size() != 0 {
+		t.Errorf("Expected NULL to empty the set, got %v, %v", ids, err)
+	}
+
+	var tags ThreadUnsafeSet
+	if err := tags.Scan([]byte(`{a,"b c","d\"e",a}`)); err != nil || !tags.Equal(NewThreadUnsafeSet("a", "b c", `d"e`)) {
+		t.Errorf(`Expected {a, b c, d"e}, got %v, %v`, &tags, err)
+	}
+	if err := tags.Scan(`{ "a" , "b c",d}`); err != nil || !tags.Equal(NewThreadUnsafeSet("a", "b c", "d")) {
+		t.Errorf(`Expected {a, b c, d}, got %v, %v`, &tags, err)
+	}
+	for _, src := range []interface{}{"{a,NULL}", "{{1},{2}}", `{"a}`, "a,b", 1} {
+		if err := tags.Scan(src); err == nil {
+			t.Errorf("Expected an error scanning %v", src)
+		}
+	}
+	if tags.Size() != 3 {
+		t.Errorf("Expected failed scans to leave the set untouched, got %v", &tags)
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "reflect"
+
+// store holds the elements of a ThreadUnsafeSet. It decides how elements
+// are identified and laid out in memory, everything else is built on top
+// of it by ThreadUnsafeSet.
+type store interface {
+	// add stores val unless an element with the same identity is
+	// present already, and returns whether val was stored.
+	add(val interface{}) (bool, error)
+
+	// get returns the stored element with the same identity as val.
+	get(val interface{}) (interface{}, bool)
+
+	// remove deletes the element with the same identity as val and
+	// returns whether there was one.
+	remove(val interface{}) (bool, error)
+
+	// len returns the number of stored elements.
+	len() int
+
+	// each calls f for every stored element until f returns true.
+	each(f func(val interface{}) bool)
+
+	// clear removes all elements.
+	clear()
+
+	// grow makes room for n more elements, if the store supports it.
+	grow(n int)
+
+	// empty returns a new, empty store of the same kind, sized for
+	// capacity elements.
+	empty(capacity int) store
+}
+
+// externalStore is implemented by stores backed by something outside of
+// the set, like kvStore, which writes its changes through to a key-value
+// store, or the map views of AsSet. A set must keep using such a store,
+// rather than swap in an in-memory copy of it.
+type externalStore interface {
+	store
+	external()
+}
+
+// concurrentStore is implemented by stores that can be read while the set
+// changes them, like syncMapStore, which lets a ThreadSafeSet serve
+// Contains, Size, Each and ToSlice without locking. Such a store must be
+// an externalStore as well, so that the set never swaps it for another.
+type concurrentStore interface {
+	externalStore
+
+	// view returns the store to read without holding the lock of the
+	// set.
+	view() store
+
+	// publish makes the changes made while the set was write-locked
+	// visible through view. The caller must hold the write lock.
+	publish()
+}
+
+// concurrentOf returns the concurrentStore behind s, looking through the
+// stores of WithLog and OnAdd, or nil if there is none.
+func concurrentOf(s store) concurrentStore {
+	switch w := s.(type) {
+	case concurrentStore:
+		return w
+	case *hookStore:
+		return concurrentOf(w.store)
+	case *logStore:
+		return concurrentOf(w.store)
+	}
+	return nil
+}
+
+// hashStore is the default store. Elements of the native types are map
+// keys themselves, which needs no hashing and no allocation. Hashable
+// elements, and elements that are not equal to themselves like NaN, are
+// keyed by a 64-bit hash of their hash string instead, sharing a bucket
+// with the elements whose hashes collide.
+//
+// In FloatsByBits mode, floats are hashed as well, by their bit patterns,
+// since map keys compare them by ==.
+//
+// The maps are made on the first add, so that a store sized by grow or
+// empty gets its capacity in the map it ends up using.
+type hashStore struct {
+	vals     map[interface{}]struct{}
+	hashed   map[uint64][]hashedElem // Store {hashKey($hash): [{$value, $hash}...]} of elem
+	nHashed  int
+	capacity int
+	floats   FloatMode
+	exposed  bool // vals was returned by AsMap and must stay in use
+}
+
+// hashedElem is an element of a hashStore bucket with its hash string,
+// kept so that looking up the bucket doesn't hash its elements again.
+type hashedElem struct {
+	val  interface{}
+	hash string
+}
+
+func newHashStore(capacity int) *hashStore {
+	return &hashStore{capacity: capacity}
+}
+
+// direct reports whether val is stored as a key of vals, or returns
+// an error if val can't be stored at all.
+func (s *hashStore) direct(val interface{}) (bool, error) {
+	if !isHashableObj(val) {
+		return false, &NotHashableError{Elem: val}
+	}
+	if s.floats == FloatsByBits {
+		if _, ok := floatBits(val); ok {
+			return false, nil
+		}
+	}
+	return isComparableNative(val) && val == val, nil
+}
+
+// hash returns the hash string of an element that is not direct.
+func (s *hashStore) hash(val interface{}) string {
+	if s.floats == FloatsByBits {
+		if hash, ok := floatBits(val); ok {
+			return hash
+		}
+	}
+	hash, _ := calcHash(val)
+	return hash
+}
+
+// find returns the bucket of val, whose hash string is hash, and the
+// position of the element equal to val in it or -1. Elements of
+// different types, which sets WithMixedTypes hold, are never equal.
+func (s *hashStore) find(val interface{}, hash string) (uint64, int) {
+	key := hashKey(hash)
+	typ := reflect.TypeOf(val)
+	for i, e := range s.hashed[key] {
+		if reflect.TypeOf(e.val) == typ && sameElem(e.val, e.hash, val, hash) {
+			return key, i
+		}
+	}
+	return key, -1
+}
+
+func (s *hashStore) add(val interface{}) (bool, error) {
+	isDirect, err := s.direct(val)
+	if err != nil {
+		return false, err
+	}
+	if isDirect {
+		if _, ok := s.vals[val]; ok {
+			return false, nil
+		}
+		if s.vals == nil {
+			s.vals = make(map[interface{}]struct{}, s.capacity)
+		}
+		s.vals[val] = struct{}{}
+		return true, nil
+	}
+	hash := s.hash(val)
+	key, i := s.find(val, hash)
+	if i >= 0 {
+		return false, nil
+	}
+	if s.hashed == nil {
+		s.hashed = make(map[uint64][]hashedElem, s.capacity)
+	}
+	s.hashed[key] = append(s.hashed[key], hashedElem{val: val, hash: hash})
+	s.nHashed++
+	return true, nil
+}
+
+func (s *hashStore) get(val interface{}) (interface{}, bool) {
+	isDirect, err := s.direct(val)
+	if err != nil {
+		return nil, false
+	}
+	if isDirect {
+		_, ok := s.vals[val]
+		return val, ok
+	}
+	hash := s.hash(val)
+	key, i := s.find(val, hash)
+	if i < 0 {
+		return nil, false
+	}
+	return s.hashed[key][i].val, true
+}
+
+func (s *hashStore) remove(val interface{}) (bool, error) {
+	isDirect, err := s.direct(val)
+	if err != nil {
+		return false, err
+	}
+	if isDirect {
+		if _, ok := s.vals[val]; !ok {
+			return false, nil
+		}
+		delete(s.vals, val)
+		return true, nil
+	}
+	hash := s.hash(val)
+	key, i := s.find(val, hash)
+	if i < 0 {
+		return false, nil
+	}
+	if bucket := s.hashed[key]; len(bucket) == 1 {
+		delete(s.hashed, key)
+	} else {
+		bucket[i] = bucket[len(bucket)-1]
+		bucket[len(bucket)-1] = hashedElem{}
+		s.hashed[key] = bucket[:len(bucket)-1]
+	}
+	s.nHashed--
+	return true, nil
+}
+
+func (s *hashStore) len() int {
+	return len(s.vals) + s.nHashed
+}
+
+func (s *hashStore) each(f func(val interface{}) bool) {
+	for obj := range s.vals {
+		if f(obj) {
+			return
+		}
+	}
+	for _, bucket := range s.hashed {
+		for _, e := range bucket {
+			if f(e.val) {
+				return
+			}
+		}
+	}
+}
+
+func (s *hashStore) clear() {
+	if s.exposed {
+		for obj := range s.vals {
+			delete(s.vals, obj)
+		}
+		s.hashed, s.nHashed = nil, 0
+		return
+	}
+	*s = hashStore{floats: s.floats}
+}
+
+func (s *hashStore) grow(n int) {
+	switch {
+	case s.exposed:
+		// The map of AsMap can't be replaced by a bigger one.
+	case len(s.vals) > 0:
+		vals := make(map[interface{}]struct{}, len(s.vals)+n)
+		for obj := range s.vals {
+			vals[obj] = struct{}{}
+		}
+		s.vals = vals
+	case s.nHashed > 0:
+		hashed := make(map[uint64][]hashedElem, len(s.hashed)+n)
+		for key, bucket := range s.hashed {
+			hashed[key] = bucket
+		}
+		s.hashed = hashed
+	default:
+		s.capacity += n
+	}
+}
+
+func (s *hashStore) empty(capacity int) store {
+	return &hashStore{capacity: capacity, floats: s.floats}
+}
+
+// setOp is a binary set operation.
+type setOp int
+
+const (
+	opUnion setOp = iota
+	opIntersect
+	opDifference
+	opSymmetricDifference
+)
+
+// algebraStore is implemented by stores that can combine with a store of
+// the same kind faster than element by element.
+type algebraStore interface {
+	store
+
+	// combine returns a new store with the result of op applied to
+	// the store and other, or false if other is not supported.
+	combine(op setOp, other store) (store, bool)
+
+	// combineWith is like combine, but stores the result in place.
+	combineWith(op setOp, other store) bool
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_StringSet(t *testing.T) {
+	a := NewStringSet("a", "b", "c")
+	b := NewStringSet("b", "c", "d")
+	if !a.Union(b).Equal(NewStringSet("a", "b", "c", "d")) {
+		t.Errorf("Unexpected union %v", a.Union(b))
+	}
+	if !a.Intersect(b).Equal(NewStringSet("b", "c")) {
+		t.Errorf("Unexpected intersection %v", a.Intersect(b))
+	}
+	if !a.SymmetricDifference(b).Equal(NewStringSet("a", "d")) {
+		t.Errorf("Unexpected symmetric difference %v", a.SymmetricDifference(b))
+	}
+
+	view := a.AsSet()
+	view.Add("z")
+	if !a.Contains("z") {
+		t.Errorf("Expected the view to write through to the set")
+	}
+
+	data, err := json.Marshal(NewStringSet("x"))
+	if err != nil || string(data) != `["x"]` {
+		t.Fatalf("Expected a JSON array, got %s (%v)", data, err)
+	}
+	var decoded StringSet
+	if err = json.Unmarshal([]byte(`["x","y","x"]`), &decoded); err != nil || !decoded.Equal(NewStringSet("x", "y")) {
+		t.Errorf("Expected [x y], got %v (%v)", decoded, err)
+	}
+}
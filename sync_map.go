@@ -0,0 +1,137 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// WithSyncMapBackend stores the elements of the set in a sync.Map, for
+// sets that are read far more often than they are changed. Contains,
+// Size, Each and ToSlice of a thread-safe set backed by a sync.Map don't
+// lock the set, so readers never wait for each other or for writers. They
+// are then not atomic: Contains with several elements and Each may see
+// changes made while they run.
+//
+// All other methods lock the set as usual, and changes are slower than
+// with the default store. Elements that are not of the native types are
+// identified by their type and hash string.
+func WithSyncMapBackend() Option {
+	return func(opts *setOptions) {
+		opts.newStore = func() store { return &syncMapStore{} }
+	}
+}
+
+// syncMapStore is a store backed by a sync.Map, which may be read without
+// holding the lock of the set. Native elements are keys of the map
+// themselves, other elements are keyed by a syncMapKey, and the values
+// are the stored elements.
+type syncMapStore struct {
+	m sync.Map
+	n int64 // Number of elements, updated atomically
+}
+
+// syncMapKey is the key of an element of a syncMapStore that is not a
+// native element.
+type syncMapKey struct {
+	typ  reflect.Type
+	hash string
+}
+
+func (s *syncMapStore) external() {}
+
+// view returns s, which is safe to read at any time.
+func (s *syncMapStore) view() store {
+	return s
+}
+
+// publish does nothing, changes are visible right away.
+func (s *syncMapStore) publish() {}
+
+// key returns the key of val in the map, or an error if val can't be
+// stored.
+func (s *syncMapStore) key(val interface{}) (interface{}, error) {
+	hash, err := calcHash(val)
+	if err != nil {
+		return nil, err
+	}
+	if isComparableNative(val) && val == val {
+		return val, nil
+	}
+	return syncMapKey{typ: reflect.TypeOf(val), hash: hash}, nil
+}
+
+func (s *syncMapStore) add(val interface{}) (bool, error) {
+	key, err := s.key(val)
+	if err != nil {
+		return false, err
+	}
+	if _, loaded := s.m.LoadOrStore(key, val); loaded {
+		return false, nil
+	}
+	atomic.AddInt64(&s.n, 1)
+	return true, nil
+}
+
+func (s *syncMapStore) get(val interface{}) (interface{}, bool) {
+	key, err := s.key(val)
+	if err != nil {
+		return nil, false
+	}
+	return s.m.Load(key)
+}
+
+func (s *syncMapStore) remove(val interface{}) (bool, error) {
+	key, err := s.key(val)
+	if err != nil {
+		return false, err
+	}
+	// Changes are made by one goroutine at a time, holding the lock
+	// of the set, so nothing is deleted between Load and Delete.
+	if _, loaded := s.m.Load(key); !loaded {
+		return false, nil
+	}
+	s.m.Delete(key)
+	atomic.AddInt64(&s.n, -1)
+	return true, nil
+}
+
+func (s *syncMapStore) len() int {
+	return int(atomic.LoadInt64(&s.n))
+}
+
+func (s *syncMapStore) each(f func(val interface{}) bool) {
+	s.m.Range(func(_, val interface{}) bool {
+		return !f(val)
+	})
+}
+
+// clear deletes the elements one by one rather than replacing the map,
+// which lock-free readers may be using.
+func (s *syncMapStore) clear() {
+	s.m.Range(func(key, _ interface{}) bool {
+		s.m.Delete(key)
+		atomic.AddInt64(&s.n, -1)
+		return true
+	})
+}
+
+// grow does nothing, a sync.Map can't be presized.
+func (s *syncMapStore) grow(n int) {}
+
+func (s *syncMapStore) empty(capacity int) store {
+	return &syncMapStore{}
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"math"
+	"sync"
+	"testing"
+)
+
+func Test_SyncMapBackend(t *testing.T) {
+	var removed []interface{}
+	s := NewSet(WithSyncMapBackend(), OnRemove(func(elem interface{}) {
+		removed = append(removed, elem)
+	}), 1.0, 2.0, math.NaN())
+	if s.Size() != 3 || !s.Contains(1.0, 2.0, math.NaN()) || s.Contains(3.0) {
+		t.Errorf("Expected 1, 2 and NaN, got %v", s)
+	}
+	if !s.Add(-0.0) || s.Add(0.0) {
+		t.Errorf("Expected 0 and -0 to be the same element")
+	}
+	s.Remove(1.0)
+	if s.Contains(1.0) || len(removed) != 1 {
+		t.Errorf("Expected 1 to be removed, got %v", s)
+	}
+
+	c := s.Clone()
+	s.Clear()
+	if s.Size() != 0 || c.Size() != 3 {
+		t.Errorf("Expected the clone to keep its elements, got %v and %v", s, c)
+	}
+
+	h := NewSet(WithSyncMapBackend(), hashedInt(1), hashedInt(2))
+	if !h.Contains(hashedInt(1)) || h.Add(hashedInt(2)) || h.Size() != 2 {
+		t.Errorf("Expected Hashable elements to be found by their hash, got %v", h)
+	}
+}
+
+func Test_SyncMapBackendConcurrent(t *testing.T) {
+	s := NewSet(WithSyncMapBackend())
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(2)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < N; i++ {
+				s.Add(g*N + i)
+			}
+		}(g)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < N; i++ {
+				s.Contains(i)
+				s.Size()
+			}
+		}()
+	}
+	wg.Wait()
+	if s.Size() != 4*N {
+		t.Errorf("Expected %d elements, got %d", 4*N, s.Size())
+	}
+}
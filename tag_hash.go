@@ -0,0 +1,83 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// WithTaggedHashing identifies struct elements by the fields tagged
+// `goset:"key"`, see TagHash, so domain structs can be stored without
+// writing a Hash method:
+//
+//	type Person struct {
+//		ID      int `goset:"key"`
+//		Name    string
+//		Hobbies []string
+//	}
+//
+//	people := goset.NewSet(goset.WithTaggedHashing())
+func WithTaggedHashing() Option {
+	return WithHasher(HasherFunc(TagHash))
+}
+
+// TagHash returns a hash of the fields of the struct v, or of the struct
+// v points to, that are tagged `goset:"key"`, so that structs with equal
+// key fields have the same hash. The key fields are hashed with
+// DeepHash. Values without key fields, including values that are not
+// structs, are hashed as a whole by DeepHash.
+func TagHash(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return DeepHash(v)
+	}
+	fields := keyFields(rv.Type())
+	if len(fields) == 0 {
+		return DeepHash(v)
+	}
+
+	var b strings.Builder
+	b.WriteString(rv.Type().String())
+	b.WriteString("{")
+	visited := map[uintptr]bool{}
+	for _, i := range fields {
+		deepHash(&b, rv.Field(i), visited)
+		b.WriteString(",")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+var keyFieldCache sync.Map // Store {$type: $indices} of key fields
+
+// keyFields returns the indices of the fields of the struct type t that
+// are tagged `goset:"key"`.
+func keyFields(t reflect.Type) []int {
+	if fields, ok := keyFieldCache.Load(t); ok {
+		return fields.([]int)
+	}
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("goset") == "key" {
+			fields = append(fields, i)
+		}
+	}
+	keyFieldCache.Store(t, fields)
+	return fields
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	htmltemplate "html/template"
+	"text/template"
+)
+
+// TemplateFuncs returns helper functions for text/template that let
+// templates query and render sets without pre-processing in Go code:
+//
+//	has        {{ if has .Tags "admin" }}   reports whether all elements are in the set
+//	union      {{ union .A .B }}            returns the union of two sets
+//	sortedList {{ range sortedList .Tags }} returns the elements in a deterministic order
+//
+// Numbers are listed by value and strings byte by byte, other elements
+// are ordered by type and hash.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"has":        templateHas,
+		"union":      templateUnion,
+		"sortedList": templateSortedList,
+	}
+}
+
+// HTMLTemplateFuncs returns the same helpers as TemplateFuncs for html/template.
+func HTMLTemplateFuncs() htmltemplate.FuncMap {
+	return htmltemplate.FuncMap(TemplateFuncs())
+}
+
+func templateHas(set Set, vals ...interface{}) bool {
+	return set.Contains(vals...)
+}
+
+func templateUnion(a, b Set) Set {
+	return a.Union(b)
+}
+
+func templateSortedList(set Set) []interface{} {
+	return set.ToSortedSlice(nil)
+}
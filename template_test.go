@@ -0,0 +1,38 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func Test_TemplateFuncs(t *testing.T) {
+	tmpl := template.Must(template.New("t").Funcs(TemplateFuncs()).Parse(
+		`{{ if has .A "b" }}yes{{ end }} {{ range sortedList (union .A .B) }}{{ . }},{{ end }}`,
+	))
+
+	var b strings.Builder
+	err := tmpl.Execute(&b, map[string]Set{
+		"A": NewSet("c", "b"),
+		"B": NewSet("a", "d"),
+	})
+	if err != nil {
+		t.Fatalf("Error should be nil: %v", err)
+	}
+	if b.String() != "yes a,b,c,d," {
+		t.Errorf("Unexpected template output: %q", b.String())
+	}
+}
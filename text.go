@@ -0,0 +1,161 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultTextSeparator separates the elements of the text form of sets
+// created without WithTextSeparator.
+const defaultTextSeparator = ","
+
+// WithTextSeparator sets the separator between the elements of the text
+// form of the set, see MarshalText. The default is ",".
+func WithTextSeparator(sep string) Option {
+	return func(opts *setOptions) {
+		opts.conf.textSep = sep
+	}
+}
+
+func (set *ThreadUnsafeSet) textSeparator() string {
+	if set.conf.textSep == "" {
+		return defaultTextSeparator
+	}
+	return set.conf.textSep
+}
+
+// MarshalText implements encoding.TextMarshaler. The elements are
+// sorted like ToSortedSlice(nil) and joined by the separator of the set,
+// elements implementing encoding.TextMarshaler are written with it,
+// others are formatted like fmt.Sprint. It fails if the text of an
+// element contains the separator.
+func (set *ThreadUnsafeSet) MarshalText() ([]byte, error) {
+	sep := set.textSeparator()
+	items := make([]string, 0, set.Size())
+	for _, elem := range set.ToSortedSlice(nil) {
+		var item string
+		if m, ok := elem.(encoding.TextMarshaler); ok {
+			b, err := m.MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			item = string(b)
+		} else {
+			item = fmt.Sprint(elem)
+		}
+		if strings.Contains(item, sep) {
+			return nil, fmt.Errorf("element %q contains the separator %q", item, sep)
+		}
+		items = append(items, item)
+	}
+	return []byte(strings.Join(items, sep)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, adding the elements
+// of text, separated by the separator of the set, to the set. Spaces
+// around elements and empty elements are ignored. The elements are
+// parsed as the element type of the set, which may be a string, bool,
+// number or time.Duration type, or implement encoding.TextUnmarshaler.
+// If the set has no element type yet, they are added as strings. A zero
+// set becomes a set with the default storage.
+func (set *ThreadUnsafeSet) UnmarshalText(text []byte) error {
+	if set.store == nil {
+		*set = newThreadUnsafeSet()
+	}
+	for _, item := range strings.Split(string(text), set.textSeparator()) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		elem, err := parseText(item, set.typ)
+		if err != nil {
+			return err
+		}
+		if _, err := set.TryAdd(elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseText parses s as a value of typ, or returns s if typ is nil.
+func parseText(s string, typ reflect.Type) (interface{}, error) {
+	if typ == nil {
+		return s, nil
+	}
+	v := reflect.New(typ)
+	if u, ok := v.Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return nil, err
+		}
+		return v.Elem().Interface(), nil
+	}
+	v = v.Elem()
+	var err error
+	switch kindClass(v) {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int:
+		var i int64
+		if typ == reflect.TypeOf(time.Duration(0)) {
+			var d time.Duration
+			d, err = time.ParseDuration(s)
+			i = int64(d)
+		} else {
+			i, err = strconv.ParseInt(s, 0, typ.Bits())
+		}
+		v.SetInt(i)
+	case reflect.Uint:
+		var u uint64
+		u, err = strconv.ParseUint(s, 0, typ.Bits())
+		v.SetUint(u)
+	case reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(s, typ.Bits())
+		v.SetFloat(f)
+	default:
+		if typ.Kind() != reflect.Bool {
+			return nil, fmt.Errorf("%q can't be parsed as a %s", s, typ)
+		}
+		var b bool
+		b, err = strconv.ParseBool(s)
+		v.SetBool(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// MarshalText implements encoding.TextMarshaler, see
+// ThreadUnsafeSet.MarshalText.
+func (set *ThreadSafeSet) MarshalText() ([]byte, error) {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see
+// ThreadUnsafeSet.UnmarshalText.
+func (set *ThreadSafeSet) UnmarshalText(text []byte) error {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.UnmarshalText(text)
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func Test_Text(t *testing.T) {
+	b, err := NewSet(3, 1, 2).(*ThreadSafeSet).MarshalText()
+	if err != nil || string(b) != "1,2,3" {
+		t.Errorf("Expected 1,2,3, got %s, %v", b, err)
+	}
+	if _, err := NewThreadUnsafeSet("a,b").(*ThreadUnsafeSet).MarshalText(); err == nil {
+		t.Errorf("Expected an error for an element containing the separator")
+	}
+
+	var tags ThreadSafeSet
+	if err := tags.UnmarshalText([]byte(" b, a,,a ")); err != nil || !tags.Equal(NewSet("a", "b")) {
+		t.Errorf("Expected {a, b}, got %v, %v", &tags, err)
+	}
+	ports := NewThreadUnsafeSet(WithTextSeparator(";"), uint16(80)).(*ThreadUnsafeSet)
+	if err := ports.UnmarshalText([]byte("443;8080")); err != nil || !ports.Contains(uint16(443), uint16(8080)) {
+		t.Errorf("Expected the ports to be parsed as uint16, got %v, %v", ports, err)
+	}
+	if b, _ := ports.Clone().(*ThreadUnsafeSet).MarshalText(); string(b) != "80;443;8080" {
+		t.Errorf("Expected clones to keep the separator, got %s", b)
+	}
+	if err := ports.UnmarshalText([]byte("x")); err == nil {
+		t.Errorf("Expected an error parsing x as a port")
+	}
+
+	ips := NewSet(net.ParseIP("::1")).(*ThreadSafeSet)
+	timeouts := NewSet(time.Second).(*ThreadSafeSet)
+	if ips.UnmarshalText([]byte("10.0.0.1")) != nil || !ips.Contains(net.ParseIP("10.0.0.1")) ||
+		timeouts.UnmarshalText([]byte("1m30s")) != nil || !timeouts.Contains(90*time.Second) {
+		t.Errorf("Expected text unmarshalers and durations to be parsed, got %v and %v", ips, timeouts)
+	}
+}
@@ -0,0 +1,473 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// TieredSet puts an approximate membership filter in front of an exact
+// backing set. Contains consults the filter first and only touches the
+// backing set, which may be expensive (e.g. disk-backed), when the
+// filter reports a possible hit.
+//
+// The filter is maintained by the mutating methods of TieredSet, so the
+// backing set must not be modified directly once it has been wrapped.
+// All other methods are served by the backing set. Contains reads the
+// filter without locking, so negative lookups don't contend with
+// changes to the set.
+type TieredSet struct {
+	Set
+
+	mu     sync.Mutex   // serializes mutations
+	filter atomic.Value // *bloomFilter, replaced by rebuild and Clear
+}
+
+// NewTieredSet wraps backing with a filter sized for expected elements at
+// the given false positive rate. Elements already in backing are added
+// to the filter.
+func NewTieredSet(backing Set, expected int, fpRate float64) *TieredSet {
+	if size := backing.Size(); size > expected {
+		expected = size
+	}
+	set := &TieredSet{Set: backing}
+	set.filter.Store(newBloomFilter(expected, fpRate))
+	set.rebuild()
+	return set
+}
+
+// FilteredSet is a TieredSet in front of a set in memory, for workloads
+// dominated by negative lookups: Contains answers most of them from the
+// filter without locking the set.
+type FilteredSet = TieredSet
+
+// NewFilteredSet wraps set with a filter sized for expected elements at
+// the given false positive rate, see NewTieredSet.
+func NewFilteredSet(set Set, expected int, fpRate float64) *FilteredSet {
+	return NewTieredSet(set, expected, fpRate)
+}
+
+// bloom returns the current filter.
+func (set *TieredSet) bloom() *bloomFilter {
+	return set.filter.Load().(*bloomFilter)
+}
+
+// rebuild replaces the filter by one filled from the backing set, so
+// that concurrent lookups never see a partially filled filter. The
+// caller must hold the lock unless the set is not shared yet.
+func (set *TieredSet) rebuild() {
+	f := set.bloom().emptyLike()
+	set.Set.Each(func(elem interface{}) bool {
+		if hash, err := calcHash(elem); err == nil {
+			f.add(hash)
+		}
+		return false
+	})
+	set.filter.Store(f)
+}
+
+// track registers val with the filter and runs add, undoing the
+// registration if add reports that nothing was added.
+func (set *TieredSet) track(val interface{}, add func() bool) bool {
+	hash, err := calcHash(val)
+	if err != nil {
+		panic(err)
+	}
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	set.bloom().add(hash)
+	if !add() {
+		set.bloom().remove(hash)
+		return false
+	}
+	return true
+}
+
+// Add adds an element to the set. Returns whether
+// the item was added.
+func (set *TieredSet) Add(val interface{}) bool {
+	return set.track(val, func() bool {
+		return set.Set.Add(val)
+	})
+}
+
+// TryAdd is like Add, but returns an error instead of
+// panicking if val can't be hashed or is of a different
+// type than the elements of the set.
+func (set *TieredSet) TryAdd(val interface{}) (bool, error) {
+	hash, err := calcHash(val)
+	if err != nil {
+		return false, err
+	}
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	set.bloom().add(hash)
+	added, err := set.Set.TryAdd(val)
+	if !added {
+		set.bloom().remove(hash)
+	}
+	return added, err
+}
+
+// Append adds all given elements to the set. Returns the
+// number of items that were added.
+func (set *TieredSet) Append(vals ...interface{}) int {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	registered := make(map[string]bool, len(vals))
+	for _, v := range vals {
+		hash, err := calcHash(v)
+		if err != nil {
+			panic(err)
+		}
+		if registered[hash] || set.bloom().mightContain(hash) && set.Set.Contains(v) {
+			continue
+		}
+		registered[hash] = true
+		set.bloom().add(hash)
+	}
+	return set.Set.Append(vals...)
+}
+
+// AddIf adds an element to the set only if pred, called with
+// the current contents of the set, returns true.
+func (set *TieredSet) AddIf(val interface{}, pred func(current Set) bool) bool {
+	return set.track(val, func() bool {
+		return set.Set.AddIf(val, pred)
+	})
+}
+
+// CompareAndAdd adds an element to the set only if none of
+// the expectedAbsent elements is in the set.
+func (set *TieredSet) CompareAndAdd(val interface{}, expectedAbsent ...interface{}) bool {
+	return set.track(val, func() bool {
+		return set.Set.CompareAndAdd(val, expectedAbsent...)
+	})
+}
+
+// Replace removes old from the set and adds new in its place,
+// and returns whether old was in the set.
+func (set *TieredSet) Replace(old, new interface{}) bool {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	return set.replace(old, new)
+}
+
+// UpdateElem replaces the element of the set equal to val, if
+// any, by the result of f called with it, see Replace.
+func (set *TieredSet) UpdateElem(val interface{}, f func(cur interface{}) interface{}) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	if cur, ok := set.Set.Get(val); ok {
+		set.replace(cur, f(cur))
+	}
+}
+
+// replace replaces old by new in the backing set and the filter, the
+// caller must hold the lock. new is added to the filter first, so that
+// Contains never misses it.
+func (set *TieredSet) replace(old, new interface{}) bool {
+	hash, err := calcHash(new)
+	if err != nil {
+		panic(err)
+	}
+	set.bloom().add(hash)
+	present := set.Set.Contains(new)
+	if !set.Set.Replace(old, new) {
+		set.bloom().remove(hash)
+		return false
+	}
+	set.forget(old)
+	if present && !set.Set.Contains(old) {
+		// new was in the set before, apart from old.
+		set.bloom().remove(hash)
+	}
+	return true
+}
+
+// Update calls f with a view of the set and applies the changes
+// made through it at once when f returns, see SetTx. The elements
+// added are added to the filter before the backing set, so that
+// Contains never misses them.
+func (set *TieredSet) Update(f func(tx SetTx)) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	var removed []interface{}
+	tracked := true
+	set.Set.Update(func(tx SetTx) {
+		f(tx)
+		t, ok := tx.(*setTx)
+		if !ok {
+			tracked = false
+			return
+		}
+		t.added.Each(func(elem interface{}) bool {
+			if hash, err := calcHash(elem); err == nil {
+				set.bloom().add(hash)
+			}
+			return false
+		})
+		removed = t.removed.ToSlice()
+	})
+	if !tracked {
+		// The backing set doesn't tell what changed.
+		set.rebuild()
+		return
+	}
+	for _, obj := range removed {
+		set.forget(obj)
+	}
+}
+
+// Apply applies cs to the backing set, see Set.Apply. The adds of cs
+// that are not in the set yet are added to the filter before the backing
+// set, so that Contains never misses them, and the removes are dropped
+// from it afterwards.
+func (set *TieredSet) Apply(cs ChangeSet) {
+	adds, removes := changeElems(cs)
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	for _, v := range adds {
+		hash, err := calcHash(v)
+		if err != nil || set.bloom().mightContain(hash) && set.Set.Contains(v) {
+			continue
+		}
+		set.bloom().add(hash)
+	}
+	var present []interface{}
+	for _, v := range removes {
+		if set.Set.Contains(v) {
+			present = append(present, v)
+		}
+	}
+	set.Set.Apply(cs)
+	for _, obj := range present {
+		if !set.Set.Contains(obj) {
+			set.forget(obj)
+		}
+	}
+}
+
+// Contains returns whether the given items are all in the set. The
+// backing set is only queried if the filter reports all items as
+// possibly present.
+func (set *TieredSet) Contains(val ...interface{}) bool {
+	filter := set.bloom()
+	for _, v := range val {
+		hash, err := calcHash(v)
+		if err != nil || !filter.mightContain(hash) {
+			return false
+		}
+	}
+	return set.Set.Contains(val...)
+}
+
+// Get returns the element of the set equal to val, and whether
+// there is one. The backing set is only queried if the filter
+// reports val as possibly present.
+func (set *TieredSet) Get(val interface{}) (interface{}, bool) {
+	hash, err := calcHash(val)
+	if err != nil || !set.bloom().mightContain(hash) {
+		return nil, false
+	}
+	return set.Set.Get(val)
+}
+
+// Remove remove a single element from the set.
+func (set *TieredSet) Remove(i interface{}) {
+	hash, err := calcHash(i)
+	if err != nil {
+		panic(err)
+	}
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	if set.Set.Contains(i) {
+		set.Set.Remove(i)
+		set.bloom().remove(hash)
+	}
+}
+
+// TryRemove is like Remove, but returns an error instead of
+// panicking if val can't be hashed.
+func (set *TieredSet) TryRemove(val interface{}) error {
+	hash, err := calcHash(val)
+	if err != nil {
+		return err
+	}
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	if set.Set.Contains(val) {
+		if err := set.Set.TryRemove(val); err != nil {
+			return err
+		}
+		set.bloom().remove(hash)
+	}
+	return nil
+}
+
+// RemoveAll removes all given elements from the set.
+func (set *TieredSet) RemoveAll(vals ...interface{}) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	present := make(map[string]bool, len(vals))
+	for _, v := range vals {
+		hash, err := calcHash(v)
+		if err != nil {
+			panic(err)
+		}
+		if !present[hash] && set.bloom().mightContain(hash) && set.Set.Contains(v) {
+			present[hash] = true
+		}
+	}
+	set.Set.RemoveAll(vals...)
+	for hash := range present {
+		set.bloom().remove(hash)
+	}
+}
+
+// Pop removes and returns an arbitrary item from the set.
+func (set *TieredSet) Pop() (interface{}, bool) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	obj, ok := set.Set.Pop()
+	if ok {
+		set.forget(obj)
+	}
+	return obj, ok
+}
+
+// RemoveIf removes all elements for which pred returns true
+// and returns the number of elements removed.
+func (set *TieredSet) RemoveIf(pred func(elem interface{}) bool) int {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	return set.removed(set.Set.RemoveIf(pred))
+}
+
+// RetainIf removes all elements for which pred returns false
+// and returns the number of elements removed.
+func (set *TieredSet) RetainIf(pred func(elem interface{}) bool) int {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	return set.removed(set.Set.RetainIf(pred))
+}
+
+// removed refreshes the filter after n elements were removed by
+// the backing set, the caller must hold the lock.
+func (set *TieredSet) removed(n int) int {
+	if n > 0 {
+		set.rebuild()
+	}
+	return n
+}
+
+// PopN removes and returns up to n arbitrary items from the set.
+func (set *TieredSet) PopN(n int) []interface{} {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	objs := set.Set.PopN(n)
+	for _, obj := range objs {
+		set.forget(obj)
+	}
+	return objs
+}
+
+// PopIf removes and returns the first item found that
+// satisfies pred, or false if there is no such item.
+func (set *TieredSet) PopIf(pred func(elem interface{}) bool) (interface{}, bool) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	obj, ok := set.Set.PopIf(pred)
+	if ok {
+		set.forget(obj)
+	}
+	return obj, ok
+}
+
+// forget removes a popped element from the filter, the caller
+// must hold the lock.
+func (set *TieredSet) forget(obj interface{}) {
+	if hash, err := calcHash(obj); err == nil {
+		set.bloom().remove(hash)
+	}
+}
+
+// Clear removes all elements from the set, leaving
+// the empty set.
+func (set *TieredSet) Clear() {
+	set.mu.Lock()
+	set.Set.Clear()
+	set.filter.Store(set.bloom().emptyLike())
+	set.mu.Unlock()
+}
+
+// Clone returns a TieredSet over a clone of the backing set with
+// its own copy of the filter.
+func (set *TieredSet) Clone() Set {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	clone := &TieredSet{Set: set.Set.Clone()}
+	clone.filter.Store(set.bloom().clone())
+	return clone
+}
+
+// UnmarshalJSON will unmarshal a JSON-based byte slice into the backing
+// set and refresh the filter.
+func (set *TieredSet) UnmarshalJSON(b []byte) error {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	err := set.Set.UnmarshalJSON(b)
+	set.rebuild()
+	return err
+}
+
+// DifferenceWith removes all elements of other from this set.
+func (set *TieredSet) DifferenceWith(other Set) {
+	set.inPlace(other, set.Set.DifferenceWith)
+}
+
+// IntersectWith removes all elements that are not in other
+// from this set.
+func (set *TieredSet) IntersectWith(other Set) {
+	set.inPlace(other, set.Set.IntersectWith)
+}
+
+// SymmetricDifferenceWith removes the elements of other that
+// are in this set and adds those that are not.
+func (set *TieredSet) SymmetricDifferenceWith(other Set) {
+	set.inPlace(other, set.Set.SymmetricDifferenceWith)
+}
+
+// UnionWith adds all elements of other to this set.
+func (set *TieredSet) UnionWith(other Set) {
+	set.inPlace(other, set.Set.UnionWith)
+}
+
+// inPlace runs an in-place operation of the backing set with other,
+// unwrapped if it is a TieredSet, and refreshes the filter.
+func (set *TieredSet) inPlace(other Set, op func(other Set)) {
+	if o, ok := other.(*TieredSet); ok {
+		other = o.Set
+	}
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	op(other)
+	set.rebuild()
+}
+
+// Backing returns the exact set behind the filter.
+func (set *TieredSet) Backing() Set {
+	return set.Set
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "testing"
+
+// countingSet counts the membership queries that reach the backing set.
+type countingSet struct {
+	Set
+	lookups int
+}
+
+func (set *countingSet) Contains(val ...interface{}) bool {
+	set.lookups++
+	return set.Set.Contains(val...)
+}
+
+func Test_TieredSet(t *testing.T) {
+	backing := &countingSet{Set: NewSet(1, 2, 3)}
+	s := NewTieredSet(backing, N, 0.001)
+
+	if !s.Contains(1, 2, 3) {
+		t.Errorf("Set is missing elements of the backing set")
+	}
+	s.Add(4)
+	if !s.Contains(4) {
+		t.Errorf("Set is missing element: %v", 4)
+	}
+	s.Remove(1)
+	if s.Contains(1) {
+		t.Errorf("Set contains removed element: %v", 1)
+	}
+
+	backing.lookups = 0
+	misses := 0
+	for i := 100; i < 100+N; i++ {
+		if s.Contains(i) {
+			t.Errorf("Set contains unexpected element: %v", i)
+		}
+		misses++
+	}
+	if backing.lookups > misses/10 {
+		t.Errorf("Expected the filter to answer most misses, backing set was queried %v times", backing.lookups)
+	}
+}
+
+func Test_ToBloomFilter(t *testing.T) {
+	s := NewSet()
+	for i := 0; i < N; i++ {
+		s.Add(i)
+	}
+	f := s.ToBloomFilter(0.01)
+	falsePositives := 0
+	for i := 0; i < N; i++ {
+		if !f.MightContain(i) {
+			t.Fatalf("Expected no false negatives, %d is missing", i)
+		}
+		if f.MightContain(i + N) {
+			falsePositives++
+		}
+	}
+	if falsePositives > N/50 {
+		t.Errorf("Expected about 1%% false positives, got %d of %d", falsePositives, N)
+	}
+	if f.MightContain([]int{1}) {
+		t.Errorf("Expected unhashable values to be absent")
+	}
+}
+
+func Test_FilteredSetConcurrentLookups(t *testing.T) {
+	s := NewFilteredSet(NewSet(), N, 0.01)
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < N; i++ {
+			s.Add(i)
+		}
+		s.RemoveIf(func(elem interface{}) bool { return elem.(int)%2 == 0 })
+		close(done)
+	}()
+	for i := 0; i < N; i++ {
+		s.Contains(i)
+	}
+	<-done
+	if s.Contains(2) || !s.Contains(3) {
+		t.Errorf("Expected the filter to follow the set")
+	}
+}
+
+func Test_TieredSetUpdate(t *testing.T) {
+	s := NewTieredSet(NewSet(1, 2), N, 0.001)
+	s.Update(func(tx SetTx) {
+		tx.Remove(1)
+		tx.Add(3)
+	})
+	if !s.Contains(2, 3) || s.Contains(1) || s.Size() != 2 {
+		t.Errorf("Expected 2 and 3, got %v", s)
+	}
+	if hash, _ := calcHash(1); s.bloom().mightContain(hash) {
+		t.Errorf("Expected 1 to be removed from the filter")
+	}
+}
+
+func Test_TieredSetApply(t *testing.T) {
+	s := NewTieredSet(NewSet(1, 2), N, 0.001)
+	s.Apply(ChangeSet{Adds: NewSet(2, 3), Removes: NewSet(1)})
+	if !s.Contains(2, 3) || s.Contains(1) || s.Size() != 2 {
+		t.Errorf("Expected 2 and 3, got %v", s)
+	}
+	if hash, _ := calcHash(1); s.bloom().mightContain(hash) {
+		t.Errorf("Expected 1 to be removed from the filter")
+	}
+
+	s.Apply(ChangeSet{Adds: NewSet(2), Removes: NewSet(2)})
+	if !s.Contains(2) {
+		t.Errorf("Expected 2 to be kept in the filter when it is removed and added back")
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+// treeNode is a node of an AVL tree that also tracks the size of its
+// subtree. All methods accept a nil node as the empty tree.
+type treeNode struct {
+	val         interface{}
+	left, right *treeNode
+	height      int
+	size        int
+}
+
+func (n *treeNode) getHeight() int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+func (n *treeNode) getSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *treeNode) update() {
+	n.height = n.left.getHeight() + 1
+	if h := n.right.getHeight() + 1; h > n.height {
+		n.height = h
+	}
+	n.size = n.left.getSize() + n.right.getSize() + 1
+}
+
+func (n *treeNode) rotateLeft() *treeNode {
+	r := n.right
+	n.right = r.left
+	r.left = n
+	n.update()
+	r.update()
+	return r
+}
+
+func (n *treeNode) rotateRight() *treeNode {
+	l := n.left
+	n.left = l.right
+	l.right = n
+	n.update()
+	l.update()
+	return l
+}
+
+// balance restores the AVL invariant of n after one of its subtrees
+// changed height by at most one, and returns the new subtree root.
+func (n *treeNode) balance() *treeNode {
+	n.update()
+	switch diff := n.left.getHeight() - n.right.getHeight(); {
+	case diff > 1:
+		if n.left.left.getHeight() < n.left.right.getHeight() {
+			n.left = n.left.rotateLeft()
+		}
+		return n.rotateRight()
+	case diff < -1:
+		if n.right.right.getHeight() < n.right.left.getHeight() {
+			n.right = n.right.rotateRight()
+		}
+		return n.rotateLeft()
+	}
+	return n
+}
+
+// insert adds val to the tree unless an equivalent element is present,
+// and returns the new root and whether val was added.
+func (n *treeNode) insert(val interface{}, less func(a, b interface{}) bool) (*treeNode, bool) {
+	if n == nil {
+		return &treeNode{val: val, height: 1, size: 1}, true
+	}
+	var added bool
+	switch {
+	case less(val, n.val):
+		n.left, added = n.left.insert(val, less)
+	case less(n.val, val):
+		n.right, added = n.right.insert(val, less)
+	}
+	if !added {
+		return n, false
+	}
+	return n.balance(), true
+}
+
+// delete removes the element equivalent to val, and returns the new
+// root and whether there was one.
+func (n *treeNode) delete(val interface{}, less func(a, b interface{}) bool) (*treeNode, bool) {
+	if n == nil {
+		return nil, false
+	}
+	var deleted bool
+	switch {
+	case less(val, n.val):
+		n.left, deleted = n.left.delete(val, less)
+	case less(n.val, val):
+		n.right, deleted = n.right.delete(val, less)
+	default:
+		if n.left == nil {
+			return n.right, true
+		}
+		if n.right == nil {
+			return n.left, true
+		}
+		n.val = n.right.min().val
+		n.right = n.right.deleteMin()
+		deleted = true
+	}
+	if !deleted {
+		return n, false
+	}
+	return n.balance(), true
+}
+
+func (n *treeNode) deleteMin() *treeNode {
+	if n.left == nil {
+		return n.right
+	}
+	n.left = n.left.deleteMin()
+	return n.balance()
+}
+
+func (n *treeNode) find(val interface{}, less func(a, b interface{}) bool) *treeNode {
+	for n != nil {
+		switch {
+		case less(val, n.val):
+			n = n.left
+		case less(n.val, val):
+			n = n.right
+		default:
+			return n
+		}
+	}
+	return nil
+}
+
+func (n *treeNode) min() *treeNode {
+	for n != nil && n.left != nil {
+		n = n.left
+	}
+	return n
+}
+
+func (n *treeNode) max() *treeNode {
+	for n != nil && n.right != nil {
+		n = n.right
+	}
+	return n
+}
+
+// below returns the node of the greatest element less than val, or
+// equivalent to it if inclusive is set.
+func (n *treeNode) below(val interface{}, inclusive bool, less func(a, b interface{}) bool) *treeNode {
+	var found *treeNode
+	for n != nil {
+		if less(n.val, val) || inclusive && !less(val, n.val) {
+			found, n = n, n.right
+		} else {
+			n = n.left
+		}
+	}
+	return found
+}
+
+// above returns the node of the smallest element greater than val, or
+// equivalent to it if inclusive is set.
+func (n *treeNode) above(val interface{}, inclusive bool, less func(a, b interface{}) bool) *treeNode {
+	var found *treeNode
+	for n != nil {
+		if less(val, n.val) || inclusive && !less(n.val, val) {
+			found, n = n, n.left
+		} else {
+			n = n.right
+		}
+	}
+	return found
+}
+
+// rank returns the number of elements less than val.
+func (n *treeNode) rank(val interface{}, less func(a, b interface{}) bool) int {
+	rank := 0
+	for n != nil {
+		if less(n.val, val) {
+			rank += n.left.getSize() + 1
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return rank
+}
+
+// nth returns the node of the i-th smallest element, counting from 0,
+// or nil if i is out of range.
+func (n *treeNode) nth(i int) *treeNode {
+	for n != nil {
+		switch left := n.left.getSize(); {
+		case i < left:
+			n = n.left
+		case i > left:
+			i -= left + 1
+			n = n.right
+		default:
+			return n
+		}
+	}
+	return nil
+}
+
+// eachRange is like each, but skips the elements outside [from, to).
+func (n *treeNode) eachRange(from, to interface{}, less func(a, b interface{}) bool, f func(val interface{}) bool) bool {
+	switch {
+	case n == nil:
+		return false
+	case less(n.val, from):
+		return n.right.eachRange(from, to, less, f)
+	case !less(n.val, to):
+		return n.left.eachRange(from, to, less, f)
+	}
+	return n.left.eachRange(from, to, less, f) || f(n.val) || n.right.eachRange(from, to, less, f)
+}
+
+// each calls f for the elements in ascending order until f returns
+// true, and returns whether it did.
+func (n *treeNode) each(f func(val interface{}) bool) bool {
+	if n == nil {
+		return false
+	}
+	return n.left.each(f) || f(n.val) || n.right.each(f)
+}
+
+// treeStore is a store that keeps its elements sorted in a balanced
+// tree. Elements are identified by less instead of their hash: two
+// elements are the same if neither is less than the other.
+type treeStore struct {
+	root *treeNode
+	less func(a, b interface{}) bool
+}
+
+func (s *treeStore) add(val interface{}) (bool, error) {
+	var added bool
+	s.root, added = s.root.insert(val, s.less)
+	return added, nil
+}
+
+func (s *treeStore) get(val interface{}) (interface{}, bool) {
+	if n := s.root.find(val, s.less); n != nil {
+		return n.val, true
+	}
+	return nil, false
+}
+
+func (s *treeStore) remove(val interface{}) (bool, error) {
+	var deleted bool
+	s.root, deleted = s.root.delete(val, s.less)
+	return deleted, nil
+}
+
+func (s *treeStore) len() int {
+	return s.root.getSize()
+}
+
+func (s *treeStore) each(f func(val interface{}) bool) {
+	s.root.each(f)
+}
+
+func (s *treeStore) clear() {
+	s.root = nil
+}
+
+func (s *treeStore) grow(n int) {}
+
+func (s *treeStore) empty(capacity int) store {
+	return &treeStore{less: s.less}
+}
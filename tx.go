@@ -0,0 +1,140 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "reflect"
+
+// SetTx is the view of a set inside Update. Its changes are applied to
+// the set when the func passed to Update returns, and its reads see the
+// set with the changes made so far.
+type SetTx interface {
+	// Add adds an element to the set. Returns whether
+	// the item was added.
+	Add(val interface{}) bool
+
+	// Remove removes a single element from the set. Returns
+	// whether the item was removed.
+	Remove(val interface{}) bool
+
+	// Contains returns whether the given items
+	// are all in the set.
+	Contains(vals ...interface{}) bool
+
+	// Size returns the number of elements in the set.
+	Size() int
+
+	// Each iterates over elements and executes the passed func
+	// against each element. If passed func returns true, stop
+	// iteration at the time.
+	Each(f func(elem interface{}) bool)
+
+	// Rollback discards the changes made so far, the set is left
+	// untouched unless more changes are made after it.
+	Rollback()
+}
+
+// txBase is the set a transaction reads through, locked by the caller.
+type txBase interface {
+	Contains(vals ...interface{}) bool
+	Each(f func(elem interface{}) bool)
+	Size() int
+}
+
+// setTx is a SetTx buffering its changes in two sets: the elements it
+// added, which are not in base, and the elements it removed, which are
+// all in base.
+type setTx struct {
+	base    txBase
+	typ     reflect.Type // Element type of base, nil if it has none yet
+	added   ThreadUnsafeSet
+	removed ThreadUnsafeSet
+}
+
+// newSetTx returns a transaction over base, whose elements are of type
+// typ, nil if it has none yet. The changes are buffered in sets like
+// like, so that they identify elements as base does.
+func newSetTx(base txBase, like *ThreadUnsafeSet, typ reflect.Type) *setTx {
+	tx := &setTx{base: base, typ: typ, added: like.emptyLike(0), removed: like.emptyLike(0)}
+	tx.Rollback()
+	return tx
+}
+
+func (tx *setTx) Add(val interface{}) bool {
+	if tx.removed.Contains(val) {
+		tx.removed.Remove(val)
+		return true
+	}
+	if tx.added.Contains(val) || tx.base.Contains(val) {
+		return false
+	}
+	return tx.added.Add(val)
+}
+
+func (tx *setTx) Remove(val interface{}) bool {
+	if tx.added.Contains(val) {
+		tx.added.Remove(val)
+		return true
+	}
+	if tx.removed.Contains(val) || !tx.base.Contains(val) {
+		return false
+	}
+	return tx.removed.Add(val)
+}
+
+func (tx *setTx) Contains(vals ...interface{}) bool {
+	for _, v := range vals {
+		if !tx.added.Contains(v) && (tx.removed.Contains(v) || !tx.base.Contains(v)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (tx *setTx) Size() int {
+	return tx.base.Size() + tx.added.Size() - tx.removed.Size()
+}
+
+func (tx *setTx) Each(f func(elem interface{}) bool) {
+	stopped := false
+	tx.base.Each(func(elem interface{}) bool {
+		if tx.removed.Contains(elem) {
+			return false
+		}
+		stopped = f(elem)
+		return stopped
+	})
+	if !stopped {
+		tx.added.Each(f)
+	}
+}
+
+func (tx *setTx) Rollback() {
+	tx.added.Clear()
+	tx.removed.Clear()
+	tx.added.typ, tx.removed.typ = tx.typ, tx.typ
+}
+
+// run calls f with tx and then applies the changes of tx with remove and
+// add. Nothing is applied if f panics.
+func (tx *setTx) run(f func(tx SetTx), remove, add func(val interface{})) {
+	f(tx)
+	tx.removed.Each(func(elem interface{}) bool {
+		remove(elem)
+		return false
+	})
+	tx.added.Each(func(elem interface{}) bool {
+		add(elem)
+		return false
+	})
+}
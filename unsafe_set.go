@@ -15,43 +15,205 @@ package goset
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"reflect"
 	"strings"
 )
 
 type ThreadUnsafeSet struct {
-	dat map[string]interface{} // Store {$hash: $value} of elem
-	typ reflect.Type           // Set's data type
+	store store        // Set's elements
+	typ   reflect.Type // Set's data type
+	conf  setConf      // Set's configuration, passed on to derived sets
+}
+
+// setConf is the configuration of a set by its Options that applies to
+// the sets derived from it, too.
+type setConf struct {
+	mixed   bool             // Whether elements of different types are allowed, see WithMixedTypes
+	textSep string           // Separator of the text form, see WithTextSeparator
+	str     *SetStringConfig // Rendering of String and Format, see WithStringConfig
 }
 
 func newThreadUnsafeSet() ThreadUnsafeSet {
-	return ThreadUnsafeSet{dat: map[string]interface{}{}, typ: nil}
+	return ThreadUnsafeSet{store: newHashStore(0), typ: nil}
+}
+
+// emptyLike returns a new, empty set backed by the same kind of store.
+func (set *ThreadUnsafeSet) emptyLike(capacity int) ThreadUnsafeSet {
+	return ThreadUnsafeSet{store: set.store.empty(capacity), typ: nil, conf: set.conf}
+}
+
+// combine runs op on the stores of set and o if they support it, and
+// returns the result or false.
+func (set *ThreadUnsafeSet) combine(op setOp, o *ThreadUnsafeSet) (*ThreadUnsafeSet, bool) {
+	s, ok := unwrapStore(set.store, false).(algebraStore)
+	if !ok {
+		return nil, false
+	}
+	ret, ok := s.combine(op, unwrapStore(o.store, false))
+	if !ok {
+		return nil, false
+	}
+	return &ThreadUnsafeSet{store: ret, typ: combinedType(ret, set, o), conf: set.conf}, true
+}
+
+// combineWith runs op in place on the stores of set and o if they
+// support it, and returns whether they did.
+func (set *ThreadUnsafeSet) combineWith(op setOp, o *ThreadUnsafeSet) bool {
+	if _, ok := unwrapStore(set.store, false).(algebraStore); !ok {
+		return false
+	}
+	s, ok := unwrapStore(set.store, true).(algebraStore)
+	if !ok || !s.combineWith(op, unwrapStore(o.store, false)) {
+		return false
+	}
+	set.typ = combinedType(s, set, o)
+	return true
+}
+
+// combinedType returns the element type of a set combined from a and b
+// into s.
+func combinedType(s store, a, b *ThreadUnsafeSet) reflect.Type {
+	switch {
+	case s.len() == 0:
+		return nil
+	case a.typ != nil:
+		return a.typ
+	}
+	return b.typ
+}
+
+// ToThreadSafe returns a thread-safe set that adopts the backing storage
+// of set instead of copying it. This lets a set be built without locking
+// in a single goroutine and then be published to others.
+//
+// Ownership passes to the returned set: set must not be used anymore
+// once ToThreadSafe has been called.
+func (set *ThreadUnsafeSet) ToThreadSafe() *ThreadSafeSet {
+	if hs, ok := set.store.(*hookStore); ok {
+		hs.deferred = true
+	}
+	reader := concurrentOf(set.store)
+	if reader != nil {
+		reader.publish()
+	}
+	return &ThreadSafeSet{unsafeSet: *set, reader: reader}
 }
 
 func (set *ThreadUnsafeSet) Add(val interface{}) bool {
-	typ := reflect.ValueOf(val).Type()
-	if set.typ != nil && set.typ != typ {
-		panic(
-			fmt.Errorf(
-				"type conflict when you add a new element to set (type of set elem: %s, type of new elem %s)",
-				set.typ, typ,
-			))
+	added, err := set.TryAdd(val)
+	if err != nil {
+		panic(err)
+	}
+	return added
+}
+
+func (set *ThreadUnsafeSet) TryAdd(val interface{}) (bool, error) {
+	typ := reflect.TypeOf(val)
+	if err := set.checkType(typ); err != nil {
+		return false, err
+	}
+	added, err := set.store.add(val)
+	if err != nil {
+		return false, err
 	}
 	if set.typ == nil {
 		set.typ = typ
 	}
-	hash, err := calcHash(val)
-	if err != nil {
+	return added, nil
+}
+
+// checkType returns an error if elements of type typ can't be added to
+// the set.
+func (set *ThreadUnsafeSet) checkType(typ reflect.Type) error {
+	if typ == nil {
+		return notStorable(nil, "nil can't be added to a set")
+	}
+	if set.typ != nil && set.typ != typ && !set.conf.mixed {
+		return &TypeMismatchError{Want: set.typ, Got: typ}
+	}
+	return nil
+}
+
+func (set *ThreadUnsafeSet) Append(vals ...interface{}) int {
+	n := 0
+	for _, v := range vals {
+		if set.Add(v) {
+			n++
+		}
+	}
+	return n
+}
+
+func (set *ThreadUnsafeSet) AddIf(val interface{}, pred func(current Set) bool) bool {
+	if !pred(set) {
+		return false
+	}
+	return set.Add(val)
+}
+
+func (set *ThreadUnsafeSet) CompareAndAdd(val interface{}, expectedAbsent ...interface{}) bool {
+	for _, v := range expectedAbsent {
+		if set.Contains(v) {
+			return false
+		}
+	}
+	return set.Add(val)
+}
+
+func (set *ThreadUnsafeSet) Update(f func(tx SetTx)) {
+	newSetTx(set, set, set.typ).run(f, set.Remove, func(val interface{}) {
+		set.Add(val)
+	})
+}
+
+func (set *ThreadUnsafeSet) Replace(old, new interface{}) bool {
+	stored, ok := set.store.get(old)
+	if !ok {
+		return false
+	}
+	if err := set.checkType(reflect.TypeOf(new)); err != nil {
+		panic(err)
+	}
+	set.Remove(stored)
+	if _, err := set.store.add(new); err != nil {
+		// new can't be hashed, put old back.
+		set.store.add(stored)
 		panic(err)
 	}
-	set.dat[hash] = val
 	return true
 }
 
+func (set *ThreadUnsafeSet) UpdateElem(val interface{}, f func(cur interface{}) interface{}) {
+	if cur, ok := set.store.get(val); ok {
+		set.Replace(cur, f(cur))
+	}
+}
+
+func (set *ThreadUnsafeSet) Any(pred func(elem interface{}) bool) bool {
+	found := false
+	set.store.each(func(obj interface{}) bool {
+		found = pred(obj)
+		return found
+	})
+	return found
+}
+
+func (set *ThreadUnsafeSet) All(pred func(elem interface{}) bool) bool {
+	return !set.Any(func(elem interface{}) bool {
+		return !pred(elem)
+	})
+}
+
+func (set *ThreadUnsafeSet) None(pred func(elem interface{}) bool) bool {
+	return !set.Any(pred)
+}
+
 func (set *ThreadUnsafeSet) Cardinality() int {
-	return len(set.dat)
+	return set.store.len()
 }
 
 func (set *ThreadUnsafeSet) Size() int {
@@ -59,74 +221,268 @@ func (set *ThreadUnsafeSet) Size() int {
 }
 
 func (set *ThreadUnsafeSet) Clear() {
-	*set = newThreadUnsafeSet()
+	set.store.clear()
+	set.typ = nil
+}
+
+func (set *ThreadUnsafeSet) Grow(n int) {
+	if n > 0 {
+		set.store.grow(n)
+	}
 }
 
 func (set *ThreadUnsafeSet) Clone() Set {
-	cloned := newThreadUnsafeSet()
+	cloned := set.emptyLike(set.Size())
 	cloned.typ = set.typ
-	cloned.dat = make(map[string]interface{}, set.Size())
-	for _, elem := range set.dat {
+	set.store.each(func(elem interface{}) bool {
 		cloned.Add(elem)
-	}
+		return false
+	})
 	return &cloned
 }
 
+func (set *ThreadUnsafeSet) Combinations(k int) *Iterator {
+	return combinationsIterator(set.ToSlice(), k, set.newSubset)
+}
+
 func (set *ThreadUnsafeSet) Contains(val ...interface{}) bool {
 	for _, v := range val {
-		hash, err := calcHash(v)
-		if err != nil {
-			return false
-		}
-		if _, ok := set.dat[hash]; !ok {
+		if _, ok := set.store.get(v); !ok {
 			return false
 		}
 	}
 	return true
 }
 
+func (set *ThreadUnsafeSet) Get(val interface{}) (interface{}, bool) {
+	return set.store.get(val)
+}
+
 func (set *ThreadUnsafeSet) Difference(other Set) Set {
-	o := other.(*ThreadUnsafeSet)
-	diff := newThreadUnsafeSet()
-	for _, obj := range set.dat {
+	diff, _ := set.DifferenceContext(context.Background(), other)
+	return diff
+}
+
+func (set *ThreadUnsafeSet) DifferenceContext(ctx context.Context, other Set) (Set, error) {
+	o := set.unsafeOf(other)
+	t := newOpTracker(ctx, "difference", set.Size())
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	if diff, ok := set.combine(opDifference, o); ok {
+		t.finish()
+		return diff, nil
+	}
+	diff := set.emptyLike(0)
+	var err error
+	set.store.each(func(obj interface{}) bool {
+		if err = t.step(); err != nil {
+			return true
+		}
 		if !o.Contains(obj) {
 			diff.Add(obj)
 		}
+		return false
+	})
+	if err != nil {
+		return nil, err
 	}
-	return &diff
+	t.finish()
+	return &diff, nil
+}
+
+func (set *ThreadUnsafeSet) DifferenceCardinality(other Set) int {
+	return set.Size() - set.IntersectCardinality(other)
+}
+
+func (set *ThreadUnsafeSet) DifferenceWith(other Set) {
+	_ = set.DifferenceWithContext(context.Background(), other)
+}
+
+// DifferenceWithContext is like DifferenceWith, but checks ctx while it
+// runs and aborts with an *OperationError once ctx is done, leaving the
+// set untouched. Progress is reported to the func registered with
+// WithProgress.
+func (set *ThreadUnsafeSet) DifferenceWithContext(ctx context.Context, other Set) error {
+	o := set.unsafeOf(other)
+	scan, probe := set, o
+	if o.Size() < set.Size() {
+		scan, probe = o, set
+	}
+	t := newOpTracker(ctx, "difference", scan.Size())
+	if err := t.check(); err != nil {
+		return err
+	}
+	if o == set {
+		set.store.clear()
+		t.finish()
+		return nil
+	}
+	if set.combineWith(opDifference, o) {
+		t.finish()
+		return nil
+	}
+	var (
+		drop []interface{}
+		err  error
+	)
+	scan.store.each(func(obj interface{}) bool {
+		if err = t.step(); err != nil {
+			return true
+		}
+		if probe.Contains(obj) {
+			drop = append(drop, obj)
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	set.RemoveAll(drop...)
+	t.finish()
+	return nil
+}
+
+func (set *ThreadUnsafeSet) RemoveIf(pred func(elem interface{}) bool) int {
+	var drop []interface{}
+	set.store.each(func(obj interface{}) bool {
+		if pred(obj) {
+			drop = append(drop, obj)
+		}
+		return false
+	})
+	for _, obj := range drop {
+		set.store.remove(obj)
+	}
+	return len(drop)
+}
+
+func (set *ThreadUnsafeSet) RetainIf(pred func(elem interface{}) bool) int {
+	return set.RemoveIf(func(elem interface{}) bool {
+		return !pred(elem)
+	})
+}
+
+func (set *ThreadUnsafeSet) Filter(pred func(elem interface{}) bool) Set {
+	filtered := set.emptyLike(0)
+	set.store.each(func(obj interface{}) bool {
+		if pred(obj) {
+			filtered.Add(obj)
+		}
+		return false
+	})
+	return &filtered
+}
+
+func (set *ThreadUnsafeSet) GroupBy(keyFn func(elem interface{}) string) map[string]Set {
+	groups := map[string]Set{}
+	set.store.each(func(obj interface{}) bool {
+		key := keyFn(obj)
+		group, ok := groups[key]
+		if !ok {
+			g := set.emptyLike(0)
+			group = &g
+			groups[key] = group
+		}
+		group.Add(obj)
+		return false
+	})
+	return groups
 }
 
 func (set *ThreadUnsafeSet) Equal(other Set) bool {
 	if set.Size() != other.Size() {
-		return true
+		return false
 	}
-	o := other.(*ThreadUnsafeSet)
-	for _, obj := range set.dat {
-		if !o.Contains(obj) {
-			return false
+	o := set.unsafeOf(other)
+	return set.all(o.Contains)
+}
+
+func (set *ThreadUnsafeSet) Intersect(other Set) Set {
+	intersection, _ := set.IntersectContext(context.Background(), other)
+	return intersection
+}
+
+func (set *ThreadUnsafeSet) IntersectContext(ctx context.Context, other Set) (Set, error) {
+	o := set.unsafeOf(other)
+	small, big := set, o
+	if small.Size() > big.Size() {
+		small, big = big, small
+	}
+
+	t := newOpTracker(ctx, "intersect", small.Size())
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	if intersection, ok := set.combine(opIntersect, o); ok {
+		t.finish()
+		return intersection, nil
+	}
+	intersection := set.emptyLike(0)
+	var err error
+	small.store.each(func(obj interface{}) bool {
+		if err = t.step(); err != nil {
+			return true
+		}
+		if big.Contains(obj) {
+			intersection.Add(obj)
 		}
+		return false
+	})
+	if err != nil {
+		return nil, err
 	}
-	return true
+	t.finish()
+	return &intersection, nil
 }
 
-func (set *ThreadUnsafeSet) Intersect(other Set) Set {
-	o := other.(*ThreadUnsafeSet)
-	intersection := newThreadUnsafeSet()
-
-	if set.Size() < o.Size() {
-		for _, obj := range set.dat {
-			if o.Contains(obj) {
-				intersection.Add(obj)
-			}
+func (set *ThreadUnsafeSet) IntersectCardinality(other Set) int {
+	o := set.unsafeOf(other)
+	small, big := set, o
+	if small.Size() > big.Size() {
+		small, big = big, small
+	}
+	n := 0
+	small.store.each(func(obj interface{}) bool {
+		if big.Contains(obj) {
+			n++
 		}
-	} else {
-		for _, obj := range o.dat {
-			if set.Contains(obj) {
-				intersection.Add(obj)
-			}
+		return false
+	})
+	return n
+}
+
+func (set *ThreadUnsafeSet) IntersectWith(other Set) {
+	o := set.unsafeOf(other)
+	if set.combineWith(opIntersect, o) {
+		return
+	}
+	var drop []interface{}
+	set.store.each(func(obj interface{}) bool {
+		if !o.Contains(obj) {
+			drop = append(drop, obj)
 		}
+		return false
+	})
+	set.RemoveAll(drop...)
+}
+
+func (set *ThreadUnsafeSet) IsDisjoint(other Set) bool {
+	o := set.unsafeOf(other)
+	small, big := set, o
+	if small.Size() > big.Size() {
+		small, big = big, small
 	}
-	return &intersection
+	disjoint := true
+	small.store.each(func(obj interface{}) bool {
+		disjoint = !big.Contains(obj)
+		return !disjoint
+	})
+	return disjoint
+}
+
+func (set *ThreadUnsafeSet) Overlaps(other Set) bool {
+	return !set.IsDisjoint(other)
 }
 
 func (set *ThreadUnsafeSet) IsProperSubset(other Set) bool {
@@ -141,137 +497,471 @@ func (set *ThreadUnsafeSet) IsSubset(other Set) bool {
 	if set.Size() > other.Size() {
 		return false
 	}
-	o := other.(*ThreadUnsafeSet)
-	for _, obj := range set.dat {
-		if !o.Contains(obj) {
-			return false
-		}
-	}
-	return true
+	o := set.unsafeOf(other)
+	return set.all(o.Contains)
 }
 
 func (set *ThreadUnsafeSet) IsSuperset(other Set) bool {
 	return other.IsSubset(set)
 }
 
-func (set *ThreadUnsafeSet) Each(f func(elem interface{}) bool) {
-	for _, obj := range set.dat {
-		if f(obj) {
-			break
-		}
+func (set *ThreadUnsafeSet) IsSubsetWithin(other Set, k int) bool {
+	if set.Size()-other.Size() > k {
+		return false
 	}
+	o := set.unsafeOf(other)
+	missing := 0
+	set.store.each(func(obj interface{}) bool {
+		if !o.Contains(obj) {
+			missing++
+		}
+		return missing > k
+	})
+	return missing <= k
+}
+
+func (set *ThreadUnsafeSet) IsSupersetWithin(other Set, k int) bool {
+	return other.IsSubsetWithin(set, k)
+}
+
+func (set *ThreadUnsafeSet) Each(f func(elem interface{}) bool) {
+	set.store.each(f)
+}
+
+func (set *ThreadUnsafeSet) EachErr(f func(elem interface{}) error) error {
+	var err error
+	set.store.each(func(obj interface{}) bool {
+		err = f(obj)
+		return err != nil
+	})
+	return err
+}
+
+// all reports whether f returns true for every element of the set.
+func (set *ThreadUnsafeSet) all(f func(vals ...interface{}) bool) bool {
+	ret := true
+	set.store.each(func(obj interface{}) bool {
+		ret = f(obj)
+		return !ret
+	})
+	return ret
 }
 
 func (set *ThreadUnsafeSet) Iter() <-chan interface{} {
-	ch := make(chan interface{})
-	go func() {
-		for _, obj := range set.dat {
-			ch <- obj
-		}
-		close(ch)
-	}()
-	return ch
+	return set.IterBuffered(0)
+}
+
+func (set *ThreadUnsafeSet) IterBuffered(n int) <-chan interface{} {
+	return iterSlice(set.ToSlice(), n)
 }
 
 func (set *ThreadUnsafeSet) Iterator() *Iterator {
-	iterator, ch, stopCh := newIterator()
-
-	go func() {
-	L:
-		for _, obj := range set.dat {
-			select {
-			case <-stopCh:
-				break L
-			case ch <- obj:
-			}
+	return sliceIterator(set.ToSlice())
+}
+
+func (set *ThreadUnsafeSet) Cursor() *Cursor {
+	return &Cursor{objs: set.ToSlice()}
+}
+
+func (set *ThreadUnsafeSet) MinBy(less func(a, b interface{}) bool) (interface{}, bool) {
+	var (
+		min   interface{}
+		found bool
+	)
+	set.store.each(func(obj interface{}) bool {
+		if !found || less(obj, min) {
+			min, found = obj, true
 		}
-		close(ch)
-	}()
-	return iterator
+		return false
+	})
+	return min, found
+}
+
+func (set *ThreadUnsafeSet) MaxBy(less func(a, b interface{}) bool) (interface{}, bool) {
+	return set.MinBy(func(a, b interface{}) bool {
+		return less(b, a)
+	})
+}
+
+func (set *ThreadUnsafeSet) Min() (interface{}, bool) {
+	return set.MinBy(defaultLess)
+}
+
+func (set *ThreadUnsafeSet) Max() (interface{}, bool) {
+	return set.MaxBy(defaultLess)
+}
+
+func (set *ThreadUnsafeSet) Freeze() FrozenSet {
+	return freeze(*set.Clone().(*ThreadUnsafeSet))
+}
+
+func (set *ThreadUnsafeSet) Hash() string {
+	return setHash(set.ToSlice())
+}
+
+func (set *ThreadUnsafeSet) PowerSet() Set {
+	ret := newThreadUnsafeSet()
+	powerSet(&ret, set.ToSlice(), set.newSubset)
+	return &ret
+}
+
+func (set *ThreadUnsafeSet) PowerSetIterator() *Iterator {
+	return powerSetIterator(set.ToSlice(), set.newSubset)
+}
+
+// newSubset returns a new, empty set of the same kind for a subset.
+func (set *ThreadUnsafeSet) newSubset() Set {
+	subset := set.emptyLike(0)
+	return &subset
+}
+
+func (set *ThreadUnsafeSet) RandomSample(n int, r *rand.Rand) []interface{} {
+	return sample(set.ToSlice(), n, r)
+}
+
+func (set *ThreadUnsafeSet) RandomElement(r *rand.Rand) (interface{}, bool) {
+	return randomElement(set.ToSlice(), r)
 }
 
 func (set *ThreadUnsafeSet) Remove(i interface{}) {
-	hash, err := calcHash(i)
-	if err != nil {
+	if err := set.TryRemove(i); err != nil {
 		panic(err)
 	}
-	delete(set.dat, hash)
 }
 
-func (set *ThreadUnsafeSet) String() string {
-	var builder strings.Builder
-	builder.WriteString("goset.ThreadUnsafeSet{ ")
-	atLeastOnce := false
-	for _, obj := range set.dat {
-		builder.WriteString(fmt.Sprintf("%v, ", obj))
-		atLeastOnce = true
-	}
-	ret := builder.String()
-	if atLeastOnce {
-		ret = ret[:len(ret)-2]
+func (set *ThreadUnsafeSet) TryRemove(val interface{}) error {
+	_, err := set.store.remove(val)
+	return err
+}
+
+func (set *ThreadUnsafeSet) RemoveAll(vals ...interface{}) {
+	for _, v := range vals {
+		set.Remove(v)
 	}
-	return ret + " }"
+}
+
+func (set *ThreadUnsafeSet) String() string {
+	return setString(set, "goset.ThreadUnsafeSet")
 }
 
 func (set *ThreadUnsafeSet) SymmetricDifference(other Set) Set {
-	o := other.(*ThreadUnsafeSet)
-	diff := newThreadUnsafeSet()
-	for _, obj := range set.dat {
+	o := set.unsafeOf(other)
+	if diff, ok := set.combine(opSymmetricDifference, o); ok {
+		return diff
+	}
+	diff := set.emptyLike(0)
+	set.store.each(func(obj interface{}) bool {
 		if !o.Contains(obj) {
 			diff.Add(obj)
 		}
-	}
-	for _, obj := range o.dat {
+		return false
+	})
+	o.store.each(func(obj interface{}) bool {
 		if !set.Contains(obj) {
 			diff.Add(obj)
 		}
-	}
+		return false
+	})
 	return &diff
 }
 
+func (set *ThreadUnsafeSet) SymmetricDifferenceWith(other Set) {
+	o := set.unsafeOf(other)
+	if o == set {
+		set.store.clear()
+		return
+	}
+	if set.combineWith(opSymmetricDifference, o) {
+		return
+	}
+	o.store.each(func(obj interface{}) bool {
+		if set.Contains(obj) {
+			set.Remove(obj)
+		} else {
+			set.Add(obj)
+		}
+		return false
+	})
+}
+
+// unsafeOf returns other as a *ThreadUnsafeSet. A set of another type
+// is copied, through the Set interface, into a set that identifies its
+// elements like set does. It panics if set can't hold the elements of
+// other.
+func (set *ThreadUnsafeSet) unsafeOf(other Set) *ThreadUnsafeSet {
+	o, err := set.tryUnsafeOf(other)
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+// tryUnsafeOf is like unsafeOf, but returns an error instead of
+// panicking.
+func (set *ThreadUnsafeSet) tryUnsafeOf(other Set) (*ThreadUnsafeSet, error) {
+	switch o := other.(type) {
+	case *ThreadUnsafeSet:
+		return o, nil
+	case nil:
+		return nil, &IncompatibleSetError{Set: set, Other: other}
+	}
+	o := set.emptyLike(other.Size())
+	var err error
+	other.Each(func(elem interface{}) bool {
+		_, err = o.TryAdd(elem)
+		return err != nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// operand returns other as a *ThreadUnsafeSet like tryUnsafeOf. If adds
+// is true, the elements of other are added to the result of the
+// operation, so they must be of the same type as the elements of set.
+func (set *ThreadUnsafeSet) operand(other Set, adds bool) (*ThreadUnsafeSet, error) {
+	o, err := set.tryUnsafeOf(other)
+	if err != nil {
+		return nil, err
+	}
+	if adds && !set.conf.mixed && set.Size() > 0 && o.Size() > 0 && set.typ != o.typ {
+		return nil, &TypeMismatchError{Want: set.typ, Got: o.typ}
+	}
+	return o, nil
+}
+
+func (set *ThreadUnsafeSet) TryUnion(other Set) (Set, error) {
+	o, err := set.operand(other, true)
+	if err != nil {
+		return nil, err
+	}
+	return set.Union(o), nil
+}
+
+func (set *ThreadUnsafeSet) TryIntersect(other Set) (Set, error) {
+	o, err := set.operand(other, false)
+	if err != nil {
+		return nil, err
+	}
+	return set.Intersect(o), nil
+}
+
+func (set *ThreadUnsafeSet) TryDifference(other Set) (Set, error) {
+	o, err := set.operand(other, false)
+	if err != nil {
+		return nil, err
+	}
+	return set.Difference(o), nil
+}
+
+func (set *ThreadUnsafeSet) TrySymmetricDifference(other Set) (Set, error) {
+	o, err := set.operand(other, true)
+	if err != nil {
+		return nil, err
+	}
+	return set.SymmetricDifference(o), nil
+}
+
 func (set *ThreadUnsafeSet) Union(other Set) Set {
-	o := other.(*ThreadUnsafeSet)
-	union := newThreadUnsafeSet()
-	for _, obj := range set.dat {
-		union.Add(obj)
+	union, _ := set.UnionContext(context.Background(), other)
+	return union
+}
+
+func (set *ThreadUnsafeSet) UnionContext(ctx context.Context, other Set) (Set, error) {
+	o := set.unsafeOf(other)
+	t := newOpTracker(ctx, "union", set.Size()+o.Size())
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	if union, ok := set.combine(opUnion, o); ok {
+		t.finish()
+		return union, nil
 	}
-	for _, obj := range o.dat {
+	union := set.emptyLike(set.Size())
+	var err error
+	add := func(obj interface{}) bool {
+		if err = t.step(); err != nil {
+			return true
+		}
 		union.Add(obj)
+		return false
+	}
+	set.store.each(add)
+	if err == nil {
+		o.store.each(add)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.finish()
+	return &union, nil
+}
+
+func (set *ThreadUnsafeSet) UnionCardinality(other Set) int {
+	return set.Size() + other.Size() - set.IntersectCardinality(other)
+}
+
+func (set *ThreadUnsafeSet) UnionWith(other Set) {
+	_ = set.UnionWithContext(context.Background(), other)
+}
+
+// UnionWithContext is like UnionWith, but checks ctx while it runs and
+// aborts with an *OperationError once ctx is done. Unlike UnionContext,
+// an aborted UnionWithContext keeps the elements it added so far.
+// Progress is reported to the func registered with WithProgress.
+func (set *ThreadUnsafeSet) UnionWithContext(ctx context.Context, other Set) error {
+	o := set.unsafeOf(other)
+	t := newOpTracker(ctx, "union", o.Size())
+	if err := t.check(); err != nil {
+		return err
+	}
+	if o == set || set.combineWith(opUnion, o) {
+		t.finish()
+		return nil
+	}
+	var err error
+	o.store.each(func(obj interface{}) bool {
+		if err = t.step(); err != nil {
+			return true
+		}
+		set.Add(obj)
+		return false
+	})
+	if err != nil {
+		return err
 	}
-	return &union
+	t.finish()
+	return nil
 }
 
 func (set *ThreadUnsafeSet) Pop() (interface{}, bool) {
-	for hash, obj := range set.dat {
-		delete(set.dat, hash)
-		return obj, true
+	var (
+		obj   interface{}
+		found bool
+	)
+	set.store.each(func(elem interface{}) bool {
+		obj, found = elem, true
+		return true
+	})
+	if found {
+		set.store.remove(obj)
+	}
+	return obj, found
+}
+
+func (set *ThreadUnsafeSet) PopN(n int) []interface{} {
+	if n <= 0 {
+		return []interface{}{}
 	}
-	return nil, false
+	if size := set.Size(); n > size {
+		n = size
+	}
+	objs := make([]interface{}, 0, n)
+	set.store.each(func(obj interface{}) bool {
+		objs = append(objs, obj)
+		return len(objs) == n
+	})
+	for _, obj := range objs {
+		set.store.remove(obj)
+	}
+	return objs
+}
+
+func (set *ThreadUnsafeSet) PopIf(pred func(elem interface{}) bool) (interface{}, bool) {
+	var (
+		obj   interface{}
+		found bool
+	)
+	set.store.each(func(elem interface{}) bool {
+		if pred(elem) {
+			obj, found = elem, true
+		}
+		return found
+	})
+	if found {
+		set.store.remove(obj)
+	}
+	return obj, found
 }
 
 func (set *ThreadUnsafeSet) ToSlice() []interface{} {
 	objs := make([]interface{}, 0, set.Size())
-	for _, obj := range set.dat {
+	set.store.each(func(obj interface{}) bool {
 		objs = append(objs, obj)
-	}
+		return false
+	})
 	return objs
 }
 
+func (set *ThreadUnsafeSet) ToSortedSlice(less func(a, b interface{}) bool) []interface{} {
+	return sortSlice(set.ToSlice(), less)
+}
+
+func (set *ThreadUnsafeSet) ToMap() map[interface{}]struct{} {
+	m := make(map[interface{}]struct{}, set.Size())
+	set.store.each(func(obj interface{}) bool {
+		m[obj] = struct{}{}
+		return false
+	})
+	return m
+}
+
+// AsMap returns the map the set stores its elements in, without copying
+// it. The map must not be modified, and it reflects later changes of the
+// set, including Clear. Grow has no effect on the set from then on.
+//
+// Only elements of the native types are stored in a map like that. If
+// the set holds other elements, or it is a view created by AsSet or
+// AsSetOf, AsMap returns a copy like ToMap.
+func (set *ThreadUnsafeSet) AsMap() map[interface{}]struct{} {
+	if s, ok := unwrapStore(set.store, true).(*hashStore); ok && s.nHashed == 0 {
+		if s.vals == nil {
+			s.vals = make(map[interface{}]struct{}, s.capacity)
+		}
+		s.exposed = true
+		return s.vals
+	}
+	return set.ToMap()
+}
+
 func (set *ThreadUnsafeSet) MarshalJSON() ([]byte, error) {
 	items := make([]string, 0, set.Size())
 
-	for _, obj := range set.dat {
-		b, err := json.Marshal(obj)
-		if err != nil {
-			return nil, err
+	var err error
+	set.store.each(func(obj interface{}) bool {
+		var b []byte
+		if b, err = json.Marshal(obj); err != nil {
+			return true
 		}
 		items = append(items, string(b))
+		return false
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return []byte(fmt.Sprintf("[%s]", strings.Join(items, ","))), nil
 }
 
+func (set *ThreadUnsafeSet) MarshalJSONSorted() ([]byte, error) {
+	return marshalSorted(set.ToSlice())
+}
+
 func (set *ThreadUnsafeSet) UnmarshalJSON(b []byte) error {
+	if set.store == nil {
+		*set = newThreadUnsafeSet()
+	}
+	decoded := set.emptyLike(0)
+	decoded.typ = set.typ
+	if err := decoded.decodeJSON(b); err != nil {
+		return err
+	}
+	return set.replace(&decoded)
+}
+
+// decodeJSON adds the elements of a JSON array to the set.
+func (set *ThreadUnsafeSet) decodeJSON(b []byte) error {
 	var i []interface{}
 
 	d := json.NewDecoder(bytes.NewReader(b))
@@ -281,7 +971,32 @@ func (set *ThreadUnsafeSet) UnmarshalJSON(b []byte) error {
 		return err
 	}
 	for _, v := range i {
-		set.Add(v)
+		if _, err := set.TryAdd(v); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+// replace makes the elements of with, a set backed by the same kind of
+// store, the elements of set. An external store, or one whose map AsMap
+// returned, is kept and refilled, any other store is swapped for that of
+// with.
+func (set *ThreadUnsafeSet) replace(with *ThreadUnsafeSet) error {
+	_, keep := set.store.(externalStore)
+	if s, ok := set.store.(*hashStore); ok && s.exposed {
+		keep = true
+	}
+	if !keep {
+		set.store, set.typ = with.store, with.typ
+		return nil
+	}
+	set.store.clear()
+	var err error
+	with.store.each(func(elem interface{}) bool {
+		_, err = set.store.add(elem)
+		return err != nil
+	})
+	set.typ = with.typ
+	return err
+}
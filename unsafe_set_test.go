@@ -0,0 +1,1145 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_IsSubsetWithin(t *testing.T) {
+	s := NewThreadUnsafeSet(1, 2, 3, 4)
+	ss := NewThreadUnsafeSet(1, 2, 5)
+
+	if s.IsSubsetWithin(ss, 1) {
+		t.Errorf("%v is not a subset of %v within 1", s, ss)
+	}
+	if !s.IsSubsetWithin(ss, 2) {
+		t.Errorf("%v is a subset of %v within 2", s, ss)
+	}
+	if !ss.IsSupersetWithin(s, 2) || ss.IsSupersetWithin(s, 1) {
+		t.Errorf("Expected %v to be a superset of %v within exactly 2", ss, s)
+	}
+}
+
+func Test_UnionContextCanceled(t *testing.T) {
+	s, ss := NewThreadUnsafeSet(), NewThreadUnsafeSet()
+	for i := 0; i < N; i++ {
+		s.Add(i)
+		ss.Add(i + N)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.UnionContext(ctx, ss)
+	opErr, ok := err.(*OperationError)
+	if !ok || opErr.Err != context.Canceled {
+		t.Errorf("Expected a canceled *OperationError, got %v", err)
+	}
+	if s.Size() != N || ss.Size() != N {
+		t.Errorf("Operands were modified by an aborted union")
+	}
+
+	union, err := s.UnionContext(context.Background(), ss)
+	if err != nil || union.Size() != 2*N {
+		t.Errorf("Expected a union of %v elements, got %v (%v)", 2*N, union, err)
+	}
+}
+
+func Test_UnionContextProgress(t *testing.T) {
+	s, ss := NewThreadUnsafeSet(), NewThreadUnsafeSet()
+	for i := 0; i < N; i++ {
+		s.Add(i)
+		ss.Add(i + N)
+	}
+
+	var reports []Progress
+	ctx := WithProgress(context.Background(), 100, func(p Progress) {
+		reports = append(reports, p)
+	})
+	if _, err := s.UnionContext(ctx, ss); err != nil {
+		t.Fatalf("Error should be nil: %v", err)
+	}
+
+	if len(reports) != 2*N/100+1 {
+		t.Fatalf("Expected %v progress reports, got %v", 2*N/100+1, len(reports))
+	}
+	last := reports[len(reports)-1]
+	if !last.Done || last.Processed != 2*N || last.Total != 2*N || last.Op != "union" {
+		t.Errorf("Unexpected final progress report: %+v", last)
+	}
+}
+
+func Test_UnionWithContextProgress(t *testing.T) {
+	s, ss := NewSet(), NewSet()
+	const N = 1000
+	for i := 0; i < N; i++ {
+		ss.Add(i)
+	}
+
+	var reports []Progress
+	ctx := WithProgress(context.Background(), 100, func(p Progress) {
+		reports = append(reports, p)
+	})
+	if err := s.(*ThreadSafeSet).UnionWithContext(ctx, ss); err != nil {
+		t.Fatalf("Error should be nil: %v", err)
+	}
+
+	if s.Size() != N {
+		t.Errorf("Expected %v elements, got %v", N, s.Size())
+	}
+	if len(reports) != N/100+1 {
+		t.Fatalf("Expected %v progress reports, got %v", N/100+1, len(reports))
+	}
+	last := reports[len(reports)-1]
+	if !last.Done || last.Processed != N || last.Total != N || last.Op != "union" {
+		t.Errorf("Unexpected final progress report: %+v", last)
+	}
+}
+
+func Test_DifferenceWithContext(t *testing.T) {
+	s, ss := NewThreadUnsafeSet(), NewThreadUnsafeSet()
+	const N = 4000
+	for i := 0; i < N; i++ {
+		s.Add(i)
+		if i%2 == 0 {
+			ss.Add(i)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := s.(*ThreadUnsafeSet).DifferenceWithContext(ctx, ss)
+	if opErr, ok := err.(*OperationError); !ok || opErr.Op != "difference" {
+		t.Errorf("Expected a difference *OperationError, got %v", err)
+	}
+	if s.Size() != N {
+		t.Errorf("Set was modified by an aborted difference")
+	}
+
+	var reports []Progress
+	ctx = WithProgress(context.Background(), 100, func(p Progress) {
+		reports = append(reports, p)
+	})
+	if err := s.(*ThreadUnsafeSet).DifferenceWithContext(ctx, ss); err != nil {
+		t.Fatalf("Error should be nil: %v", err)
+	}
+
+	if s.Size() != N/2 || s.Contains(0) || !s.Contains(1) {
+		t.Errorf("Expected the odd numbers below %v, got %v elements", N, s.Size())
+	}
+	last := reports[len(reports)-1]
+	if !last.Done || last.Processed != N/2 || last.Total != N/2 || last.Op != "difference" {
+		t.Errorf("Unexpected final progress report: %+v", last)
+	}
+}
+
+func Test_UnionWithProvenance(t *testing.T) {
+	union := UnionWithProvenance(map[string]Set{
+		"a": NewSet(1, 2),
+		"b": NewSet(2, 3),
+	})
+
+	if union.Size() != 3 {
+		t.Errorf("Expected a union of 3 elements, got %v", union)
+	}
+	if sources := union.Sources(2); len(sources) != 2 || sources[0] != "a" || sources[1] != "b" {
+		t.Errorf("Expected element 2 to come from [a b], got %v", sources)
+	}
+	if sources := union.Sources(3); len(sources) != 1 || sources[0] != "b" {
+		t.Errorf("Expected element 3 to come from [b], got %v", sources)
+	}
+	if sources := union.Sources(4); sources != nil {
+		t.Errorf("Expected no sources for a missing element, got %v", sources)
+	}
+}
+
+func Test_AsSet(t *testing.T) {
+	m := map[string]struct{}{"a": {}, "b": {}}
+	s := AsSet(m)
+
+	if !s.Contains("a", "b") || s.Size() != 2 {
+		t.Errorf("Expected the view to contain the map keys, got %v", s)
+	}
+	s.Add("c")
+	s.Remove("a")
+	if _, ok := m["c"]; !ok {
+		t.Errorf("Element added to the view is missing from the map")
+	}
+	if _, ok := m["a"]; ok {
+		t.Errorf("Element removed from the view is still in the map")
+	}
+	m["d"] = struct{}{}
+	if !s.Contains("d") {
+		t.Errorf("Element added to the map is missing from the view")
+	}
+	s.Clear()
+	if len(m) != 0 {
+		t.Errorf("Expected clearing the view to clear the map, got %v", m)
+	}
+}
+
+func Test_AsSetStaysAttached(t *testing.T) {
+	m := map[string]struct{}{"a": {}}
+	s := AsSet(m)
+	if err := s.UnmarshalJSON([]byte(`["b","c","d"]`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["b"]; !ok || len(m) != 3 {
+		t.Errorf("Expected UnmarshalJSON to refill the map, got %v", m)
+	}
+
+	safe := s.(*ThreadUnsafeSet).ToThreadSafe()
+	clone := safe.Clone()
+	safe.Add("x")
+	clone.Add("y")
+	if _, ok := m["x"]; !ok {
+		t.Errorf("Expected a cloned view to still write to the map, got %v", m)
+	}
+	if _, ok := m["y"]; ok {
+		t.Errorf("Expected the clone to be independent of the map, got %v", m)
+	}
+}
+
+func Test_Grow(t *testing.T) {
+	s := NewThreadUnsafeSetWithCapacity(100)
+	if s.Size() != 0 {
+		t.Errorf("Expected an empty set, got %v", s)
+	}
+	for i := 0; i < 50; i++ {
+		s.Add(i)
+	}
+	s.Grow(1000)
+	s.Grow(0)
+	s.Grow(-1)
+	if s.Size() != 50 || !s.Contains(0, 25, 49) {
+		t.Errorf("Expected Grow to keep the elements, got %v", s)
+	}
+
+	hashed := NewThreadUnsafeSet(hashedInt(1), hashedInt(2))
+	hashed.Grow(10)
+	if !hashed.Contains(hashedInt(1), hashedInt(2)) || hashed.Size() != 2 {
+		t.Errorf("Expected Grow to keep hashed elements, got %v", hashed)
+	}
+
+	m := map[string]struct{}{"a": {}}
+	view := AsSet(m)
+	view.Grow(10)
+	view.Add("b")
+	if len(m) != 2 {
+		t.Errorf("Expected a grown view to still write to the map, got %v", m)
+	}
+}
+
+func Test_ToThreadSafe(t *testing.T) {
+	u := NewThreadUnsafeSet(1, 2).(*ThreadUnsafeSet)
+	store := u.store
+	s := u.ToThreadSafe()
+
+	if s.unsafeSet.store != store {
+		t.Errorf("Expected ToThreadSafe to adopt the storage instead of copying it")
+	}
+	s.Add(3)
+	if !s.Contains(1, 2, 3) || s.Size() != 3 {
+		t.Errorf("Expected {1, 2, 3}, got %v", s)
+	}
+	if _, err := s.TryAdd("a"); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected the element type to be kept, got %v", err)
+	}
+}
+
+func Test_NewSetFromChannel(t *testing.T) {
+	ch := make(chan interface{})
+	go func() {
+		for i := 0; i < N; i++ {
+			ch <- i % 10
+		}
+		close(ch)
+	}()
+
+	s := NewSetFromChannel(ch)
+	if s.Size() != 10 {
+		t.Errorf("Expected 10 distinct elements, got %v", s)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s, err := NewSetFromChannelContext(ctx, make(chan interface{}))
+	if _, ok := err.(*OperationError); !ok || s.Size() != 0 {
+		t.Errorf("Expected an empty set and an *OperationError, got %v, %v", s, err)
+	}
+}
+
+func Test_InPlaceOperations(t *testing.T) {
+	s := NewThreadUnsafeSet(1, 2, 3)
+	s.UnionWith(NewThreadUnsafeSet(3, 4))
+	if !s.Equal(NewThreadUnsafeSet(1, 2, 3, 4)) {
+		t.Errorf("Unexpected result of UnionWith: %v", s)
+	}
+	s.DifferenceWith(NewThreadUnsafeSet(1, 5))
+	if !s.Equal(NewThreadUnsafeSet(2, 3, 4)) {
+		t.Errorf("Unexpected result of DifferenceWith: %v", s)
+	}
+	s.IntersectWith(NewThreadUnsafeSet(2, 3, 5))
+	if !s.Equal(NewThreadUnsafeSet(2, 3)) {
+		t.Errorf("Unexpected result of IntersectWith: %v", s)
+	}
+	s.SymmetricDifferenceWith(NewThreadUnsafeSet(3, 6))
+	if !s.Equal(NewThreadUnsafeSet(2, 6)) {
+		t.Errorf("Unexpected result of SymmetricDifferenceWith: %v", s)
+	}
+	s.SymmetricDifferenceWith(s)
+	if s.Size() != 0 {
+		t.Errorf("Expected an empty set, got %v", s)
+	}
+}
+
+func Test_IsDisjoint(t *testing.T) {
+	s := NewThreadUnsafeSet(1, 2, 3)
+
+	if !s.IsDisjoint(NewThreadUnsafeSet(4, 5)) || s.Overlaps(NewThreadUnsafeSet(4, 5)) {
+		t.Errorf("Expected %v to be disjoint from {4, 5}", s)
+	}
+	if s.IsDisjoint(NewThreadUnsafeSet(3, 4)) || !s.Overlaps(NewThreadUnsafeSet(3, 4)) {
+		t.Errorf("Expected %v to overlap {3, 4}", s)
+	}
+	if !s.IsDisjoint(NewThreadUnsafeSet()) {
+		t.Errorf("Expected %v to be disjoint from the empty set", s)
+	}
+}
+
+func Test_OperationCardinality(t *testing.T) {
+	s := NewThreadUnsafeSet(1, 2, 3, 4)
+	ss := NewThreadUnsafeSet(3, 4, 5)
+
+	if n := s.IntersectCardinality(ss); n != 2 {
+		t.Errorf("Expected an intersection of 2 elements, got %v", n)
+	}
+	if n := s.DifferenceCardinality(ss); n != 2 {
+		t.Errorf("Expected a difference of 2 elements, got %v", n)
+	}
+	if n := s.UnionCardinality(ss); n != 5 {
+		t.Errorf("Expected a union of 5 elements, got %v", n)
+	}
+}
+
+func Test_NaryOperations(t *testing.T) {
+	a, b, c := NewSet(1, 2, 3), NewSet(2, 3, 4), NewSet(3, 4, 5)
+
+	if union := Union(a, b, c); !union.Equal(NewSet(1, 2, 3, 4, 5)) {
+		t.Errorf("Unexpected union: %v", union)
+	}
+	if intersection := Intersect(a, b, c); !intersection.Equal(NewSet(3)) {
+		t.Errorf("Unexpected intersection: %v", intersection)
+	}
+	if a.Size() != 3 || b.Size() != 3 || c.Size() != 3 {
+		t.Errorf("Operands were modified")
+	}
+}
+
+func Test_Filter(t *testing.T) {
+	s := NewSet(1, 2, 3, 4, 5, 6)
+
+	even := s.Filter(func(elem interface{}) bool {
+		return elem.(int)%2 == 0
+	})
+	if !even.Equal(NewSet(2, 4, 6)) {
+		t.Errorf("Unexpected filtered set: %v", even)
+	}
+}
+
+func Test_Map(t *testing.T) {
+	s := NewSet(1, 2, 3, 4)
+
+	mapped := Map(s, func(elem interface{}) interface{} {
+		return elem.(int) % 2
+	})
+	if !mapped.Equal(NewSet(0, 1)) {
+		t.Errorf("Unexpected mapped set: %v", mapped)
+	}
+}
+
+func Test_Reduce(t *testing.T) {
+	s := NewSet(1, 2, 3, 4)
+
+	sum := Reduce(s, 0, func(acc, elem interface{}) interface{} {
+		return acc.(int) + elem.(int)
+	})
+	if sum != 10 {
+		t.Errorf("Expected a sum of 10, got %v", sum)
+	}
+}
+
+func Test_GroupBy(t *testing.T) {
+	s := NewSet("a1", "a2", "b1")
+
+	groups := s.GroupBy(func(elem interface{}) string {
+		return elem.(string)[:1]
+	})
+	if len(groups) != 2 || !groups["a"].Equal(NewSet("a1", "a2")) || !groups["b"].Equal(NewSet("b1")) {
+		t.Errorf("Unexpected groups: %v", groups)
+	}
+}
+
+func Test_Predicates(t *testing.T) {
+	s := NewSet(2, 4, 6)
+	even := func(elem interface{}) bool { return elem.(int)%2 == 0 }
+	big := func(elem interface{}) bool { return elem.(int) > 5 }
+
+	if !s.All(even) || s.Any(func(elem interface{}) bool { return !even(elem) }) {
+		t.Errorf("Expected all elements of %v to be even", s)
+	}
+	if !s.Any(big) || s.All(big) || s.None(big) {
+		t.Errorf("Expected some elements of %v to be big", s)
+	}
+	if !NewSet().All(big) || !NewSet().None(big) {
+		t.Errorf("Unexpected predicate result for the empty set")
+	}
+}
+
+func Test_MinMax(t *testing.T) {
+	s := NewSet(3, 1, 10, -2)
+
+	if min, ok := s.Min(); !ok || min != -2 {
+		t.Errorf("Expected a minimum of -2, got %v", min)
+	}
+	if max, ok := s.Max(); !ok || max != 10 {
+		t.Errorf("Expected a maximum of 10, got %v", max)
+	}
+	byAbs := func(a, b interface{}) bool {
+		x, y := a.(int), b.(int)
+		return x*x < y*y
+	}
+	if min, _ := s.MinBy(byAbs); min != 1 {
+		t.Errorf("Expected a minimum of 1 by absolute value, got %v", min)
+	}
+	if _, ok := NewSet().Max(); ok {
+		t.Errorf("Expected no maximum for the empty set")
+	}
+}
+
+func Test_PowerSet(t *testing.T) {
+	s := NewSet(1, 2, 3)
+
+	ps := s.PowerSet()
+	if ps.Size() != 8 {
+		t.Errorf("Expected a power set of 8 elements, got %v", ps)
+	}
+	if !ps.Contains(NewSet(), NewSet(1, 3), NewSet(3, 2, 1)) {
+		t.Errorf("Power set is missing subsets: %v", ps)
+	}
+
+	n := 0
+	for subset := range s.PowerSetIterator().C {
+		if !ps.Contains(subset) {
+			t.Errorf("Unexpected subset: %v", subset)
+		}
+		n++
+	}
+	if n != 8 {
+		t.Errorf("Expected 8 subsets, got %v", n)
+	}
+}
+
+func Test_CartesianProduct(t *testing.T) {
+	product := CartesianProduct(NewSet(1, 2), NewSet("a", "b", "c"))
+
+	if product.Size() != 6 {
+		t.Errorf("Expected a product of 6 pairs, got %v", product)
+	}
+	if !product.Contains(Pair{First: 1, Second: "a"}, Pair{First: 2, Second: "c"}) {
+		t.Errorf("Product is missing pairs: %v", product)
+	}
+	if product.Contains(Pair{First: "a", Second: 1}) {
+		t.Errorf("Product contains a reversed pair: %v", product)
+	}
+	if product.Contains(Pair{First: "1", Second: "a"}) {
+		t.Errorf("Product contains a pair with an element of another type: %v", product)
+	}
+}
+
+func Test_HashElementTypes(t *testing.T) {
+	if NewSet(1).Hash() == NewSet("1").Hash() {
+		t.Errorf("Expected sets of elements of different types to have different hashes")
+	}
+	if NewSet(1, 2).Hash() != NewSet(2, 1).Hash() {
+		t.Errorf("Expected equal sets to have equal hashes")
+	}
+	sets := NewSet(NewFrozenSet(1), NewFrozenSet("1"))
+	if sets.Size() != 2 {
+		t.Errorf("Expected two distinct frozen sets, got %v", sets)
+	}
+}
+
+func Test_Combinations(t *testing.T) {
+	s := NewThreadUnsafeSet(1, 2, 3, 4)
+
+	seen := NewThreadUnsafeSet()
+	for subset := range s.Combinations(2).C {
+		if subset.(Set).Size() != 2 || !subset.(Set).IsSubset(s) {
+			t.Errorf("Unexpected combination: %v", subset)
+		}
+		seen.Add(subset)
+	}
+	if seen.Size() != 6 {
+		t.Errorf("Expected 6 distinct combinations, got %v", seen)
+	}
+
+	it := s.Combinations(1)
+	<-it.C
+	it.Stop()
+
+	if _, ok := <-s.Combinations(5).C; ok {
+		t.Errorf("Expected no combinations of more elements than the set has")
+	}
+}
+
+func Test_RandomSample(t *testing.T) {
+	s := NewSet()
+	for i := 0; i < N; i++ {
+		s.Add(i)
+	}
+
+	a := s.RandomSample(10, rand.New(rand.NewSource(42)))
+	b := s.Clone().RandomSample(10, rand.New(rand.NewSource(42)))
+	if len(a) != 10 || NewSet(a...).Size() != 10 {
+		t.Fatalf("Expected 10 distinct elements, got %v", a)
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("Expected equally seeded samples to match, got %v and %v", a, b)
+		}
+	}
+
+	if all := s.RandomSample(2*N, nil); len(all) != N {
+		t.Errorf("Expected the whole set, got %v elements", len(all))
+	}
+	if _, ok := NewSet().RandomElement(nil); ok {
+		t.Errorf("Expected no element from the empty set")
+	}
+	for seed := int64(0); seed < 10; seed++ {
+		x, _ := s.RandomElement(rand.New(rand.NewSource(seed)))
+		y, _ := s.Clone().RandomElement(rand.New(rand.NewSource(seed)))
+		if x != y {
+			t.Fatalf("Expected equally seeded elements to match, got %v and %v", x, y)
+		}
+	}
+}
+
+func Test_PopN(t *testing.T) {
+	s := NewSet(1, 2, 3, 4, 5)
+	popped := s.PopN(2)
+	if len(popped) != 2 || s.Size() != 3 || s.Contains(popped...) {
+		t.Errorf("Expected 2 popped elements, got %v leaving %v", popped, s)
+	}
+	if popped = s.PopN(10); len(popped) != 3 || s.Size() != 0 {
+		t.Errorf("Expected the remaining 3 elements, got %v", popped)
+	}
+
+	s = NewThreadUnsafeSet(1, 2, 3)
+	obj, ok := s.PopIf(func(elem interface{}) bool { return elem.(int) > 2 })
+	if !ok || obj != 3 || s.Contains(3) {
+		t.Errorf("Expected to pop 3, got %v", obj)
+	}
+	if _, ok = s.PopIf(func(elem interface{}) bool { return elem.(int) > 2 }); ok {
+		t.Errorf("Expected no element to match")
+	}
+}
+
+func Test_RemoveIf(t *testing.T) {
+	s := NewSet(1, 2, 3, 4, 5, 6)
+	if n := s.RemoveIf(func(elem interface{}) bool { return elem.(int)%2 == 0 }); n != 3 || !s.Equal(NewSet(1, 3, 5)) {
+		t.Errorf("Expected 3 even elements removed, got %v leaving %v", n, s)
+	}
+	if n := s.RetainIf(func(elem interface{}) bool { return elem.(int) > 1 }); n != 1 || !s.Equal(NewSet(3, 5)) {
+		t.Errorf("Expected 1 element removed, got %v leaving %v", n, s)
+	}
+}
+
+func Test_EachErr(t *testing.T) {
+	s := NewSet(1, 2, 3)
+	errStop := errors.New("stop")
+	visited := 0
+	err := s.EachErr(func(elem interface{}) error {
+		visited++
+		return errStop
+	})
+	if err != errStop || visited != 1 {
+		t.Errorf("Expected iteration to stop with the first error, got %v after %v elements", err, visited)
+	}
+	if err = s.EachErr(func(elem interface{}) error { return nil }); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func Test_Cursor(t *testing.T) {
+	s := NewSet(1, 2, 3)
+	c := s.Cursor()
+	s.Add(4) // the cursor works on a snapshot
+	seen := NewSet()
+	for elem, ok := c.Next(); ok; elem, ok = c.Next() {
+		seen.Add(elem)
+	}
+	if !seen.Equal(NewSet(1, 2, 3)) || c.Remaining() != 0 {
+		t.Errorf("Expected the cursor to yield the snapshot, got %v", seen)
+	}
+	if _, ok := c.Next(); ok {
+		t.Errorf("Expected an exhausted cursor to stay exhausted")
+	}
+}
+
+func Test_ToMap(t *testing.T) {
+	s := NewThreadUnsafeSet(1, 2).(*ThreadUnsafeSet)
+	m := s.ToMap()
+	if _, ok := m[2]; !ok || len(m) != 2 {
+		t.Errorf("Expected a map of both elements, got %v", m)
+	}
+
+	view := s.AsMap()
+	s.Add(3)
+	if _, ok := view[3]; !ok || len(view) != 3 {
+		t.Errorf("Expected AsMap to share the set's storage, got %v", view)
+	}
+	if m := AsSet(map[string]struct{}{"a": {}}).(*ThreadUnsafeSet).AsMap(); len(m) != 1 {
+		t.Errorf("Expected AsMap of a map view to contain its elements, got %v", m)
+	}
+
+	s.Grow(1000)
+	s.Add(4)
+	if _, ok := view[4]; !ok {
+		t.Errorf("Expected AsMap to share the set's storage after Grow, got %v", view)
+	}
+	s.Clear()
+	s.Add(5)
+	if _, ok := view[5]; !ok || len(view) != 1 {
+		t.Errorf("Expected AsMap to share the set's storage after Clear, got %v", view)
+	}
+	if err := s.UnmarshalJSON([]byte("[]")); err != nil || len(view) != 0 {
+		t.Errorf("Expected AsMap to share the set's storage after UnmarshalJSON, got %v, %v", view, err)
+	}
+}
+
+func Test_DirectKeys(t *testing.T) {
+	s := NewThreadUnsafeSet(0.123456781, 0.123456789)
+	if s.Size() != 2 {
+		t.Errorf("Expected floats to be told apart exactly, got %v", s)
+	}
+	if NewThreadUnsafeSet(1).Contains(int64(1)) {
+		t.Errorf("Expected elements of other types not to be found")
+	}
+
+	nan := NewThreadUnsafeSet(math.NaN(), math.NaN())
+	if nan.Size() != 1 || !nan.Contains(math.NaN()) {
+		t.Errorf("Expected NaN to be a single element, got %v", nan)
+	}
+}
+
+type hashedInt int
+
+func (h hashedInt) Hash() string {
+	return strconv.Itoa(int(h))
+}
+
+func Test_HashedElements(t *testing.T) {
+	s := NewThreadUnsafeSet()
+	for i := 0; i < N; i++ {
+		s.Add(hashedInt(i))
+	}
+	for i := 0; i < N; i += 2 {
+		s.Remove(hashedInt(i))
+	}
+	if s.Size() != N/2 || s.Add(hashedInt(1)) || !s.Contains(hashedInt(N-1)) || s.Contains(hashedInt(0)) {
+		t.Errorf("Expected the odd elements to remain, got %v", s.Size())
+	}
+	if len(s.ToSlice()) != N/2 {
+		t.Errorf("Expected iteration to visit %v elements", N/2)
+	}
+}
+
+type parityInt int
+
+func (p parityInt) Hash() string {
+	return strconv.Itoa(int(p) % 2)
+}
+
+func (p parityInt) Equal(other interface{}) bool {
+	return p == other.(parityInt)
+}
+
+type account struct {
+	ID   int
+	Name string
+}
+
+func (a account) Hash() string {
+	return strconv.Itoa(a.ID)
+}
+
+type foldedWords []string
+
+func (w foldedWords) Hash() string {
+	return strconv.Itoa(len(w))
+}
+
+func (w foldedWords) Equal(other interface{}) bool {
+	o := other.(foldedWords)
+	for i := range w {
+		if strings.ToLower(w[i]) != strings.ToLower(o[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// countedInt counts the calls of its Hash, and collides for all values.
+type countedInt int
+
+var countedHashes int
+
+func (c countedInt) Hash() string {
+	countedHashes++
+	return "c"
+}
+
+func (c countedInt) Equal(other interface{}) bool {
+	return c == other.(countedInt)
+}
+
+func Test_HashOncePerCall(t *testing.T) {
+	s := NewThreadUnsafeSet()
+	for i := 0; i < 100; i++ {
+		s.Add(countedInt(i))
+	}
+	countedHashes = 0
+	s.Contains(countedInt(50))
+	s.Remove(countedInt(10))
+	s.Add(countedInt(10))
+	if countedHashes != 3 {
+		t.Errorf("Expected one Hash call per operation, got %v", countedHashes)
+	}
+}
+
+func Test_HashCollisions(t *testing.T) {
+	s := NewThreadUnsafeSet(parityInt(1), parityInt(3), parityInt(3))
+	if s.Size() != 2 || !s.Contains(parityInt(3)) || s.Contains(parityInt(5)) {
+		t.Errorf("Expected colliding elements to be kept apart, got %v", s)
+	}
+	s.Remove(parityInt(1))
+	if s.Size() != 1 || !s.Contains(parityInt(3)) {
+		t.Errorf("Expected only parityInt(1) to be removed, got %v", s)
+	}
+
+	words := NewThreadUnsafeSet(foldedWords{"a", "b"}, foldedWords{"A", "B"}, foldedWords{"a", "c"})
+	if words.Size() != 2 || !words.Contains(foldedWords{"A", "c"}) {
+		t.Errorf("Expected elements to be compared by Equal, got %v", words)
+	}
+
+	accounts := NewThreadUnsafeSet(account{1, "old"})
+	if accounts.Add(account{1, "new"}) || accounts.Size() != 1 || !accounts.Contains(account{1, "x"}) {
+		t.Errorf("Expected the hash to define identity without Equal, got %v", accounts)
+	}
+	if stored, ok := accounts.Get(account{ID: 1}); !ok || stored != (account{1, "old"}) {
+		t.Errorf("Expected the stored account, got %v, %v", stored, ok)
+	}
+	accounts.Remove(account{ID: 1})
+	if accounts.Size() != 0 {
+		t.Errorf("Expected the account to be removed by its ID, got %v", accounts)
+	}
+}
+
+func Test_FloatModes(t *testing.T) {
+	negZero, nan := math.Copysign(0, -1), math.NaN()
+	otherNaN := math.Float64frombits(math.Float64bits(nan) + 1)
+	for _, s := range []Set{NewThreadUnsafeSet(), NewOrderedSet()} {
+		s.Append(0.123456781, 0.123456789, 0.0, negZero, nan, otherNaN)
+		if s.Size() != 4 || !s.Contains(nan) {
+			t.Errorf("Expected 0 and -0, and all NaNs, to be one element each, got %v", s)
+		}
+	}
+
+	bits := NewThreadUnsafeSet(WithFloatMode(FloatsByBits), 0.0, negZero, nan, otherNaN, nan)
+	if bits.Size() != 4 || !bits.Contains(negZero) || !bits.Contains(otherNaN) {
+		t.Errorf("Expected 0, -0 and both NaNs to be different elements, got %v", bits)
+	}
+	bits.Remove(nan)
+	if bits.Size() != 3 || bits.Contains(nan) || !bits.Clone().Contains(otherNaN) {
+		t.Errorf("Expected only the first NaN to be removed, got %v", bits)
+	}
+}
+
+func Test_StdlibElements(t *testing.T) {
+	now := time.Now()
+	times := NewThreadUnsafeSet(now, now.UTC(), now.Add(time.Second))
+	if times.Size() != 2 || !times.Contains(now.Round(0).In(time.FixedZone("X", 3600))) {
+		t.Errorf("Expected times to be identified by their instant, got %v", times)
+	}
+	if sorted := times.ToSortedSlice(nil); !sorted[0].(time.Time).Equal(now) {
+		t.Errorf("Expected times to be sorted chronologically, got %v", sorted)
+	}
+
+	ips := NewThreadUnsafeSet(net.ParseIP("10.0.0.1"), net.IPv4(10, 0, 0, 1).To4(), net.ParseIP("::1"))
+	if ips.Size() != 2 || !ips.Contains(net.IP{10, 0, 0, 1}) {
+		t.Errorf("Expected IPv4 addresses to be one element in both forms, got %v", ips)
+	}
+	ips.Remove(net.ParseIP("10.0.0.1"))
+	if ips.Size() != 1 {
+		t.Errorf("Expected the IPv4 address to be removed, got %v", ips)
+	}
+
+	if uuids := NewThreadUnsafeSet([16]byte{1}, [16]byte{1}, [16]byte{2}); uuids.Size() != 2 {
+		t.Errorf("Expected 2 uuids, got %v", uuids)
+	}
+	if durations := NewOrderedSet(time.Second, time.Second, time.Minute); durations.Size() != 2 {
+		t.Errorf("Expected 2 durations, got %v", durations)
+	}
+	if bytes := NewOrderedSet(uint8(1), uint8(1)); bytes.Size() != 1 {
+		t.Errorf("Expected 1 byte, got %v", bytes)
+	}
+}
+
+func Test_ByteSliceElements(t *testing.T) {
+	s := NewThreadUnsafeSet([]byte("b"), []byte("a"), []byte("b"))
+	if s.Size() != 2 || !s.Contains([]byte("a")) || s.Contains([]byte("c")) {
+		t.Errorf("Expected byte slices to be identified by their contents, got %v", s)
+	}
+	if sorted := s.ToSortedSlice(nil); string(sorted[0].([]byte)) != "a" {
+		t.Errorf("Expected byte slices to be sorted by their contents, got %v", sorted)
+	}
+	s.Remove([]byte("b"))
+	if s.Size() != 1 || s.Contains([]byte("b")) {
+		t.Errorf("Expected []byte(\"b\") to be removed, got %v", s)
+	}
+}
+
+func Test_TryOperations(t *testing.T) {
+	for _, s := range []Set{NewThreadUnsafeSet(1, 2), NewSet(1, 2), NewTieredSet(NewSet(1, 2), 10, 0.01)} {
+		if added, err := s.TryAdd(3); !added || err != nil {
+			t.Errorf("Expected 3 to be added, got %v, %v", added, err)
+		}
+		if added, err := s.TryAdd("3"); added || err == nil {
+			t.Errorf("Expected an error adding a string to %v", s)
+		}
+		if _, err := s.TryAdd([]int{3}); err == nil {
+			t.Errorf("Expected an error adding an unhashable element to %v", s)
+		}
+		if err := s.TryRemove(struct{}{}); err == nil {
+			t.Errorf("Expected an error removing an unhashable element from %v", s)
+		}
+		if err := s.TryRemove(3); err != nil || s.Contains(3) {
+			t.Errorf("Expected 3 to be removed, got %v", err)
+		}
+	}
+
+	a, b := NewThreadUnsafeSet(1, 2), NewThreadUnsafeSet(2, 3)
+	if union, err := a.TryUnion(b); err != nil || !union.Equal(NewThreadUnsafeSet(1, 2, 3)) {
+		t.Errorf("Expected {1, 2, 3}, got %v, %v", union, err)
+	}
+	if diff, err := a.TrySymmetricDifference(b); err != nil || !diff.Equal(NewThreadUnsafeSet(1, 3)) {
+		t.Errorf("Expected {1, 3}, got %v, %v", diff, err)
+	}
+	if _, err := NewThreadUnsafeBitSet(1).TryIntersect(NewSet("1")); err == nil {
+		t.Errorf("Expected an error combining a bit set with strings")
+	}
+	strs := NewThreadUnsafeSet("1")
+	if _, err := a.TryUnion(strs); err == nil {
+		t.Errorf("Expected an error for a union of ints and strings")
+	}
+	if diff, err := a.TryDifference(strs); err != nil || !diff.Equal(a) {
+		t.Errorf("Expected the difference with strings to be %v, got %v, %v", a, diff, err)
+	}
+
+	safe := NewSet(1, 2)
+	if inter, err := safe.TryIntersect(NewSortedSet(nil, 2, 3)); err != nil || !inter.Equal(NewSet(2)) {
+		t.Errorf("Expected {2}, got %v, %v", inter, err)
+	}
+	if _, err := safe.TryUnion(NewThreadUnsafeSet("1")); err == nil {
+		t.Errorf("Expected an error for a union of ints and strings")
+	}
+}
+
+func Test_SentinelErrors(t *testing.T) {
+	s := NewThreadUnsafeSet(1)
+	if _, err := NewThreadUnsafeSet().TryAdd([]int{1}); !errors.Is(err, ErrNotHashable) {
+		t.Errorf("Expected ErrNotHashable, got %v", err)
+	}
+	var mismatch *TypeMismatchError
+	if _, err := s.TryAdd("1"); !errors.Is(err, ErrTypeMismatch) || !errors.As(err, &mismatch) || mismatch.Got.Kind() != reflect.String {
+		t.Errorf("Expected a *TypeMismatchError for a string, got %v", err)
+	}
+	if _, err := s.TryUnion(nil); !errors.Is(err, ErrIncompatibleSet) {
+		t.Errorf("Expected ErrIncompatibleSet, got %v", err)
+	}
+	if _, err := NewThreadUnsafeBitSet().TryAdd(-1); !errors.Is(err, ErrNotHashable) {
+		t.Errorf("Expected ErrNotHashable for a negative int in a bit set, got %v", err)
+	}
+	if err := s.UnmarshalJSON([]byte(`["a"]`)); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected ErrTypeMismatch decoding strings into a set of ints, got %v", err)
+	}
+	if err := NewSet().UnmarshalJSON([]byte(`[{"a": 1}]`)); !errors.Is(err, ErrNotHashable) {
+		t.Errorf("Expected ErrNotHashable decoding an object, got %v", err)
+	}
+}
+
+func Test_CrossImplementationOperations(t *testing.T) {
+	safe, unsafe := NewSet(1, 2, 3), NewThreadUnsafeSet(2, 3, 4)
+	tiered := NewTieredSet(NewSet(3, 4), 10, 0.01)
+	for _, pair := range [][2]Set{{safe, unsafe}, {unsafe, safe}, {safe, tiered}, {unsafe, tiered}} {
+		a, b := pair[0], pair[1]
+		union, inter := a.Union(b), a.Intersect(b)
+		if !union.IsSuperset(a) || !union.IsSuperset(b) || union.Size() != a.Size()+b.Size()-inter.Size() ||
+			!inter.IsSubset(a) || !inter.IsSubset(b) {
+			t.Errorf("Expected %v and %v to combine, got %v and %v", a, b, union, inter)
+		}
+		if !a.Difference(b).Union(inter).Equal(a) {
+			t.Errorf("Expected the difference and the intersection of %v to make up %v", b, a)
+		}
+		if !a.SymmetricDifference(b).Equal(union.Difference(inter)) {
+			t.Errorf("Expected the symmetric difference of %v and %v to be %v", a, b, union.Difference(inter))
+		}
+	}
+	if !safe.Equal(NewThreadUnsafeSet(3, 2, 1)) || !NewOrderedSet(1, 2).IsSubset(safe) {
+		t.Errorf("Expected sets of different types to compare by their elements")
+	}
+	unsafe.UnionWith(NewBitSet(7))
+	if !unsafe.Contains(7) {
+		t.Errorf("Expected the bit set to be merged into %v", unsafe)
+	}
+}
+
+func Test_WithMixedTypes(t *testing.T) {
+	s := NewSet(WithMixedTypes(), 1, int64(1), "1", hashedInt(1), true)
+	if s.Size() != 5 || !s.Contains(int64(1)) || !s.Contains(hashedInt(1)) || s.Contains(int8(1)) {
+		t.Errorf("Expected elements of different types to be different elements, got %v", s)
+	}
+	if union := s.Union(NewSet(2.5)); union.Size() != 6 || !union.Clone().Add(false) {
+		t.Errorf("Expected results of operations to allow mixed types, got %v", union)
+	}
+
+	decoded := NewThreadUnsafeSet(WithMixedTypes())
+	if err := decoded.UnmarshalJSON([]byte(`[1, "a", true, 1]`)); err != nil || decoded.Size() != 3 {
+		t.Errorf("Expected the JSON scalars to be decoded, got %v, %v", decoded, err)
+	}
+	if _, err := NewThreadUnsafeSet(1).TryAdd("1"); err == nil {
+		t.Errorf("Expected sets to hold a single type by default")
+	}
+}
+
+func Test_Similarity(t *testing.T) {
+	a, b := NewThreadUnsafeSet(1, 2, 3, 4), NewSet(3, 4, 5)
+	if j := a.Jaccard(b); j != 0.4 {
+		t.Errorf("Expected a Jaccard index of 2/5, got %v", j)
+	}
+	if d := b.Dice(a); math.Abs(d-4.0/7) > 1e-9 {
+		t.Errorf("Expected a Dice coefficient of 4/7, got %v", d)
+	}
+	if o := a.Overlap(NewSet(1, 2)); o != 1 {
+		t.Errorf("Expected an overlap of 1 for a subset, got %v", o)
+	}
+	empty := NewSet()
+	if empty.Jaccard(NewSet()) != 1 || empty.Dice(a) != 0 || a.Overlap(empty) != 0 {
+		t.Errorf("Unexpected similarity of empty sets")
+	}
+	if j := NewFrozenSet("a", "b").Jaccard(NewFrozenSet("b")); j != 0.5 {
+		t.Errorf("Expected a Jaccard index of 1/2, got %v", j)
+	}
+}
+
+func Test_Diff(t *testing.T) {
+	actual, desired := NewSet("a", "b", "c"), NewThreadUnsafeSet("b", "c", "d", "e")
+	added, removed := actual.Diff(desired)
+	if !added.Equal(NewSet("d", "e")) || !removed.Equal(NewSet("a")) {
+		t.Errorf("Expected to add {d, e} and remove {a}, got %v and %v", added, removed)
+	}
+	added.Add("f")
+	if actual.Contains("f") || desired.Contains("f") {
+		t.Errorf("Expected the diff to be independent of the sets")
+	}
+	if added, removed := desired.Diff(desired); added.Size() != 0 || removed.Size() != 0 {
+		t.Errorf("Expected an empty diff of a set with itself")
+	}
+}
+
+func Test_ChangeSet(t *testing.T) {
+	actual, desired := NewSet(1, 2, 3), NewSet(2, 3, 4)
+	cs := NewChangeSet(actual.Diff(desired))
+	b, err := cs.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var replicated ChangeSet
+	if err := replicated.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	replica := NewThreadUnsafeSet(1, 2, 3)
+	actual.Apply(cs)
+	replica.Apply(replicated)
+	if !actual.Equal(desired) || !replica.Equal(desired) {
+		t.Errorf("Expected the change to turn the sets into %v, got %v and %v", desired, actual, replica)
+	}
+	if !NewChangeSet(actual.Diff(desired)).IsEmpty() || NewChangeSet(nil, nil).String() != "+goset.ThreadUnsafeSet{  } -goset.ThreadUnsafeSet{  }" {
+		t.Errorf("Expected an empty change")
+	}
+
+	bad := ChangeSet{Adds: NewSet(WithMixedTypes(), 5, "x"), Removes: NewSet(2)}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected Apply to panic for an element of another type")
+			}
+		}()
+		actual.Apply(bad)
+	}()
+	if !actual.Equal(desired) {
+		t.Errorf("Expected a failed change to be undone, got %v", actual)
+	}
+}
+
+func Test_UpdateView(t *testing.T) {
+	s := NewThreadUnsafeSet(1, 2, 3)
+	s.Update(func(tx SetTx) {
+		if !tx.Remove(1) || tx.Remove(1) || tx.Remove(4) {
+			t.Errorf("Expected only the first removal of 1 to succeed")
+		}
+		if !tx.Add(4) || tx.Add(4) || tx.Add(2) || !tx.Add(1) || !tx.Remove(1) {
+			t.Errorf("Unexpected results of Add")
+		}
+		if tx.Size() != 3 || !tx.Contains(2, 3, 4) || tx.Contains(1) {
+			t.Errorf("Expected the view to hold 2, 3 and 4")
+		}
+		n := 0
+		tx.Each(func(elem interface{}) bool {
+			n++
+			return false
+		})
+		if n != 3 {
+			t.Errorf("Expected Each to visit 3 elements, visited %v", n)
+		}
+		if s.Contains(4) || !s.Contains(1) {
+			t.Errorf("Set was modified before the transaction committed")
+		}
+	})
+	if !s.Equal(NewThreadUnsafeSet(2, 3, 4)) {
+		t.Errorf("Expected 2, 3 and 4, got %v", s)
+	}
+
+	s.Update(func(tx SetTx) {
+		tx.Add(5)
+		tx.Rollback()
+		tx.Remove(2)
+	})
+	if !s.Equal(NewThreadUnsafeSet(3, 4)) {
+		t.Errorf("Expected 3 and 4 after a rollback, got %v", s)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected adding a string to panic")
+			}
+		}()
+		s.Update(func(tx SetTx) {
+			tx.Remove(3)
+			tx.Add("a")
+		})
+	}()
+	if !s.Equal(NewThreadUnsafeSet(3, 4)) {
+		t.Errorf("Set was modified by a panicking transaction, got %v", s)
+	}
+}
+
+func Test_Get(t *testing.T) {
+	for _, s := range []Set{
+		NewThreadUnsafeSet(foldedWords{"a", "b"}),
+		NewSet(foldedWords{"a", "b"}),
+		NewSet(WithSyncMapBackend(), foldedWords{"a", "b"}),
+		NewShardedSet(4, foldedWords{"a", "b"}),
+	} {
+		stored, ok := s.Get(foldedWords{"A", "B"})
+		if w, _ := stored.(foldedWords); !ok || len(w) != 2 || w[0] != "a" || w[1] != "b" {
+			t.Errorf("Expected the stored element [a b], got %v, %v", stored, ok)
+		}
+		if stored, ok := s.Get(foldedWords{"a"}); ok {
+			t.Errorf("Expected no element, got %v", stored)
+		}
+		if _, ok := s.Get([]int{1}); ok {
+			t.Errorf("Expected no element for an unhashable value")
+		}
+	}
+}
+
+type versioned struct {
+	id, version int
+}
+
+func (v versioned) Hash() string {
+	return strconv.Itoa(v.id)
+}
+
+func (v versioned) Equal(other interface{}) bool {
+	return v.id == other.(versioned).id
+}
+
+func Test_Replace(t *testing.T) {
+	for _, s := range []Set{
+		NewThreadUnsafeSet(1, 2, 3),
+		NewSet(1, 2, 3),
+		NewShardedSet(4, 1, 2, 3),
+		NewTieredSet(NewSet(1, 2, 3), N, 0.01),
+	} {
+		if s.Replace(4, 5) || !s.Replace(1, 10) || !s.Replace(2, 3) {
+			t.Errorf("Unexpected results of Replace on %T", s)
+		}
+		if s.Size() != 2 || !s.Contains(3, 10) || s.Contains(1) || s.Contains(2) {
+			t.Errorf("Expected 3 and 10, got %v", s)
+		}
+		s.UpdateElem(10, func(cur interface{}) interface{} { return cur.(int) * 2 })
+		s.UpdateElem(7, func(cur interface{}) interface{} {
+			t.Errorf("Expected f not to be called for a missing element")
+			return cur
+		})
+		if !s.Contains(20) || s.Contains(10) || s.Size() != 2 {
+			t.Errorf("Expected 3 and 20, got %v", s)
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected replacing by a string to panic")
+				}
+			}()
+			s.Replace(3, "a")
+		}()
+		if !s.Contains(3) || s.Size() != 2 {
+			t.Errorf("Set was modified by a failed replace, got %v", s)
+		}
+	}
+
+	s := NewSet(versioned{1, 1}, versioned{2, 1})
+	s.UpdateElem(versioned{id: 1}, func(cur interface{}) interface{} {
+		v := cur.(versioned)
+		v.version++
+		return v
+	})
+	if stored, _ := s.Get(versioned{id: 1}); stored != (versioned{1, 2}) || s.Size() != 2 {
+		t.Errorf("Expected version 2 of element 1, got %v in %v", stored, s)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"context"
+	"sync"
+)
+
+// watchBuffer is the number of events a watcher may fall behind before
+// the changes to its set wait for it.
+const watchBuffer = 256
+
+// ChangeOp is the kind of a ChangeEvent.
+type ChangeOp int
+
+// Kinds of changes to a set.
+const (
+	OpAdd ChangeOp = iota + 1
+	OpRemove
+	OpClear
+)
+
+func (op ChangeOp) String() string {
+	switch op {
+	case OpAdd:
+		return "add"
+	case OpRemove:
+		return "remove"
+	case OpClear:
+		return "clear"
+	}
+	return "unknown"
+}
+
+// ChangeEvent is a change to a set, see Watch.
+type ChangeEvent struct {
+	Op   ChangeOp
+	Elem interface{} // The element added or removed, nil for OpClear
+}
+
+// Watch returns a channel that receives the changes to the set, in the
+// order they are made, until ctx is done, at which point it is closed.
+// Each element added or removed by any method is an event, and so is
+// each Clear, which sends no event for the elements it removes.
+//
+// The events are buffered, but a watcher that falls behind by more than
+// 256 of them slows down the changes to the set, which wait for it to
+// catch up before they return. The channel must be read from until ctx
+// is done.
+func (set *ThreadSafeSet) Watch(ctx context.Context) <-chan ChangeEvent {
+	w := &watcher{ch: make(chan ChangeEvent)}
+	w.cond = sync.NewCond(&w.mu)
+
+	set.Lock()
+	hs := set.hooks()
+	hs.watchers = append(hs.watchers, w)
+	set.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		w.mu.Lock()
+		w.done = true
+		w.cond.Broadcast()
+		w.mu.Unlock()
+	}()
+	go func() {
+		w.send(ctx)
+		close(w.ch)
+		set.Lock()
+		for i, o := range hs.watchers {
+			if o == w {
+				// Copy, as Unlock may be ranging over the old slice.
+				hs.watchers = append(hs.watchers[:i:i], hs.watchers[i+1:]...)
+				break
+			}
+		}
+		set.Unlock()
+	}()
+	return w.ch
+}
+
+// watcher queues the changes to a set for the channel returned by Watch.
+type watcher struct {
+	mu    sync.Mutex
+	cond  *sync.Cond // Signaled when the queue or done change
+	queue []ChangeEvent
+	done  bool
+	ch    chan ChangeEvent
+}
+
+// push queues e for the channel.
+func (w *watcher) push(e ChangeEvent) {
+	w.mu.Lock()
+	if !w.done {
+		w.queue = append(w.queue, e)
+		w.cond.Broadcast()
+	}
+	w.mu.Unlock()
+}
+
+// wait waits until the queue is no longer than watchBuffer.
+func (w *watcher) wait() {
+	w.mu.Lock()
+	for len(w.queue) > watchBuffer && !w.done {
+		w.cond.Wait()
+	}
+	w.mu.Unlock()
+}
+
+// send sends the queued events to the channel until ctx is done.
+func (w *watcher) send(ctx context.Context) {
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && !w.done {
+			w.cond.Wait()
+		}
+		if w.done {
+			w.queue = nil
+			w.mu.Unlock()
+			return
+		}
+		e := w.queue[0]
+		w.queue[0] = ChangeEvent{}
+		w.queue = w.queue[1:]
+		w.cond.Broadcast()
+		w.mu.Unlock()
+
+		select {
+		case w.ch <- e:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
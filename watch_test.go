@@ -0,0 +1,76 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_Watch(t *testing.T) {
+	s := NewSet(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	events := s.(*ThreadSafeSet).Watch(ctx)
+	s.Add(2)
+	s.Add(2)
+	s.Remove(1)
+	s.IntersectWith(NewSet(3))
+	s.Add(3)
+	s.Clear()
+	expected := []ChangeEvent{{OpAdd, 2}, {OpRemove, 1}, {OpRemove, 2}, {OpAdd, 3}, {Op: OpClear}}
+	for _, want := range expected {
+		if got := <-events; got != want {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+	s.Add(4)
+	if !s.Contains(4) {
+		t.Errorf("Expected the set to work once the watcher is gone")
+	}
+}
+
+func Test_WatchBackpressure(t *testing.T) {
+	s := NewSortedSet(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := s.Watch(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*watchBuffer; i++ {
+			s.Add(i)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatalf("Expected the changes to wait for the watcher")
+	case <-time.After(50 * time.Millisecond):
+	}
+	for i := 0; i < 2*watchBuffer; i++ {
+		if e := <-events; e.Elem != i {
+			t.Fatalf("Expected %d, got %v", i, e)
+		}
+	}
+	<-done
+	s.PopMin()
+	if e := <-events; e != (ChangeEvent{OpRemove, 0}) {
+		t.Errorf("Expected PopMin to remove 0, got %v", e)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"sync"
+	"time"
+)
+
+// windowBuckets is the number of buckets a window is divided into.
+const windowBuckets = 10
+
+// WindowSet is a thread-safe set of the elements seen within a sliding
+// window of time, e.g. the clients seen in the last five minutes:
+//
+//	recent := goset.NewWindowSet(5 * time.Minute)
+//	recent.Add(clientID)
+//	active := recent.Size()
+//
+// The elements are kept in buckets of a tenth of the window, which are
+// dropped as a whole once they leave the window, so expiring elements
+// costs nothing per element. An element is therefore a member for the
+// window after it was last added, and at most a tenth of the window
+// longer.
+type WindowSet struct {
+	sync.RWMutex
+	buckets [windowBuckets + 1]windowBucket // Ring of the buckets, by epoch
+	window  time.Duration
+	width   time.Duration // The time span of a bucket
+	now     func() time.Time
+}
+
+// windowBucket holds the elements added in an epoch, a time span of the
+// width of the buckets.
+type windowBucket struct {
+	epoch int64
+	set   *ThreadUnsafeSet // nil if the bucket has never been used
+}
+
+// NewWindowSet creates and returns a new set of the elements seen within
+// the last window, with the given elements seen now. It panics if window
+// is shorter than windowBuckets nanoseconds.
+func NewWindowSet(window time.Duration, vals ...interface{}) *WindowSet {
+	if window < windowBuckets {
+		panic("goset: the window of a WindowSet is too short")
+	}
+	set := &WindowSet{window: window, width: window / windowBuckets, now: time.Now}
+	for _, item := range vals {
+		set.Add(item)
+	}
+	return set
+}
+
+// Window returns the time window of the set.
+func (set *WindowSet) Window() time.Duration {
+	return set.window
+}
+
+// epoch returns the current epoch.
+func (set *WindowSet) epoch() int64 {
+	return set.now().UnixNano() / int64(set.width)
+}
+
+// live returns the buckets within the window. The caller must hold the
+// lock.
+func (set *WindowSet) live() []*ThreadUnsafeSet {
+	cur := set.epoch()
+	live := make([]*ThreadUnsafeSet, 0, len(set.buckets))
+	for i := range set.buckets {
+		b := &set.buckets[i]
+		if b.set != nil && b.epoch > cur-int64(len(set.buckets)) && b.epoch <= cur {
+			live = append(live, b.set)
+		}
+	}
+	return live
+}
+
+// Add marks an element as seen now. Returns whether the item was not
+// seen within the window before.
+func (set *WindowSet) Add(val interface{}) bool {
+	set.Lock()
+	defer set.Unlock()
+	live := set.live()
+	seen := false
+	for _, s := range live {
+		if s.Contains(val) {
+			seen = true
+			break
+		}
+	}
+	cur := set.epoch()
+	b := &set.buckets[int(cur%int64(len(set.buckets)))]
+	if b.set == nil || b.epoch != cur {
+		// The bucket of an epoch that has left the window is reused,
+		// for elements of the type of those still in the window.
+		s := newThreadUnsafeSet()
+		for _, l := range live {
+			if l.typ != nil {
+				s.typ = l.typ
+				break
+			}
+		}
+		b.epoch, b.set = cur, &s
+	}
+	b.set.Add(val)
+	return !seen
+}
+
+// Contains returns whether the given items
+// were all seen within the window.
+func (set *WindowSet) Contains(val ...interface{}) bool {
+	set.RLock()
+	defer set.RUnlock()
+	live := set.live()
+	for _, v := range val {
+		found := false
+		for _, s := range live {
+			if s.Contains(v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// elems returns the elements seen within the window. The caller must hold
+// the lock.
+func (set *WindowSet) elems() *ThreadUnsafeSet {
+	ret := newThreadUnsafeSet()
+	for _, s := range set.live() {
+		ret.UnionWith(s)
+	}
+	return &ret
+}
+
+// Size Returns the number of elements seen within the window. It takes
+// time in proportion to the number of elements in all buckets.
+func (set *WindowSet) Size() int {
+	set.RLock()
+	defer set.RUnlock()
+	return set.elems().Size()
+}
+
+// Each iterates over the elements seen within the window and executes the
+// passed func against each element. If passed func returns true, stop
+// iteration at the time.
+func (set *WindowSet) Each(f func(elem interface{}) bool) {
+	set.RLock()
+	defer set.RUnlock()
+	set.elems().Each(f)
+}
+
+// ToSlice returns the elements seen within the window as a slice.
+func (set *WindowSet) ToSlice() []interface{} {
+	set.RLock()
+	defer set.RUnlock()
+	return set.elems().ToSlice()
+}
+
+// ToSet returns a thread-safe snapshot of the elements seen within the
+// window.
+func (set *WindowSet) ToSet() Set {
+	set.RLock()
+	defer set.RUnlock()
+	return set.elems().ToThreadSafe()
+}
+
+// Clear removes all elements from the set.
+func (set *WindowSet) Clear() {
+	set.Lock()
+	defer set.Unlock()
+	set.buckets = [windowBuckets + 1]windowBucket{}
+}
+
+// String provides a convenient string representation
+// of the current state of the set.
+func (set *WindowSet) String() string {
+	set.RLock()
+	defer set.RUnlock()
+	return set.elems().String()
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_WindowSet(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewWindowSet(10 * time.Minute)
+	s.now = func() time.Time { return now }
+
+	if !s.Add("a") || s.Add("a") {
+		t.Errorf("Expected a to be new once")
+	}
+	now = now.Add(5 * time.Minute)
+	s.Add("b")
+	if !s.Contains("a", "b") || s.Size() != 2 {
+		t.Errorf("Expected a and b, got %v", s.ToSlice())
+	}
+
+	now = now.Add(6 * time.Minute)
+	if s.Contains("a") || !s.Contains("b") || s.Size() != 1 {
+		t.Errorf("Expected a to have left the window, got %v", s.ToSlice())
+	}
+	if !s.Add("a") {
+		t.Errorf("Expected a to be new again")
+	}
+
+	// Seeing b again keeps it in the window.
+	s.Add("b")
+	now = now.Add(9 * time.Minute)
+	if !s.Contains("a", "b") {
+		t.Errorf("Expected a and b, got %v", s.ToSlice())
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected a panic for an element of the wrong type")
+			}
+		}()
+		s.Add(1)
+	}()
+
+	s.Clear()
+	if s.Size() != 0 {
+		t.Errorf("Expected an empty set, got %v", s.ToSlice())
+	}
+}
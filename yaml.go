@@ -0,0 +1,73 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import "fmt"
+
+// MarshalYAML implements the Marshaler interface of the YAML packages
+// (gopkg.in/yaml.v2 and v3) without depending on them: the set is
+// represented as a sequence of its elements, sorted like
+// ToSortedSlice(nil) to keep the output stable.
+func (set *ThreadUnsafeSet) MarshalYAML() (interface{}, error) {
+	return set.ToSortedSlice(nil), nil
+}
+
+// UnmarshalYAML implements the obsolete Unmarshaler interface of
+// gopkg.in/yaml.v2 and v3, which both packages support, replacing the
+// elements of the set by those of a YAML sequence. It fails if an
+// element occurs twice in the sequence, which usually is a mistake in
+// a configuration file, and leaves the set unchanged on any error.
+// Numbers are converted to the numeric element type of the set, if it
+// has one. A zero set becomes a set with the default storage.
+func (set *ThreadUnsafeSet) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var elems []interface{}
+	if err := unmarshal(&elems); err != nil {
+		return err
+	}
+	if set.store == nil {
+		*set = newThreadUnsafeSet()
+	}
+	decoded := set.emptyLike(len(elems))
+	decoded.typ = set.typ
+	for _, elem := range elems {
+		elem, err := decoded.convert(elem)
+		if err != nil {
+			return err
+		}
+		added, err := decoded.TryAdd(elem)
+		if err != nil {
+			return err
+		}
+		if !added {
+			return fmt.Errorf("yaml: duplicate set element %v", elem)
+		}
+	}
+	return set.replace(&decoded)
+}
+
+// MarshalYAML represents the set as a YAML sequence, see
+// ThreadUnsafeSet.MarshalYAML.
+func (set *ThreadSafeSet) MarshalYAML() (interface{}, error) {
+	set.RLock()
+	defer set.RUnlock()
+	return set.unsafeSet.MarshalYAML()
+}
+
+// UnmarshalYAML replaces the elements of the set by those of a YAML
+// sequence, see ThreadUnsafeSet.UnmarshalYAML.
+func (set *ThreadSafeSet) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	set.Lock()
+	defer set.Unlock()
+	return set.unsafeSet.UnmarshalYAML(unmarshal)
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 Wang Bohan <wangbohan2000@gmail.com>
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package goset
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// yamlSequence returns an unmarshal func like the YAML packages pass to
+// UnmarshalYAML, decoding elems.
+func yamlSequence(elems ...interface{}) func(interface{}) error {
+	return func(v interface{}) error {
+		*v.(*[]interface{}) = elems
+		return nil
+	}
+}
+
+func Test_YAML(t *testing.T) {
+	out, err := NewSet(3, 1, 2).(*ThreadSafeSet).MarshalYAML()
+	if b, _ := json.Marshal(out); err != nil || string(b) != "[1,2,3]" {
+		t.Errorf("Expected a sorted sequence, got %v, %v", out, err)
+	}
+
+	var tags ThreadSafeSet
+	if err := tags.UnmarshalYAML(yamlSequence("a", "b")); err != nil || !tags.Equal(NewSet("a", "b")) {
+		t.Errorf("Expected {a, b}, got %v, %v", &tags, err)
+	}
+	if err := tags.UnmarshalYAML(yamlSequence("c", "a", "c")); err == nil || !tags.Equal(NewSet("a", "b")) {
+		t.Errorf("Expected an error for a duplicate element and {a, b} to be kept, got %v, %v", &tags, err)
+	}
+	ports := NewThreadUnsafeSet(uint16(80)).(*ThreadUnsafeSet)
+	if err := ports.UnmarshalYAML(yamlSequence(443, 8080)); err != nil || !ports.Equal(NewThreadUnsafeSet(uint16(443), uint16(8080))) {
+		t.Errorf("Expected the ports to be replaced and converted to uint16, got %v, %v", ports, err)
+	}
+	for _, elem := range []interface{}{70000, -1, 1.5} {
+		if err := ports.UnmarshalYAML(yamlSequence(elem)); !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("Expected a type mismatch decoding %v into a set of uint16, got %v", elem, err)
+		}
+		if ports.Size() != 2 {
+			t.Errorf("Expected a failed decode to leave the set unchanged, got %v", ports)
+		}
+	}
+}